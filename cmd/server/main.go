@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"io"
+	"errors"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,6 +15,8 @@ import (
 	"github.com/joho/godotenv"
 	httpHandler "github.com/mmuslimabdulj/goat-chat/internal/delivery/http"
 	"github.com/mmuslimabdulj/goat-chat/internal/delivery/ws"
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+	"github.com/mmuslimabdulj/goat-chat/internal/handoff"
 	"github.com/mmuslimabdulj/goat-chat/internal/middleware"
 	"github.com/mmuslimabdulj/goat-chat/internal/usecase"
 	"github.com/mmuslimabdulj/goat-chat/internal/config"
@@ -36,8 +40,20 @@ func main() {
 		log.SetOutput(io.Discard)
 	}
 
+	// Rebuild config-driven globals now that .env is loaded
+	cfg := config.AppConfig
+	middleware.ConfigureLimiters(cfg)
+	ws.GlobalSessionStore.Close()
+	ws.GlobalSessionStore = newSessionStore(cfg)
+
 	// Initialize dependencies
-	roomManager := ws.NewRoomManager()
+	roomManager := ws.NewRoomManager(
+		ws.WithMaxHistorySize(cfg.MaxHistorySize),
+		ws.WithHistoryJoinBurst(cfg.HistoryJoinBurst),
+		ws.WithMaxMessageSize(cfg.MaxMessageSize),
+		ws.WithShutdownGracePeriod(cfg.ShutdownGracePeriod),
+		ws.WithFloodConfig(floodConfig(cfg)),
+	)
 	generator := usecase.NewPersonaGenerator()
 	roomManager.SetPersonaReleaser(generator)
 	handler := httpHandler.NewHandler(roomManager, generator)
@@ -79,26 +95,160 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// With handoff enabled, bind alongside the previous process and take over its rooms
+	var listener net.Listener
+	var handoffServer *handoff.Server
+	var err error
+	if cfg.HandoffSocket != "" {
+		listener, err = handoff.Listen(server.Addr)
+		if err == nil {
+			// Serving empty next to a process that still has the rooms would split clients between the two
+			if err := takeOver(cfg.HandoffSocket, roomManager); err != nil {
+				log.Fatalf("Handoff failed, leaving the previous process to serve: %v", err)
+			}
+			handoffServer = serveHandoff(cfg.HandoffSocket)
+		}
+	} else {
+		listener, err = net.Listen("tcp", server.Addr)
+	}
+	if err != nil {
+		log.Fatalf("Server error: %v", err)
+	}
+
 	// Start server in goroutine
 	go func() {
 		log.Printf("GOAT chat running at http://localhost:%s", port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Server error: %v", err)
 		}
 	}()
 
-	// Graceful shutdown
+	// Graceful shutdown, or hand everything to the next process when it asks
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	for stopping := false; !stopping; {
+		var handoffRequests <-chan net.Conn
+		if handoffServer != nil {
+			handoffRequests = handoffServer.Requests()
+		}
+		select {
+		case <-quit:
+			stopping = true
+		case conn := <-handoffRequests:
+			if handOff(server, handoffServer, conn, roomManager) {
+				return
+			}
+			// The successor exits when it cannot restore, so keep our clients and wait for the next one
+			handoffServer = serveHandoff(cfg.HandoffSocket)
+		}
+	}
 	log.Println("Shutting down server...")
+	if handoffServer != nil {
+		handoffServer.Close()
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+	shutdownErr := server.Shutdown(ctx)
+
+	// Seal sessions (if enabled) even when shutdown timed out
+	if err := ws.GlobalSessionStore.Close(); err != nil {
+		log.Printf("Session snapshot failed: %v", err)
+	}
+
+	if shutdownErr != nil {
+		log.Fatalf("Server forced to shutdown: %v", shutdownErr)
 	}
 
 	log.Println("Server exited gracefully")
 }
+
+// takeOver restores the rooms and sessions of the process listening on socketPath, if any
+// Having no process to take over from is not an error; any other failure is returned
+// An abstract socket cannot have a peer either, since Serve refuses it too
+func takeOver(socketPath string, roomManager *ws.RoomManager) error {
+	err := handoff.Receive(socketPath, func(r io.Reader) error {
+		rooms, err := roomManager.ReadHandoff(r)
+		if err == nil {
+			log.Printf("Took over %d rooms from the previous process", rooms)
+		}
+		return err
+	})
+	if errors.Is(err, handoff.ErrNoPeer) || errors.Is(err, handoff.ErrAbstractSocket) {
+		return nil
+	}
+	return err
+}
+
+// serveHandoff waits on socketPath for the next process, returning nil if that is not possible
+func serveHandoff(socketPath string) *handoff.Server {
+	handoffServer, err := handoff.Serve(socketPath)
+	if err != nil {
+		log.Printf("Handoff disabled: %v", err)
+		return nil
+	}
+	return handoffServer
+}
+
+// handOff passes rooms and sessions to the successor, then sends every client over to it
+// It keeps serving and returns false if the successor never acknowledged the state
+// Nothing is written to disk, so the sealed session snapshot is skipped
+func handOff(server *http.Server, handoffServer *handoff.Server, conn net.Conn, roomManager *ws.RoomManager) bool {
+	log.Println("Handing off to the new process...")
+
+	// The listener stays open until the successor has the state, so a failed handoff loses nothing.
+	// Sessions minted in between are not carried over; their clients start afresh on reconnect
+	if err := handoffServer.Send(conn, roomManager.WriteHandoff); err != nil {
+		log.Printf("Handoff failed, still serving: %v", err)
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Server forced to shutdown: %v", err)
+	}
+	roomManager.DisconnectAll()
+
+	log.Println("Handoff complete")
+	return true
+}
+
+// floodConfig applies the configured limits and message costs to the default flood protection
+func floodConfig(cfg *config.Config) ws.FloodConfig {
+	flood := ws.DefaultFloodConfig()
+	flood.Rate = cfg.FloodRate
+	flood.Burst = cfg.FloodBurst
+	for msgType, cost := range cfg.FloodCosts {
+		flood.Costs[domain.MessageType(msgType)] = cost
+	}
+	flood.WarnStrikes = cfg.FloodWarnStrikes
+	flood.TimeoutStrikes = cfg.FloodTimeoutStrikes
+	flood.TimeoutDuration = cfg.FloodTimeoutDuration
+	flood.ChaosCooldown = cfg.ChaosCooldown
+	return flood
+}
+
+// newSessionStore builds the session store from config
+// With a snapshot key, sessions from the previous process are restored so rolling restarts keep users signed in
+func newSessionStore(cfg *config.Config) ws.SessionStore {
+	opts := []ws.SessionStoreOption{ws.WithSessionTTL(cfg.SessionTTL)}
+	if cfg.SessionSnapshotKey == "" {
+		return ws.NewSessionStore(opts...)
+	}
+
+	store, err := ws.NewSealedSessionStore(cfg.SessionSnapshotPath, cfg.SessionSnapshotKey, opts...)
+	if err != nil {
+		log.Printf("Session snapshot disabled: %v", err)
+		return ws.NewSessionStore(opts...)
+	}
+
+	restored, err := store.Restore()
+	if err != nil {
+		log.Printf("Session snapshot discarded: %v", err)
+	} else if restored > 0 {
+		log.Printf("Restored %d sessions from snapshot", restored)
+	}
+	return store
+}
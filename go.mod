@@ -7,5 +7,8 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/gorilla/websocket v1.5.3
 	github.com/joho/godotenv v1.5.1
+	github.com/vmihailenco/msgpack/v5 v5.4.1
 	golang.org/x/time v0.14.0
 )
+
+require github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
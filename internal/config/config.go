@@ -2,10 +2,12 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
 	"golang.org/x/time/rate"
 )
 
@@ -18,17 +20,50 @@ type Config struct {
 	AllowedOrigins []string
 	SessionTTL     time.Duration
 
+	// Session snapshot: sealed to SessionSnapshotPath on shutdown, restored on startup
+	// Disabled unless SessionSnapshotKey is set
+	SessionSnapshotKey  string
+	SessionSnapshotPath string
+
+	// Handoff: Unix socket the next process takes over rooms through (empty = disabled)
+	// Must be a filesystem path; the socket is created 0600 and only serves the same user
+	HandoffSocket string
+
 	// Rate Limiting
 	RateLimitAPI    rate.Limit
 	RateLimitWS     rate.Limit
 	RateLimitStrict rate.Limit
 
+	RateLimitAPIBurst    int
+	RateLimitWSBurst     int
+	RateLimitStrictBurst int
+
 	// Logging
 	LogLevel string
 
 	// WebSocket
-	MaxMessageSize int
-	MaxHistorySize int
+	MaxMessageSize   int
+	MaxHistorySize   int
+	HistoryJoinBurst int // Recent messages sent on join; older ones are paged in
+
+	// Rooms
+	ShutdownGracePeriod time.Duration // Empty rooms wait this long for reconnects
+
+	// Flood protection (per-user token buckets for inbound WebSocket messages)
+	FloodRate            rate.Limit     // Tokens refilled per second
+	FloodBurst           int            // Bucket size
+	FloodCosts           map[string]int // Token cost per message type, overriding the built-in costs
+	FloodWarnStrikes     int            // Dropped messages before a warning
+	FloodTimeoutStrikes  int            // Dropped messages before an auto-timeout
+	FloodTimeoutDuration time.Duration  // Length of an auto-timeout
+	ChaosCooldown        time.Duration  // Room-wide gap between chaos messages
+
+	// WebSocket transport
+	ReadBufferSize       int
+	WriteBufferSize      int
+	Compression          bool // Offer permessage-deflate during the upgrade
+	CompressionLevel     int  // flate level, 1 (fastest) to 9 (smallest)
+	CompressionThreshold int  // Frames smaller than this are sent uncompressed
 
 	// External APIs
 	GiphyAPIKey string
@@ -39,13 +74,37 @@ func DefaultConfig() *Config {
 	return &Config{
 		Port:            "8080",
 		AllowedOrigins:  []string{"http://localhost:8080", "http://localhost:3000"},
-		SessionTTL:      24 * time.Hour,
-		RateLimitAPI:    10,
-		RateLimitWS:     5,
-		RateLimitStrict: 2,
-		LogLevel:        "info", // Options: debug, info, warn, error, silent
-		MaxMessageSize:  4096,
-		MaxHistorySize:  200,
+		SessionTTL:      domain.SessionTTL,
+		RateLimitAPI:    domain.DefaultRateLimitAPI,
+		RateLimitWS:     domain.DefaultRateLimitWS,
+		RateLimitStrict: domain.DefaultRateLimitStrict,
+
+		RateLimitAPIBurst:    20,
+		RateLimitWSBurst:     10,
+		RateLimitStrictBurst: 5,
+
+		LogLevel:       "info", // Options: debug, info, warn, error, silent
+		MaxMessageSize: domain.MaxMessageSize,
+		MaxHistorySize: domain.MaxHistorySize,
+
+		HistoryJoinBurst: domain.HistoryJoinBurst,
+
+		ShutdownGracePeriod: domain.ShutdownGracePeriod,
+
+		FloodRate:            domain.FloodRate,
+		FloodBurst:           domain.FloodBurst,
+		FloodWarnStrikes:     domain.FloodWarnStrikes,
+		FloodTimeoutStrikes:  domain.FloodTimeoutStrikes,
+		FloodTimeoutDuration: domain.FloodTimeoutDuration,
+		ChaosCooldown:        domain.ChaosCooldown,
+
+		SessionSnapshotPath: filepath.Join(os.TempDir(), "goat-chat-sessions.sealed"),
+
+		ReadBufferSize:       4096,
+		WriteBufferSize:      4096,
+		Compression:          true,
+		CompressionLevel:     1,
+		CompressionThreshold: 512,
 	}
 }
 
@@ -69,6 +128,18 @@ func LoadFromEnv() *Config {
 		}
 	}
 
+	if key := os.Getenv("SESSION_SNAPSHOT_KEY"); key != "" {
+		cfg.SessionSnapshotKey = key
+	}
+
+	if path := os.Getenv("SESSION_SNAPSHOT_PATH"); path != "" {
+		cfg.SessionSnapshotPath = path
+	}
+
+	if socket := os.Getenv("HANDOFF_SOCKET"); socket != "" {
+		cfg.HandoffSocket = socket
+	}
+
 	// Rate Limiting
 	if rl := os.Getenv("RATE_LIMIT_API"); rl != "" {
 		if val, err := strconv.Atoi(rl); err == nil && val > 0 {
@@ -88,6 +159,24 @@ func LoadFromEnv() *Config {
 		}
 	}
 
+	if b := os.Getenv("RATE_LIMIT_API_BURST"); b != "" {
+		if val, err := strconv.Atoi(b); err == nil && val > 0 {
+			cfg.RateLimitAPIBurst = val
+		}
+	}
+
+	if b := os.Getenv("RATE_LIMIT_WS_BURST"); b != "" {
+		if val, err := strconv.Atoi(b); err == nil && val > 0 {
+			cfg.RateLimitWSBurst = val
+		}
+	}
+
+	if b := os.Getenv("RATE_LIMIT_STRICT_BURST"); b != "" {
+		if val, err := strconv.Atoi(b); err == nil && val > 0 {
+			cfg.RateLimitStrictBurst = val
+		}
+	}
+
 	// Logging
 	if level := os.Getenv("LOG_LEVEL"); level != "" {
 		cfg.LogLevel = level
@@ -106,6 +195,90 @@ func LoadFromEnv() *Config {
 		}
 	}
 
+	if burst := os.Getenv("HISTORY_JOIN_BURST"); burst != "" {
+		if val, err := strconv.Atoi(burst); err == nil && val > 0 {
+			cfg.HistoryJoinBurst = val
+		}
+	}
+
+	// Rooms
+	if grace := os.Getenv("ROOM_SHUTDOWN_GRACE_SECONDS"); grace != "" {
+		if secs, err := strconv.Atoi(grace); err == nil && secs > 0 {
+			cfg.ShutdownGracePeriod = time.Duration(secs) * time.Second
+		}
+	}
+
+	// Flood protection
+	if rl := os.Getenv("FLOOD_RATE"); rl != "" {
+		if val, err := strconv.Atoi(rl); err == nil && val > 0 {
+			cfg.FloodRate = rate.Limit(val)
+		}
+	}
+
+	if b := os.Getenv("FLOOD_BURST"); b != "" {
+		if val, err := strconv.Atoi(b); err == nil && val > 0 {
+			cfg.FloodBurst = val
+		}
+	}
+
+	if costs := os.Getenv("FLOOD_COSTS"); costs != "" {
+		cfg.FloodCosts = parseFloodCosts(costs)
+	}
+
+	if strikes := os.Getenv("FLOOD_WARN_STRIKES"); strikes != "" {
+		if val, err := strconv.Atoi(strikes); err == nil && val > 0 {
+			cfg.FloodWarnStrikes = val
+		}
+	}
+
+	if strikes := os.Getenv("FLOOD_TIMEOUT_STRIKES"); strikes != "" {
+		if val, err := strconv.Atoi(strikes); err == nil && val >= 0 {
+			cfg.FloodTimeoutStrikes = val // 0 disables auto-timeouts
+		}
+	}
+
+	if secs := os.Getenv("FLOOD_TIMEOUT_SECONDS"); secs != "" {
+		if val, err := strconv.Atoi(secs); err == nil && val > 0 {
+			cfg.FloodTimeoutDuration = time.Duration(val) * time.Second
+		}
+	}
+
+	if secs := os.Getenv("CHAOS_COOLDOWN_SECONDS"); secs != "" {
+		if val, err := strconv.Atoi(secs); err == nil && val >= 0 {
+			cfg.ChaosCooldown = time.Duration(val) * time.Second // 0 disables the cooldown
+		}
+	}
+
+	if size := os.Getenv("WS_READ_BUFFER_SIZE"); size != "" {
+		if val, err := strconv.Atoi(size); err == nil && val > 0 {
+			cfg.ReadBufferSize = val
+		}
+	}
+
+	if size := os.Getenv("WS_WRITE_BUFFER_SIZE"); size != "" {
+		if val, err := strconv.Atoi(size); err == nil && val > 0 {
+			cfg.WriteBufferSize = val
+		}
+	}
+
+	if enabled := os.Getenv("WS_COMPRESSION"); enabled != "" {
+		if val, err := strconv.ParseBool(enabled); err == nil {
+			cfg.Compression = val
+		}
+	}
+
+	if level := os.Getenv("WS_COMPRESSION_LEVEL"); level != "" {
+		if val, err := strconv.Atoi(level); err == nil && val >= 1 && val <= 9 {
+			cfg.CompressionLevel = val
+		}
+	}
+
+	if threshold := os.Getenv("WS_COMPRESSION_THRESHOLD"); threshold != "" {
+		if val, err := strconv.Atoi(threshold); err == nil && val >= 0 {
+			cfg.CompressionThreshold = val
+		}
+	}
+
 	// External APIs
 	if key := os.Getenv("GIPHY_API_KEY"); key != "" {
 		cfg.GiphyAPIKey = key
@@ -127,5 +300,24 @@ func parseOrigins(origins string) []string {
 	return result
 }
 
+// parseFloodCosts parses comma-separated type:cost pairs, e.g. "chaos:5,vibrate:4"
+// Malformed pairs and negative costs are skipped
+func parseFloodCosts(costs string) map[string]int {
+	result := make(map[string]int)
+	for _, p := range strings.Split(costs, ",") {
+		msgType, cost, ok := strings.Cut(p, ":")
+		if !ok {
+			continue
+		}
+		msgType = strings.TrimSpace(msgType)
+		val, err := strconv.Atoi(strings.TrimSpace(cost))
+		if msgType == "" || err != nil || val < 0 {
+			continue
+		}
+		result[msgType] = val
+	}
+	return result
+}
+
 // Global configuration instance
 var AppConfig = LoadFromEnv()
@@ -4,12 +4,16 @@ import (
 	"encoding/json"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/mmuslimabdulj/goat-chat/internal/delivery/ws"
 	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+	"github.com/mmuslimabdulj/goat-chat/internal/middleware"
 	"github.com/mmuslimabdulj/goat-chat/internal/usecase"
 	"github.com/mmuslimabdulj/goat-chat/internal/config"
 	"github.com/mmuslimabdulj/goat-chat/view/pages"
@@ -30,13 +34,19 @@ func isOriginAllowed(origin string) bool {
 	return false
 }
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		origin := r.Header.Get("Origin")
-		return isOriginAllowed(origin)
-	},
+// newUpgrader builds the WebSocket upgrader from the transport settings
+// permessage-deflate is only used when the browser offers it too
+func newUpgrader(cfg *config.Config) *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:    cfg.ReadBufferSize,
+		WriteBufferSize:   cfg.WriteBufferSize,
+		EnableCompression: cfg.Compression,
+		Subprotocols:      ws.Subprotocols, // JSON unless the client offers MessagePack
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			return isOriginAllowed(origin)
+		},
+	}
 }
 
 // sanitizeRoomName cleans and validates room name input
@@ -72,15 +82,34 @@ func sanitizeRoomName(name string) string {
 type Handler struct {
 	roomManager *ws.RoomManager
 	generator   *usecase.PersonaGenerator
+	authLimiter *middleware.IPRateLimiter // Counts wrong passphrase/invite attempts
+
+	upgrader             *websocket.Upgrader
+	compressionLevel     int
+	compressionThreshold int
 }
 
 func NewHandler(rm *ws.RoomManager, generator *usecase.PersonaGenerator) *Handler {
 	return &Handler{
 		roomManager: rm,
 		generator:   generator,
+		authLimiter: middleware.StrictLimiter,
+
+		upgrader:             newUpgrader(config.AppConfig),
+		compressionLevel:     config.AppConfig.CompressionLevel,
+		compressionThreshold: config.AppConfig.CompressionThreshold,
 	}
 }
 
+// writeJSONError writes a JSON error body with the given status
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error": message,
+	})
+}
+
 // HandleLobby serves the lobby page (create/join room)
 func (h *Handler) HandleLobby(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Cache-Control", "no-store, no-cache, must-revalidate, max-age=0")
@@ -101,23 +130,46 @@ func (h *Handler) HandleCreateRoom(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Name string `json:"name"`
+		Name         string `json:"name"`
+		Passphrase   string `json:"passphrase"`
+		MaxOccupancy int    `json:"max_occupancy"`
+		Knock        bool   `json:"knock"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
 
+	if len(req.Passphrase) > domain.MaxPassphraseLength {
+		writeJSONError(w, http.StatusBadRequest, "Passphrase terlalu panjang")
+		return
+	}
+
+	if req.MaxOccupancy < 0 || req.MaxOccupancy > domain.MaxRoomOccupancy {
+		writeJSONError(w, http.StatusBadRequest, "Kapasitas room tidak valid")
+		return
+	}
+
 	// Sanitize room name
 	req.Name = sanitizeRoomName(req.Name)
 
-	room := h.roomManager.CreateRoom(req.Name)
+	room := h.roomManager.CreateRoomWithOptions(req.Name, ws.RoomOptions{
+		Passphrase:   req.Passphrase,
+		MaxOccupancy: req.MaxOccupancy,
+		KnockToEnter: req.Knock,
+	})
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
+	res := map[string]string{
 		"code": room.Code,
 		"name": room.Name,
-	})
+	}
+	// Creator already knows the passphrase, so hand them a join ticket directly
+	if room.IsProtected() {
+		res["ticket"] = room.IssueTicket()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
 }
 
 // HandleJoinRoom validates room code and returns room info
@@ -128,7 +180,9 @@ func (h *Handler) HandleJoinRoom(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Code string `json:"code"`
+		Code       string `json:"code"`
+		Passphrase string `json:"passphrase"`
+		Invite     string `json:"invite"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
@@ -137,19 +191,45 @@ func (h *Handler) HandleJoinRoom(w http.ResponseWriter, r *http.Request) {
 
 	room := h.roomManager.GetRoom(req.Code)
 	if room == nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "Room tidak ditemukan",
-		})
+		writeJSONError(w, http.StatusNotFound, "Room tidak ditemukan")
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
+	if room.Hub.IsBanned("", middleware.ClientIP(r)) {
+		writeJSONError(w, http.StatusForbidden, "Kamu diblokir dari room ini")
+		return
+	}
+
+	if room.Hub.IsFull() {
+		writeJSONError(w, http.StatusConflict, "Room sudah penuh")
+		return
+	}
+
+	res := map[string]string{
 		"code": room.Code,
 		"name": room.Name,
-	})
+	}
+
+	if room.IsProtected() {
+		ip := middleware.ClientIP(r)
+		if h.authLimiter.Blocked(ip) {
+			writeJSONError(w, http.StatusTooManyRequests, "Terlalu banyak percobaan, coba lagi nanti")
+			return
+		}
+
+		admitted := (req.Invite != "" && room.ConsumeInvite(req.Invite)) ||
+			(req.Passphrase != "" && room.CheckPassphrase(req.Passphrase))
+		if !admitted {
+			h.authLimiter.Allow(ip) // Wrong attempt consumes a token
+			writeJSONError(w, http.StatusForbidden, "Passphrase atau undangan salah")
+			return
+		}
+
+		res["ticket"] = room.IssueTicket()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
 }
 
 // HandleRoom serves the chat room page
@@ -176,60 +256,173 @@ func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	conn, err := upgrader.Upgrade(w, r, nil)
+	// Clients present their session token, join ticket or invite in subprotocols, or in
+	// the first frame when they ask for it with ?auth=frame (never in the URL)
+	ip := middleware.ClientIP(r)
+	authFrame := r.URL.Query().Get("auth") == "frame"
+	creds := ws.CredentialsFromSubprotocols(websocket.Subprotocols(r))
+	var session *ws.SessionToken
+
+	if !authFrame {
+		var status int
+		var reason string
+		if session, status, reason = h.admitAndResume(room, creds, ip); status != 0 {
+			http.Error(w, reason, status)
+			return
+		}
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return
 	}
+	// No-op unless permessage-deflate was negotiated with this client
+	conn.SetCompressionLevel(h.compressionLevel)
+
+	if authFrame {
+		frameCreds, err := ws.ReadAuthFrame(conn, room.Hub.MaxMessageSize(), domain.SessionAuthTimeout)
+		if err != nil {
+			rejectWebSocket(conn, "Auth frame required")
+			return
+		}
+		if frameCreds.Token != "" {
+			creds.Token = frameCreds.Token
+		}
+		if frameCreds.Ticket != "" {
+			creds.Ticket = frameCreds.Ticket
+		}
+		if frameCreds.Invite != "" {
+			creds.Invite = frameCreds.Invite
+		}
+		var status int
+		var reason string
+		if session, status, reason = h.admitAndResume(room, creds, ip); status != 0 {
+			rejectWebSocket(conn, reason)
+			return
+		}
+	}
 
 	var user *domain.User
-	
-	// Check if client is reconnecting with session token
-	token := r.URL.Query().Get("token")
-	if token != "" {
-		// Validate session token
-		session, valid := ws.GlobalSessionStore.ValidateToken(token)
-		if valid && session.RoomCode == code {
-			// Valid token - restore persona
-			user = h.generator.GenerateWithPersona(session.PersonaName, session.PersonaColor)
-		} else {
-			// Invalid token - generate new persona
-			user = h.generator.Generate()
+	var lineage string
+	if session != nil {
+		// Valid token - restore the same user ID and persona
+		id, err := uuid.Parse(session.UserID)
+		if err != nil {
+			id = uuid.New()
 		}
+		user = h.generator.GenerateWithPersona(id, session.PersonaName, session.PersonaColor)
+		lineage = session.Lineage
 	} else {
-		// New user - generate fresh persona
+		// New user or invalid token - generate fresh persona
 		user = h.generator.Generate()
 	}
 	user.Conn = conn
 
-	// Create client and register with room's hub
-	client := ws.NewClient(room.Hub, conn, user)
-	room.Hub.Register(client)
-
 	// Generate session token for this user (for future reconnection)
-	sessionToken := ws.GlobalSessionStore.GenerateToken(
+	// Reconnects continue the same lineage so bans follow the person
+	sessionToken := ws.GlobalSessionStore.GenerateTokenInLineage(
 		user.ID.String(),
 		user.PersonaName,
 		user.PersonaColor,
 		code,
+		lineage,
 	)
+	if lineage == "" {
+		lineage = sessionToken
+	}
+
+	// Create client and register with room's hub
+	client := ws.NewClient(room.Hub, conn, user, ws.WithCompressionThreshold(h.compressionThreshold))
+	client.SetOrigin(ip, lineage)
+	client.SetSessionToken(sessionToken)
+	// Reconnecting clients only need the history they missed
+	if lastSeq, err := strconv.ParseUint(r.URL.Query().Get("last_seq"), 10, 64); err == nil {
+		client.SetResumeSeq(lastSeq)
+	}
 
 	// Send token to client via WebSocket message
-	go func() {
-		tokenMsg := map[string]interface{}{
-			"type": "session_token",
-			"payload": map[string]string{
-				"token": sessionToken,
-			},
-		}
-		data, _ := json.Marshal(tokenMsg)
-		client.Send(data)
-	}()
+	// Queued before Register, so it is always the first frame and never races the hub's identity
+	client.SendMessage(ws.SessionTokenMessage(sessionToken))
+
+	room.Hub.Register(client)
 
 	// Start read/write pumps in goroutines
 	go client.WritePump()
 	go client.ReadPump()
 }
 
+// admitAndResume applies the admission checks, then redeems the reconnect token that passed them
+// Tokens are single-use: a valid one is consumed and replaced by the token issued on connect,
+// but only once the connection is admitted, so a refused attempt can still be retried
+// Returns the session to resume (nil for a newcomer), or the status and reason to refuse with
+func (h *Handler) admitAndResume(room *ws.Room, creds ws.Credentials, ip string) (*ws.SessionToken, int, string) {
+	var session *ws.SessionToken
+	if creds.Token != "" {
+		if s, valid := ws.GlobalSessionStore.ValidateToken(creds.Token); valid && s.RoomCode == room.Code {
+			session = s
+		}
+	}
+
+	if status, reason := h.admitWebSocket(room, session, creds, ip); status != 0 {
+		return nil, status, reason
+	}
+	if session == nil {
+		return nil, 0, ""
+	}
+
+	if s, valid := ws.GlobalSessionStore.ConsumeToken(creds.Token); valid {
+		return s, 0, ""
+	}
+	// Another connection redeemed it in the meantime, so this one is admitted as a newcomer
+	status, reason := h.admitWebSocket(room, nil, creds, ip)
+	return nil, status, reason
+}
+
+// admitWebSocket applies the ban and protected-room checks to a connection attempt
+// Returns the HTTP status and reason to refuse it with, or 0 when it may join
+func (h *Handler) admitWebSocket(room *ws.Room, session *ws.SessionToken, creds ws.Credentials, ip string) (int, string) {
+	// Refuse banned connections before they reach the hub
+	var lineage string
+	if session != nil {
+		lineage = session.Lineage
+	}
+	if room.Hub.IsBanned(lineage, ip) {
+		return http.StatusForbidden, "Banned from this room"
+	}
+
+	// Refuse a full room before a session token is minted
+	var userID string
+	if session != nil {
+		userID = session.UserID
+	}
+	if !room.Hub.HasSeatFor(userID) {
+		return http.StatusConflict, "Room is full"
+	}
+
+	// Protected rooms require a join ticket, an invite, or an existing session for this room
+	if room.IsProtected() && session == nil {
+		if h.authLimiter.Blocked(ip) {
+			return http.StatusTooManyRequests, "Too Many Requests"
+		}
+
+		admitted := (creds.Ticket != "" && room.ConsumeTicket(creds.Ticket)) ||
+			(creds.Invite != "" && room.ConsumeInvite(creds.Invite))
+		if !admitted {
+			h.authLimiter.Allow(ip)
+			return http.StatusForbidden, "Room is protected"
+		}
+	}
+
+	return 0, ""
+}
+
+// rejectWebSocket closes an upgraded connection that failed the post-upgrade checks
+func rejectWebSocket(conn *websocket.Conn, reason string) {
+	msg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason)
+	conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+	conn.Close()
+}
+
 // HandleGifSearch proxies GIF search requests to GIPHY API
 func (h *Handler) HandleGifSearch(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
@@ -7,10 +7,15 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/mmuslimabdulj/goat-chat/internal/config"
 	"github.com/mmuslimabdulj/goat-chat/internal/delivery/ws"
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+	"github.com/mmuslimabdulj/goat-chat/internal/middleware"
 	"github.com/mmuslimabdulj/goat-chat/internal/usecase"
+	"github.com/vmihailenco/msgpack/v5"
 )
 
 // === SECURITY TESTS ===
@@ -412,3 +417,641 @@ func TestHandleGifSearch_MissingAPIKey(t *testing.T) {
 	}
 }
 
+// === Protected Room Tests ===
+
+func TestHandleCreateRoom_ProtectedReturnsTicket(t *testing.T) {
+	h := setupTestHandler()
+
+	body := []byte(`{"name": "Secret", "passphrase": "opensesame"}`)
+	req := httptest.NewRequest("POST", "/create-room", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	h.HandleCreateRoom(w, req)
+
+	var res map[string]string
+	json.NewDecoder(w.Result().Body).Decode(&res)
+	if res["ticket"] == "" {
+		t.Fatal("Expected join ticket for creator of protected room")
+	}
+
+	room := h.roomManager.GetRoom(res["code"])
+	if room == nil || !room.IsProtected() {
+		t.Fatal("Expected protected room to be created")
+	}
+	if !room.ConsumeTicket(res["ticket"]) {
+		t.Error("Expected creator ticket to be valid")
+	}
+}
+
+func TestHandleCreateRoom_PassphraseTooLong(t *testing.T) {
+	h := setupTestHandler()
+
+	body := []byte(`{"name": "Secret", "passphrase": "` + strings.Repeat("a", 200) + `"}`)
+	req := httptest.NewRequest("POST", "/create-room", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	h.HandleCreateRoom(w, req)
+
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for long passphrase, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestHandleJoinRoom_Protected(t *testing.T) {
+	h := setupTestHandler()
+	h.authLimiter = middleware.NewIPRateLimiter(1, 5)
+	room := h.roomManager.CreateRoomWithOptions("Secret", ws.RoomOptions{Passphrase: "opensesame"})
+
+	join := func(body string) *http.Response {
+		req := httptest.NewRequest("POST", "/join-room", bytes.NewBufferString(body))
+		req.RemoteAddr = "10.0.0.1:1234"
+		w := httptest.NewRecorder()
+		h.HandleJoinRoom(w, req)
+		return w.Result()
+	}
+
+	// Missing passphrase
+	if resp := join(`{"code": "` + room.Code + `"}`); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 without passphrase, got %d", resp.StatusCode)
+	}
+
+	// Wrong passphrase
+	if resp := join(`{"code": "` + room.Code + `", "passphrase": "wrong"}`); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for wrong passphrase, got %d", resp.StatusCode)
+	}
+
+	// Correct passphrase
+	resp := join(`{"code": "` + room.Code + `", "passphrase": "opensesame"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 for correct passphrase, got %d", resp.StatusCode)
+	}
+	var res map[string]string
+	json.NewDecoder(resp.Body).Decode(&res)
+	if res["ticket"] == "" {
+		t.Error("Expected join ticket in response")
+	}
+
+	// Single-use invite works once
+	invite := room.CreateInvite(true, 0)
+	if resp := join(`{"code": "` + room.Code + `", "invite": "` + invite.Token + `"}`); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 for valid invite, got %d", resp.StatusCode)
+	}
+	if resp := join(`{"code": "` + room.Code + `", "invite": "` + invite.Token + `"}`); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for reused invite, got %d", resp.StatusCode)
+	}
+}
+
+func TestHandleJoinRoom_WrongAttemptsRateLimited(t *testing.T) {
+	h := setupTestHandler()
+	h.authLimiter = middleware.NewIPRateLimiter(0.001, 2)
+	room := h.roomManager.CreateRoomWithOptions("Secret", ws.RoomOptions{Passphrase: "opensesame"})
+
+	var last int
+	for i := 0; i < 3; i++ {
+		body := []byte(`{"code": "` + room.Code + `", "passphrase": "wrong"}`)
+		req := httptest.NewRequest("POST", "/join-room", bytes.NewBuffer(body))
+		req.RemoteAddr = "10.0.0.2:1234"
+		w := httptest.NewRecorder()
+		h.HandleJoinRoom(w, req)
+		last = w.Result().StatusCode
+	}
+
+	if last != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 after repeated wrong attempts, got %d", last)
+	}
+}
+
+func TestHandleWebSocket_ProtectedRequiresProof(t *testing.T) {
+	h := setupTestHandler()
+	h.authLimiter = middleware.NewIPRateLimiter(1, 5)
+	room := h.roomManager.CreateRoomWithOptions("Secret", ws.RoomOptions{Passphrase: "opensesame"})
+
+	req := httptest.NewRequest("GET", "/ws?room="+room.Code, nil)
+	w := httptest.NewRecorder()
+	h.HandleWebSocket(w, req)
+
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403 without ticket or invite, got %d", w.Result().StatusCode)
+	}
+
+	req = httptest.NewRequest("GET", "/ws?room="+room.Code, nil)
+	req.Header.Set("Sec-WebSocket-Protocol", ws.SubprotocolJSON+", "+ws.SubprotocolTicketPrefix+"bogus")
+	w = httptest.NewRecorder()
+	h.HandleWebSocket(w, req)
+
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403 for bogus ticket, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestHandleWebSocket_ProtectedProofOutsideURL(t *testing.T) {
+	h := setupTestHandler()
+	room := h.roomManager.CreateRoomWithOptions("Secret", ws.RoomOptions{Passphrase: "opensesame"})
+
+	// Proof in the URL would end up in proxy and access logs, so it is not accepted there
+	ticket := room.IssueTicket()
+	req := httptest.NewRequest("GET", "/ws?room="+room.Code+"&ticket="+ticket, nil)
+	w := httptest.NewRecorder()
+	h.HandleWebSocket(w, req)
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403 for a ticket in the URL, got %d", w.Result().StatusCode)
+	}
+
+	byTicket := dialRoom(t, h, room.Code, ws.SubprotocolJSON, ws.SubprotocolTicketPrefix+ticket)
+	readJoin(t, byTicket)
+
+	invite := room.CreateInvite(true, 0)
+	byInvite := dialRoomQuery(t, h, "room="+room.Code+"&auth=frame")
+	auth, _ := json.Marshal(map[string]interface{}{
+		"type":    domain.MessageTypeAuth,
+		"payload": domain.AuthPayload{Invite: invite.Token},
+	})
+	if err := byInvite.WriteMessage(websocket.TextMessage, auth); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	readJoin(t, byInvite)
+}
+
+func TestHandleJoinRoom_BannedIP(t *testing.T) {
+	h := setupTestHandler()
+	room := h.roomManager.CreateRoom("Strict")
+
+	host := ws.NewClient(room.Hub, nil, domain.NewUser("Host", "#000000"))
+	victim := ws.NewClient(room.Hub, nil, domain.NewUser("Victim", "#000000"))
+	victim.SetOrigin("10.0.0.3", "")
+	room.Hub.Register(host)
+	time.Sleep(20 * time.Millisecond)
+	room.Hub.Register(victim)
+	time.Sleep(20 * time.Millisecond)
+	room.Hub.BanUser(host.ID, victim.ID, 0)
+
+	body := []byte(`{"code": "` + room.Code + `"}`)
+	req := httptest.NewRequest("POST", "/join-room", bytes.NewBuffer(body))
+	req.RemoteAddr = "10.0.0.3"
+	w := httptest.NewRecorder()
+	h.HandleJoinRoom(w, req)
+
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403 for banned IP, got %d", w.Result().StatusCode)
+	}
+
+	req = httptest.NewRequest("GET", "/ws?room="+room.Code, nil)
+	req.RemoteAddr = "10.0.0.3"
+	w = httptest.NewRecorder()
+	h.HandleWebSocket(w, req)
+
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("Expected websocket upgrade to be refused for banned IP, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestHandleJoinRoom_KickedIP(t *testing.T) {
+	h := setupTestHandler()
+	room := h.roomManager.CreateRoom("Shared Wifi")
+
+	host := ws.NewClient(room.Hub, nil, domain.NewUser("Host", "#000000"))
+	victim := ws.NewClient(room.Hub, nil, domain.NewUser("Victim", "#000000"))
+	victim.SetOrigin("10.0.0.4", "")
+	room.Hub.Register(host)
+	time.Sleep(20 * time.Millisecond)
+	room.Hub.Register(victim)
+	time.Sleep(20 * time.Millisecond)
+	room.Hub.KickUser(host.ID, victim.ID)
+
+	// A kicked user cannot come straight back as a fresh persona
+	body := []byte(`{"code": "` + room.Code + `"}`)
+	req := httptest.NewRequest("POST", "/join-room", bytes.NewBuffer(body))
+	req.RemoteAddr = "10.0.0.4"
+	w := httptest.NewRecorder()
+	h.HandleJoinRoom(w, req)
+
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403 for kicked IP, got %d", w.Result().StatusCode)
+	}
+}
+
+// dialRoom opens a real WebSocket to a room, offering the given subprotocols
+func dialRoom(t *testing.T, h *Handler, code string, subprotocols ...string) *websocket.Conn {
+	return dialRoomQuery(t, h, "room="+code, subprotocols...)
+}
+
+// dialRoomQuery is dialRoom with a raw query string
+func dialRoomQuery(t *testing.T, h *Handler, query string, subprotocols ...string) *websocket.Conn {
+	server := httptest.NewServer(http.HandlerFunc(h.HandleWebSocket))
+	t.Cleanup(server.Close)
+
+	dialer := websocket.Dialer{Subprotocols: subprotocols}
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?" + query
+	conn, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestHandleWebSocket_FullRoomRejectedBeforeUpgrade(t *testing.T) {
+	h := setupTestHandler()
+	room := h.roomManager.CreateRoomWithOptions("Sempit", ws.RoomOptions{MaxOccupancy: 1})
+
+	first := dialRoom(t, h, room.Code)
+	readJoin(t, first)
+
+	server := httptest.NewServer(http.HandlerFunc(h.HandleWebSocket))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?room=" + room.Code
+
+	sessions := ws.GlobalSessionStore.Count()
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil {
+		t.Fatal("Expected a full room to refuse the upgrade")
+	}
+	if resp == nil || resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409, got %v", resp)
+	}
+	if ws.GlobalSessionStore.Count() != sessions {
+		t.Error("A refused connection must not mint a session token")
+	}
+}
+
+func TestHandleWebSocket_DefaultsToJSON(t *testing.T) {
+	h := setupTestHandler()
+	room := h.roomManager.CreateRoom("Plain")
+
+	conn := dialRoom(t, h, room.Code)
+	if conn.Subprotocol() != "" {
+		t.Errorf("Expected no subprotocol, got %q", conn.Subprotocol())
+	}
+
+	readJSONUntil(t, conn, domain.MessageTypeIdentity)
+}
+
+func TestHandleWebSocket_MsgpackSubprotocol(t *testing.T) {
+	h := setupTestHandler()
+	room := h.roomManager.CreateRoom("Packed")
+
+	conn := dialRoom(t, h, room.Code, ws.SubprotocolMsgpack, ws.SubprotocolJSON)
+	if conn.Subprotocol() != ws.SubprotocolMsgpack {
+		t.Fatalf("Expected %s, got %q", ws.SubprotocolMsgpack, conn.Subprotocol())
+	}
+
+	readFrame := func() map[string]interface{} {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		kind, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		if kind != websocket.BinaryMessage {
+			t.Fatalf("Expected binary frame, got %d", kind)
+		}
+		var frame map[string]interface{}
+		if err := msgpack.Unmarshal(data, &frame); err != nil {
+			t.Fatalf("Frame is not MessagePack: %v", err)
+		}
+		return frame
+	}
+
+	// The session token is queued ahead of the identity
+	identity := false
+	for i := 0; i < 20 && !identity; i++ {
+		identity = readFrame()["type"] == string(domain.MessageTypeIdentity)
+	}
+	if !identity {
+		t.Fatal("Expected an identity frame")
+	}
+
+	out, _ := msgpack.Marshal(map[string]interface{}{
+		"type":    "chat",
+		"payload": map[string]interface{}{"text": "halo"},
+	})
+	if err := conn.WriteMessage(websocket.BinaryMessage, out); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		frame := readFrame()
+		if frame["type"] != string(domain.MessageTypeChat) {
+			continue
+		}
+		// The payload travels as binary holding its JSON encoding
+		raw, _ := frame["payload"].([]byte)
+		var payload map[string]interface{}
+		json.Unmarshal(raw, &payload)
+		if payload["text"] != "halo" {
+			t.Errorf("Expected chat text to round-trip, got %v", payload)
+		}
+		return
+	}
+	t.Error("Expected chat broadcast back over MessagePack")
+}
+
+func TestHandleWebSocket_NegotiatesCompression(t *testing.T) {
+	h := setupTestHandler()
+	room := h.roomManager.CreateRoom("Deflate")
+
+	server := httptest.NewServer(http.HandlerFunc(h.HandleWebSocket))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?room=" + room.Code
+
+	dialer := websocket.Dialer{EnableCompression: true}
+	conn, resp, err := dialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	if ext := resp.Header.Get("Sec-WebSocket-Extensions"); !strings.Contains(ext, "permessage-deflate") {
+		t.Errorf("Expected permessage-deflate, got %q", ext)
+	}
+
+	// Compressed frames still decode to plain JSON
+	readJSONUntil(t, conn, domain.MessageTypeIdentity)
+}
+
+func TestHandleWebSocket_CompressionDisabled(t *testing.T) {
+	h := setupTestHandler()
+	h.upgrader.EnableCompression = false
+	room := h.roomManager.CreateRoom("NoDeflate")
+
+	server := httptest.NewServer(http.HandlerFunc(h.HandleWebSocket))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?room=" + room.Code
+
+	dialer := websocket.Dialer{EnableCompression: true}
+	conn, resp, err := dialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	if ext := resp.Header.Get("Sec-WebSocket-Extensions"); ext != "" {
+		t.Errorf("Expected no extensions, got %q", ext)
+	}
+}
+
+// readJSONUntil reads text frames until one carries a message of msgType, and returns it
+func readJSONUntil(t *testing.T, conn *websocket.Conn, msgType domain.MessageType) domain.Message {
+	t.Helper()
+	for i := 0; i < 20; i++ {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		kind, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		if kind != websocket.TextMessage {
+			t.Fatalf("Expected text frame, got %d", kind)
+		}
+		for _, raw := range bytes.Split(data, []byte{'\n'}) {
+			var msg domain.Message
+			if json.Unmarshal(raw, &msg) == nil && msg.Type == msgType {
+				return msg
+			}
+		}
+	}
+	t.Fatalf("Expected a JSON %s frame", msgType)
+	return domain.Message{}
+}
+
+// readJoin reads JSON frames until both the identity and the session token arrive
+// Returns the identity frame and the reconnect token issued on connect
+func readJoin(t *testing.T, conn *websocket.Conn) (domain.Message, string) {
+	t.Helper()
+	var identity domain.Message
+	var token string
+	for i := 0; i < 20 && (identity.FromID == "" || token == ""); i++ {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		for _, raw := range bytes.Split(data, []byte{'\n'}) {
+			var msg domain.Message
+			if json.Unmarshal(raw, &msg) != nil {
+				continue
+			}
+			switch msg.Type {
+			case domain.MessageTypeIdentity:
+				identity = msg
+			case domain.MessageTypeSessionToken:
+				var payload domain.SessionTokenPayload
+				json.Unmarshal(msg.Payload, &payload)
+				token = payload.Token
+			}
+		}
+	}
+	if identity.FromID == "" || token == "" {
+		t.Fatal("Expected identity and session token frames")
+	}
+	return identity, token
+}
+
+func TestHandleWebSocket_ResumesFromSubprotocolAndRotates(t *testing.T) {
+	h := setupTestHandler()
+	room := h.roomManager.CreateRoom("Resume")
+
+	first := dialRoom(t, h, room.Code)
+	identity, token := readJoin(t, first)
+	first.Close()
+
+	second := dialRoom(t, h, room.Code, ws.SubprotocolJSON, ws.SubprotocolTokenPrefix+token)
+	if second.Subprotocol() != ws.SubprotocolJSON {
+		t.Errorf("Expected %s to be selected (token never echoed), got %q", ws.SubprotocolJSON, second.Subprotocol())
+	}
+	got, rotated := readJoin(t, second)
+	if got.FromName != identity.FromName {
+		t.Errorf("Expected persona %q to be restored, got %q", identity.FromName, got.FromName)
+	}
+	if got.FromID != identity.FromID {
+		t.Errorf("Expected user ID %s to survive the reconnect, got %s", identity.FromID, got.FromID)
+	}
+	if rotated == token {
+		t.Error("Expected a fresh token after resume")
+	}
+	if _, valid := ws.GlobalSessionStore.ValidateToken(token); valid {
+		t.Error("Expected the used token to be invalidated")
+	}
+}
+
+func TestHandleWebSocket_RefusedResumeKeepsToken(t *testing.T) {
+	h := setupTestHandler()
+	room := h.roomManager.CreateRoom("Refused")
+
+	host := ws.NewClient(room.Hub, nil, domain.NewUser("Host", "#000000"))
+	neighbour := ws.NewClient(room.Hub, nil, domain.NewUser("Neighbour", "#000000"))
+	neighbour.SetOrigin("10.0.0.9", "")
+	room.Hub.Register(host)
+	time.Sleep(20 * time.Millisecond)
+	room.Hub.Register(neighbour)
+	time.Sleep(20 * time.Millisecond)
+
+	first := dialRoom(t, h, room.Code)
+	_, token := readJoin(t, first)
+	first.Close()
+
+	// Someone else on the network this user moves to gets banned with their IP
+	room.Hub.BanUser(host.ID, neighbour.ID, 0)
+
+	req := httptest.NewRequest("GET", "/ws?room="+room.Code, nil)
+	req.RemoteAddr = "10.0.0.9"
+	req.Header.Set("Sec-WebSocket-Protocol", ws.SubprotocolTokenPrefix+token)
+	w := httptest.NewRecorder()
+	h.HandleWebSocket(w, req)
+
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("Expected the connection from a banned IP to be refused, got %d", w.Result().StatusCode)
+	}
+	if _, valid := ws.GlobalSessionStore.ValidateToken(token); !valid {
+		t.Error("A refused resume must not consume the token")
+	}
+}
+
+func TestHandleWebSocket_KickRevokesToken(t *testing.T) {
+	h := setupTestHandler()
+	room := h.roomManager.CreateRoom("Kicked")
+
+	host := ws.NewClient(room.Hub, nil, domain.NewUser("Host", "#000000"))
+	room.Hub.Register(host)
+	time.Sleep(20 * time.Millisecond)
+
+	conn := dialRoom(t, h, room.Code)
+	identity, token := readJoin(t, conn)
+	room.Hub.KickUser(host.ID, identity.FromID)
+	conn.Close()
+
+	if _, valid := ws.GlobalSessionStore.ValidateToken(token); valid {
+		t.Error("Expected kicked user's session token to be revoked")
+	}
+
+	req := httptest.NewRequest("GET", "/ws?room="+room.Code, nil)
+	req.RemoteAddr = "127.0.0.1:40000"
+	req.Header.Set("Sec-WebSocket-Protocol", ws.SubprotocolTokenPrefix+token)
+	w := httptest.NewRecorder()
+	h.HandleWebSocket(w, req)
+
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("Expected a kicked user's IP to be refused, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestHandleWebSocket_EveryTabResumes(t *testing.T) {
+	h := setupTestHandler()
+	room := h.roomManager.CreateRoom("Tabs")
+
+	tabA := dialRoom(t, h, room.Code)
+	identity, tokenA := readJoin(t, tabA)
+
+	// A duplicated tab starts out with the first tab's token
+	tabB := dialRoom(t, h, room.Code, ws.SubprotocolJSON, ws.SubprotocolTokenPrefix+tokenA)
+	joinedB, tokenB := readJoin(t, tabB)
+	if joinedB.FromID != identity.FromID {
+		t.Fatal("Expected the second tab to join as the same user")
+	}
+
+	// The first tab's token was spent, so it is handed a new one
+	var reissued domain.SessionTokenPayload
+	json.Unmarshal(readJSONUntil(t, tabA, domain.MessageTypeSessionToken).Payload, &reissued)
+	if reissued.Token == "" || reissued.Token == tokenA {
+		t.Fatalf("Expected the first tab to get a fresh token, got %q", reissued.Token)
+	}
+
+	// Each tab drops and reconnects on its own token
+	tabA.Close()
+	tabA = dialRoom(t, h, room.Code, ws.SubprotocolJSON, ws.SubprotocolTokenPrefix+reissued.Token)
+	if got, _ := readJoin(t, tabA); got.FromID != identity.FromID {
+		t.Error("Expected the first tab to resume the same user")
+	}
+
+	tabB.Close()
+	tabB = dialRoom(t, h, room.Code, ws.SubprotocolJSON, ws.SubprotocolTokenPrefix+tokenB)
+	if got, _ := readJoin(t, tabB); got.FromID != identity.FromID {
+		t.Error("Expected the second tab to resume the same user")
+	}
+	tabA.Close()
+	tabB.Close()
+}
+
+func TestHandleWebSocket_TokenForAnotherRoomIsNotConsumed(t *testing.T) {
+	h := setupTestHandler()
+	home := h.roomManager.CreateRoom("Home")
+	other := h.roomManager.CreateRoom("Other")
+
+	first := dialRoom(t, h, home.Code)
+	identity, token := readJoin(t, first)
+	first.Close()
+
+	elsewhere := dialRoom(t, h, other.Code, ws.SubprotocolJSON, ws.SubprotocolTokenPrefix+token)
+	if got, _ := readJoin(t, elsewhere); got.FromID == identity.FromID {
+		t.Error("A token must not resume a session in another room")
+	}
+
+	back := dialRoom(t, h, home.Code, ws.SubprotocolJSON, ws.SubprotocolTokenPrefix+token)
+	if got, _ := readJoin(t, back); got.FromID != identity.FromID {
+		t.Errorf("Expected the token to still resume user %s, got %s", identity.FromID, got.FromID)
+	}
+}
+
+func TestHandleWebSocket_ResumesFromAuthFrame(t *testing.T) {
+	h := setupTestHandler()
+	room := h.roomManager.CreateRoom("Auth Frame")
+
+	first := dialRoom(t, h, room.Code)
+	identity, token := readJoin(t, first)
+	first.Close()
+
+	second := dialRoomQuery(t, h, "room="+room.Code+"&auth=frame")
+	auth, _ := json.Marshal(map[string]interface{}{
+		"type":    domain.MessageTypeAuth,
+		"payload": domain.AuthPayload{Token: token},
+	})
+	if err := second.WriteMessage(websocket.TextMessage, auth); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if got, _ := readJoin(t, second); got.FromID != identity.FromID {
+		t.Errorf("Expected user %s to be restored, got %s", identity.FromID, got.FromID)
+	}
+}
+
+func TestHandleWebSocket_AuthFrameUsesRoomMessageLimit(t *testing.T) {
+	h := NewHandler(ws.NewRoomManager(ws.WithMaxMessageSize(8192)), usecase.NewPersonaGenerator())
+	room := h.roomManager.CreateRoom("Big Frames")
+
+	conn := dialRoomQuery(t, h, "room="+room.Code+"&auth=frame")
+	auth, _ := json.Marshal(map[string]interface{}{
+		"type":    domain.MessageTypeAuth,
+		"payload": domain.AuthPayload{},
+		"padding": strings.Repeat("x", domain.MaxMessageSize),
+	})
+	if err := conn.WriteMessage(websocket.TextMessage, auth); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	readJoin(t, conn)
+}
+
+func TestHandleWebSocket_AuthFrameRequired(t *testing.T) {
+	h := setupTestHandler()
+	room := h.roomManager.CreateRoom("Auth Required")
+
+	conn := dialRoomQuery(t, h, "room="+room.Code+"&auth=frame")
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"chat","payload":{"text":"halo"}}`))
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+		t.Errorf("Expected policy violation close, got %v", err)
+	}
+}
+
+func TestHandleWebSocket_IgnoresTokenInURL(t *testing.T) {
+	h := setupTestHandler()
+	room := h.roomManager.CreateRoom("No URL Tokens")
+
+	first := dialRoom(t, h, room.Code)
+	_, token := readJoin(t, first)
+	first.Close()
+
+	second := dialRoomQuery(t, h, "room="+room.Code+"&token="+token)
+	readJoin(t, second)
+
+	// Not consumed: the URL is not a place tokens are accepted from
+	if _, valid := ws.GlobalSessionStore.ValidateToken(token); !valid {
+		t.Error("Expected a token in the URL to be ignored")
+	}
+}
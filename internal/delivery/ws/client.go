@@ -2,6 +2,8 @@ package ws
 
 import (
 	"encoding/json"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,9 +20,6 @@ const (
 
 	// Send pings to peer with this period (must be less than pongWait)
 	pingPeriod = (pongWait * 9) / 10
-
-	// Maximum message size allowed from peer
-	maxMessageSize = 4096
 )
 
 // Client represents a single websocket connection
@@ -30,17 +29,95 @@ type Client struct {
 	hub  *Hub
 	conn *websocket.Conn
 	send chan []byte
+
+	// Guards send against use after closeSend; the pumps and handlers keep sending after a rejection
+	sendMu     sync.RWMutex
+	sendClosed bool
+
+	waiting atomic.Bool // Parked in the knock-to-enter lobby
+	left    bool        // Unregistered; guarded by hub.mu
+
+	remoteIP string // Recorded for bans
+	lineage  string // Session token lineage, recorded for bans
+	token    string // Reconnect token issued to this connection; guarded by hub.mu once registered
+
+	// Message being dispatched by ReadPump, echoed in ack and error frames
+	inflightID     atomic.Value // string
+	inflightFailed atomic.Bool
+
+	// Negotiated in the hello handshake; nil means a v1 client
+	protocol  atomic.Pointer[clientProtocol]
+	saidHello atomic.Bool // Only the first hello on a connection is honoured
+
+	// Wire encoding, fixed at upgrade by the Sec-WebSocket-Protocol subprotocol
+	codec Codec
+
+	// Smallest write worth deflating; 0 compresses everything
+	compressThreshold int
+
+	// Largest inbound frame, from the room unless overridden
+	maxMessageSize int64
+
+	// Last history seq the client saw before reconnecting (0 = send everything)
+	resumeSeq atomic.Uint64
+}
+
+// ClientOption overrides a per-connection setting when creating a Client
+type ClientOption func(*Client)
+
+// WithCompressionThreshold sets the smallest write that gets deflated
+// Only matters when permessage-deflate was negotiated at upgrade
+func WithCompressionThreshold(threshold int) ClientOption {
+	return func(c *Client) {
+		c.compressThreshold = threshold
+	}
+}
+
+// WithReadLimit sets the largest inbound frame, overriding the room's limit
+func WithReadLimit(n int) ClientOption {
+	return func(c *Client) {
+		if n > 0 {
+			c.maxMessageSize = int64(n)
+		}
+	}
 }
 
 // NewClient creates a new Client
-func NewClient(hub *Hub, conn *websocket.Conn, user *domain.User) *Client {
-	return &Client{
-		ID:   user.ID.String(),
-		User: user,
-		hub:  hub,
-		conn: conn,
-		send: make(chan []byte, 1024),
+func NewClient(hub *Hub, conn *websocket.Conn, user *domain.User, opts ...ClientOption) *Client {
+	c := &Client{
+		ID:             user.ID.String(),
+		User:           user,
+		hub:            hub,
+		conn:           conn,
+		send:           make(chan []byte, 1024),
+		maxMessageSize: hub.maxMessageSize,
+	}
+	if conn != nil {
+		c.codec = codecForSubprotocol(conn.Subprotocol())
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	// Ignore input until the hub decides whether to admit
+	c.waiting.Store(hub.knockToEnter)
+	return c
+}
+
+// SetResumeSeq makes the hub replay only history after seq when the client registers
+func (c *Client) SetResumeSeq(seq uint64) {
+	c.resumeSeq.Store(seq)
+}
+
+// SetOrigin records where the connection came from so bans can match it
+func (c *Client) SetOrigin(remoteIP, lineage string) {
+	c.remoteIP = remoteIP
+	c.lineage = lineage
+}
+
+// SetSessionToken records the reconnect token handed to this connection
+// The hub replaces it if another connection of the same user redeems it
+func (c *Client) SetSessionToken(token string) {
+	c.token = token
 }
 
 // ReadPump pumps messages from the websocket connection to the hub
@@ -50,15 +127,17 @@ func (c *Client) ReadPump() {
 		c.conn.Close()
 	}()
 
-	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadLimit(c.maxMessageSize)
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
 	c.conn.SetPongHandler(func(string) error {
 		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		// Idle expiry only counts time spent disconnected
+		GlobalSessionStore.Touch(c.User.ID.String())
 		return nil
 	})
 
 	for {
-		_, message, err := c.conn.ReadMessage()
+		messageType, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				// Silent close - no logging for privacy
@@ -66,10 +145,21 @@ func (c *Client) ReadPump() {
 			break
 		}
 
+		// MessagePack clients send binary frames; everything below speaks JSON
+		if messageType == websocket.BinaryMessage {
+			if c.codec != CodecMsgpack {
+				continue
+			}
+			if message, err = msgpackToJSON(message); err != nil {
+				continue
+			}
+		}
+
 		// Parse incoming message
 		var incoming struct {
-			Type    string          `json:"type"`
-			Payload json.RawMessage `json:"payload"`
+			Type        string          `json:"type"`
+			Payload     json.RawMessage `json:"payload"`
+			ClientMsgID string          `json:"client_msg_id"`
 		}
 
 		if err := json.Unmarshal(message, &incoming); err != nil {
@@ -86,72 +176,240 @@ func (c *Client) ReadPump() {
 			FromColor: c.User.PersonaColor,
 			Payload:   incoming.Payload,
 			CreatedAt: time.Now(),
-		}
-
 
-		// Handle specific message types
-		switch msg.Type {
-		case domain.MessageTypeKick:
-			var payload map[string]string
-			if err := json.Unmarshal(msg.Payload, &payload); err == nil {
-				if targetID, ok := payload["target_id"]; ok {
-					c.hub.KickUser(c.ID, targetID)
-				}
-			}
-			continue
+			ClientMsgID: normalizeClientMsgID(incoming.ClientMsgID),
+		}
 
-		case domain.MessageTypeTransfer:
-			var payload map[string]string
-			if err := json.Unmarshal(msg.Payload, &payload); err == nil {
-				if newHostID, ok := payload["new_host_id"]; ok {
-					c.hub.TransferHost(c.ID, newHostID)
-				}
-			}
+		// Handshake is allowed even while waiting in the lobby
+		if msg.Type == domain.MessageTypeHello {
+			c.handshake(msg)
 			continue
-
-		case domain.MessageTypeStatusUpdate:
-			var status domain.StatusUpdatePayload
-			if err := json.Unmarshal(incoming.Payload, &status); err == nil {
-				if status.Battery > 0 {
-					c.User.BatteryLevel = status.Battery
-				}
-			}
 		}
 
-		// Handle music control
-		if msg.Type == domain.MessageTypeMusic {
-			c.hub.HandleMusic(c, &msg)
+		// Clients waiting for host approval cannot talk to the room
+		if c.waiting.Load() {
 			continue
 		}
 
-		if msg.Type == domain.MessageTypeMusicApprove {
-			c.hub.HandleMusicApprove(c, &msg)
-			continue
+		c.beginRequest(msg.ClientMsgID)
+		accepted := c.dispatch(msg)
+		c.finishRequest(msg.ID, accepted)
+	}
+}
+
+// handshake answers a hello frame, charging it to the flood bucket like any other message
+func (c *Client) handshake(msg domain.Message) {
+	if !c.hub.allowInbound(c, msg.Type) {
+		return
+	}
+	c.hub.HandleHello(c, msg)
+}
+
+// dispatch validates an inbound message and routes it to its handler
+// Returns false when the message was dropped or rejected
+func (c *Client) dispatch(msg domain.Message) bool {
+	// Drop types clients may not send; reject or normalize malformed payloads
+	payload, err := validatePayload(msg.Type, msg.Payload)
+	if err == errUnknownMessageType {
+		return false
+	}
+	if err != nil {
+		c.hub.sendError(c, domain.ErrCodeInvalidPayload, err.Error())
+		return false
+	}
+	msg.Payload = payload
+
+	// Muted or timed-out users still receive messages but cannot send them
+	if c.hub.Silenced(c, msg.Type) {
+		if msg.Type != domain.MessageTypeTyping {
+			c.hub.sendSilencedError(c)
 		}
-		
-		if msg.Type == domain.MessageTypePartyChange {
-			c.hub.HandlePartyChange(c, msg)
-			continue
+		return false
+	}
+
+	// Per-user token buckets and room-wide chaos cooldown
+	if !c.hub.allowInbound(c, msg.Type) {
+		return false
+	}
+
+	// Handle specific message types
+	switch msg.Type {
+	case domain.MessageTypeKick:
+		var payload map[string]string
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil || payload["target_id"] == "" {
+			c.hub.sendError(c, domain.ErrCodeInvalidPayload, "Format kick tidak valid")
+			return false
 		}
+		c.hub.KickUser(c.ID, payload["target_id"])
+		return true
 
-		if msg.Type == domain.MessageTypeMusicReject {
-			c.hub.HandleMusicReject(c, &msg)
-			continue
+	case domain.MessageTypeInvite:
+		c.hub.HandleInvite(c, msg)
+		return true
+
+	case domain.MessageTypeAdmitApprove:
+		c.hub.HandleAdmitApprove(c, msg)
+		return true
+
+	case domain.MessageTypeAdmitDeny:
+		c.hub.HandleAdmitDeny(c, msg)
+		return true
+
+	case domain.MessageTypeBan:
+		c.hub.HandleBan(c, msg)
+		return true
+
+	case domain.MessageTypeUnban:
+		c.hub.HandleUnban(c, msg)
+		return true
+
+	case domain.MessageTypeBanList:
+		c.hub.HandleBanList(c)
+		return true
+
+	case domain.MessageTypeMute, domain.MessageTypeTimeout, domain.MessageTypeUnmute:
+		c.hub.HandleModeration(c, msg)
+		return true
+
+	case domain.MessageTypeGrantModerator, domain.MessageTypeRevokeModerator:
+		c.hub.HandleRoleChange(c, msg)
+		return true
+
+	case domain.MessageTypePresenceResync:
+		c.hub.HandlePresenceResync(c)
+		return true
+
+	case domain.MessageTypeHistoryRequest:
+		c.hub.HandleHistoryRequest(c, msg)
+		return true
+
+	case domain.MessageTypeLogout:
+		c.hub.HandleLogout(c)
+		return true
+
+	case domain.MessageTypeTransfer:
+		var payload map[string]string
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil || payload["new_host_id"] == "" {
+			c.hub.sendError(c, domain.ErrCodeInvalidPayload, "Format transfer host tidak valid")
+			return false
 		}
+		c.hub.TransferHost(c.ID, payload["new_host_id"])
+		return true
 
-		if msg.Type == domain.MessageTypeNobar {
-			c.hub.HandleNobar(c, msg)
-			continue
+	case domain.MessageTypeStatusUpdate:
+		var status domain.StatusUpdatePayload
+		if err := json.Unmarshal(msg.Payload, &status); err == nil {
+			if status.Battery > 0 {
+				c.User.BatteryLevel = status.Battery
+			}
 		}
+	}
 
-		// Broadcast message to all clients
-		data, err := json.Marshal(msg)
-		if err != nil {
-			continue
+	// Handle music control
+	if msg.Type == domain.MessageTypeMusic {
+		c.hub.HandleMusic(c, &msg)
+		return true
+	}
+
+	if msg.Type == domain.MessageTypeMusicApprove {
+		c.hub.HandleMusicApprove(c, &msg)
+		return true
+	}
+	
+	if msg.Type == domain.MessageTypePartyChange {
+		c.hub.HandlePartyChange(c, msg)
+		return true
+	}
+
+	if msg.Type == domain.MessageTypeMusicReject {
+		c.hub.HandleMusicReject(c, &msg)
+		return true
+	}
+
+	if msg.Type == domain.MessageTypeNobar {
+		c.hub.HandleNobar(c, msg)
+		return true
+	}
+
+	if msg.Type == domain.MessageTypeWhisper {
+		c.hub.HandleWhisper(c, msg)
+		return true
+	}
+
+	// Game results and poll tallies are resolved server-side
+	switch msg.Type {
+	case domain.MessageTypeDice:
+		c.hub.HandleDice(c, msg)
+		return true
+	case domain.MessageTypeFlip:
+		c.hub.HandleFlip(c, msg)
+		return true
+	case domain.MessageTypeTod:
+		c.hub.HandleTod(c, msg)
+		return true
+	case domain.MessageTypePoll:
+		c.hub.HandlePoll(c, msg)
+		return true
+	case domain.MessageTypeVote:
+		c.hub.HandleVote(c, msg)
+		return true
+	case domain.MessageTypePollClose:
+		c.hub.HandlePollClose(c, msg)
+		return true
+	case domain.MessageTypeSuit:
+		c.hub.HandleSuit(c, msg)
+		return true
+	case domain.MessageTypeSuitScoreboard:
+		c.hub.HandleSuitScoreboard(c)
+		return true
+	case domain.MessageTypeScoreboard:
+		c.hub.HandleScoreboard(c)
+		return true
+	}
+
+	// Broadcast message to all clients
+	c.hub.BroadcastMessage(msg)
+	return true
+}
+
+// normalizeClientMsgID keeps a usable client message id, or "" when missing or malformed
+func normalizeClientMsgID(id string) string {
+	if id == "" || len(id) > domain.MaxClientMsgIDLength {
+		return ""
+	}
+	for _, r := range id {
+		if r < 0x20 || r == 0x7f {
+			return ""
 		}
+	}
+	return id
+}
 
-		c.hub.Broadcast(data)
+// beginRequest records the message ReadPump is about to dispatch
+func (c *Client) beginRequest(clientMsgID string) {
+	c.inflightID.Store(clientMsgID)
+	c.inflightFailed.Store(false)
+}
+
+// failRequest marks the in-flight message as rejected and returns its client id
+// Called by sendError; errors are only ever triggered by the client's own messages
+func (c *Client) failRequest() string {
+	id, _ := c.inflightID.Load().(string)
+	if id != "" {
+		c.inflightFailed.Store(true)
 	}
+	return id
+}
+
+// finishRequest acks the dispatched message unless it was dropped or rejected
+func (c *Client) finishRequest(messageID string, accepted bool) {
+	id, _ := c.inflightID.Load().(string)
+	c.inflightID.Store("")
+
+	if id == "" || !accepted || c.inflightFailed.Load() {
+		return
+	}
+	c.hub.sendAck(c, id, messageID)
 }
 
 // WritePump pumps messages from the hub to the websocket connection
@@ -172,17 +430,36 @@ func (c *Client) WritePump() {
 				return
 			}
 
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
+			// Binary frames cannot be newline-batched, so each goes out on its own
+			if c.codec == CodecMsgpack {
+				c.conn.EnableWriteCompression(c.shouldCompress(len(message)))
+				if err := c.conn.WriteMessage(websocket.BinaryMessage, message); err != nil {
+					return
+				}
+				continue
 			}
-			w.Write(message)
 
 			// Add queued messages to the current websocket message
+			batch := [][]byte{message}
+			size := len(message)
 			n := len(c.send)
 			for i := 0; i < n; i++ {
-				w.Write([]byte{'\n'})
-				w.Write(<-c.send)
+				queued := <-c.send
+				batch = append(batch, queued)
+				size += len(queued) + 1
+			}
+
+			// Small batches are not worth the deflate overhead
+			c.conn.EnableWriteCompression(c.shouldCompress(size))
+			w, err := c.conn.NextWriter(websocket.TextMessage)
+			if err != nil {
+				return
+			}
+			for i, msg := range batch {
+				if i > 0 {
+					w.Write([]byte{'\n'})
+				}
+				w.Write(msg)
 			}
 
 			if err := w.Close(); err != nil {
@@ -198,11 +475,61 @@ func (c *Client) WritePump() {
 	}
 }
 
-// Send adds a message to the client's send queue
+// shouldCompress reports whether a write of this size is worth deflating
+func (c *Client) shouldCompress(size int) bool {
+	return size >= c.compressThreshold
+}
+
+// Send adds a JSON-encoded message to the client's send queue
+// MessagePack clients get it re-encoded first
 func (c *Client) Send(msg []byte) {
+	if c.codec == CodecMsgpack {
+		if msg = newJSONFrame(msg).Bytes(CodecMsgpack); msg == nil {
+			return
+		}
+	}
+
+	c.enqueue(msg)
+}
+
+// SendFrame queues a frame in the connection's encoding
+// Frames sent to several clients are encoded once per codec, not once per client
+func (c *Client) SendFrame(f *Frame) {
+	if data := f.Bytes(c.codec); data != nil {
+		c.enqueue(data)
+	}
+}
+
+// SendMessage encodes and queues a message for this connection only
+func (c *Client) SendMessage(msg domain.Message) {
+	c.SendFrame(NewFrame(msg))
+}
+
+// enqueue queues an encoded frame without blocking, reporting false when the buffer is full
+// Frames for a connection whose queue was closed are dropped
+func (c *Client) enqueue(msg []byte) bool {
+	c.sendMu.RLock()
+	defer c.sendMu.RUnlock()
+
+	if c.sendClosed {
+		return true
+	}
 	select {
 	case c.send <- msg:
+		return true
 	default:
-		// Buffer full
+		return false
+	}
+}
+
+// closeSend closes the outbound queue, which makes WritePump close the connection
+// Safe to call more than once and concurrently with Send
+func (c *Client) closeSend() {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if !c.sendClosed {
+		c.sendClosed = true
+		close(c.send)
 	}
 }
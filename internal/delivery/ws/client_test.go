@@ -2,8 +2,11 @@ package ws
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/mmuslimabdulj/goat-chat/internal/domain"
 )
 
@@ -167,27 +170,21 @@ func TestClient_MessageRouting_RestorePersona(t *testing.T) {
 	}
 
 	hub.mu.RLock()
-	originalHostPersona := hub.hostPersona
+	originalHostID := hub.hostID
 	hub.mu.RUnlock()
 
-	if originalHostPersona != "OriginalHost" {
-		t.Errorf("Expected host persona 'OriginalHost', got %s", originalHostPersona)
+	if originalHostID != host.ID {
+		t.Errorf("Expected host %s, got %s", host.ID, originalHostID)
 	}
 
-	// Test ReclaimHost (simulating restore_persona flow)
-	newClient := newMockClient(hub, "OriginalHost") // Same persona, different ID
+	// Restored session (same user ID) keeps host without any reclaim step
+	newClient := reconnectMockClient(hub, host)
 	hub.Register(newClient)
-
-	// Wait for registration
-	for hub.ClientCount() < 2 {
-	}
-
-	// Reclaim host
-	hub.ReclaimHost(newClient.ID, "OriginalHost")
+	time.Sleep(30 * time.Millisecond)
 
 	hub.mu.RLock()
-	if hub.hostID != newClient.ID {
-		t.Error("Expected new client to reclaim host")
+	if hub.hostID != newClient.ID || len(hub.conns[newClient.ID]) != 2 {
+		t.Error("Expected restored client to be host, as a second connection of the same user")
 	}
 	hub.mu.RUnlock()
 }
@@ -265,6 +262,140 @@ func TestClient_MessageTypes_AllRouted(t *testing.T) {
 
 // === DOMAIN TESTS ===
 
+// dispatchInbound runs a message through the same path ReadPump uses
+func dispatchInbound(c *Client, msgType domain.MessageType, payload, clientMsgID string) domain.Message {
+	msg := domain.Message{
+		ID:          uuid.New().String(),
+		Type:        msgType,
+		FromID:      c.ID,
+		FromName:    c.User.PersonaName,
+		Payload:     json.RawMessage(payload),
+		CreatedAt:   time.Now(),
+		ClientMsgID: normalizeClientMsgID(clientMsgID),
+	}
+	c.beginRequest(msg.ClientMsgID)
+	accepted := c.dispatch(msg)
+	c.finishRequest(msg.ID, accepted)
+	return msg
+}
+
+func TestClient_Ack_AcceptedMessage(t *testing.T) {
+	hub := NewHub()
+	member := withAllFeatures(newMockClient(hub, "Member"))
+	setupRoom(t, hub, withAllFeatures(newMockClient(hub, "Host")), member)
+	time.Sleep(50 * time.Millisecond)
+	drainAll(member)
+
+	msg := dispatchInbound(member, domain.MessageTypeChat, `{"text":"halo"}`, "c-1")
+
+	ack, ok := drainForType(member, domain.MessageTypeAck)
+	if !ok {
+		t.Fatal("Expected ack frame")
+	}
+	var payload domain.AckPayload
+	json.Unmarshal(ack.Payload, &payload)
+	if payload.ClientMsgID != "c-1" || payload.MessageID != msg.ID {
+		t.Errorf("Unexpected ack payload: %+v", payload)
+	}
+}
+
+func TestClient_Ack_EchoedOnBroadcast(t *testing.T) {
+	hub := NewHub()
+	host := withAllFeatures(newMockClient(hub, "Host"))
+	member := withAllFeatures(newMockClient(hub, "Member"))
+	setupRoom(t, hub, host, member)
+	time.Sleep(50 * time.Millisecond)
+	drainAll(host, member)
+
+	dispatchInbound(member, domain.MessageTypeChat, `{"text":"halo"}`, "c-2")
+
+	chat, ok := drainForType(host, domain.MessageTypeChat)
+	if !ok {
+		t.Fatal("Expected chat broadcast")
+	}
+	if chat.ClientMsgID != "c-2" {
+		t.Errorf("Expected broadcast to carry client_msg_id, got %q", chat.ClientMsgID)
+	}
+}
+
+func TestClient_Ack_NotSentWithoutClientMsgID(t *testing.T) {
+	hub := NewHub()
+	member := withAllFeatures(newMockClient(hub, "Member"))
+	setupRoom(t, hub, withAllFeatures(newMockClient(hub, "Host")), member)
+	time.Sleep(50 * time.Millisecond)
+	drainAll(member)
+
+	dispatchInbound(member, domain.MessageTypeChat, `{"text":"halo"}`, "")
+
+	if _, ok := drainForType(member, domain.MessageTypeAck); ok {
+		t.Error("Did not expect an ack without client_msg_id")
+	}
+}
+
+func TestClient_Ack_RejectedMessageGetsError(t *testing.T) {
+	tests := []struct {
+		name    string
+		sender  func(host, member *Client) *Client
+		msgType domain.MessageType
+		payload string
+		code    string
+	}{
+		{"Invalid payload", func(h, m *Client) *Client { return m }, domain.MessageTypeChat, `{"text":""}`, domain.ErrCodeInvalidPayload},
+		{"Unknown kick target", func(h, m *Client) *Client { return h }, domain.MessageTypeKick, `{"target_id":"ghost"}`, domain.ErrCodeUserOffline},
+		{"Non-host music control", func(h, m *Client) *Client { return m }, domain.MessageTypeMusic, `{"action":"next"}`, domain.ErrCodeForbidden},
+		{"Invalid YouTube ID", func(h, m *Client) *Client { return h }, domain.MessageTypeMusic, `{"action":"play","video_id":"nope"}`, domain.ErrCodeInvalidPayload},
+		{"Non-host transfer", func(h, m *Client) *Client { return m }, domain.MessageTypeTransfer, `{"new_host_id":"x"}`, domain.ErrCodeForbidden},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			hub := NewHub()
+			host := withAllFeatures(newMockClient(hub, "Host"))
+			member := withAllFeatures(newMockClient(hub, "Member"))
+			setupRoom(t, hub, host, member)
+			time.Sleep(50 * time.Millisecond)
+			drainAll(host, member)
+			sender := tc.sender(host, member)
+
+			dispatchInbound(sender, tc.msgType, tc.payload, "req-1")
+
+			errMsg, ok := drainForType(sender, domain.MessageTypeError)
+			if !ok {
+				t.Fatal("Expected error frame")
+			}
+			var payload domain.ErrorPayload
+			json.Unmarshal(errMsg.Payload, &payload)
+			if payload.Code != tc.code || payload.ClientMsgID != "req-1" {
+				t.Errorf("Expected %s for req-1, got %+v", tc.code, payload)
+			}
+			if _, ok := drainForType(sender, domain.MessageTypeAck); ok {
+				t.Error("Rejected message must not be acked")
+			}
+		})
+	}
+}
+
+func TestNormalizeClientMsgID(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		expected string
+	}{
+		{"Normal", "abc-123", "abc-123"},
+		{"Empty", "", ""},
+		{"Too long", strings.Repeat("a", domain.MaxClientMsgIDLength+1), ""},
+		{"Control chars", "abc\n", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := normalizeClientMsgID(tc.id); got != tc.expected {
+				t.Errorf("normalizeClientMsgID(%q) = %q, expected %q", tc.id, got, tc.expected)
+			}
+		})
+	}
+}
+
 func TestDomain_NewUser(t *testing.T) {
 	user := domain.NewUser("TestName", "#AABBCC")
 
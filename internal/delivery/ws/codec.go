@@ -0,0 +1,53 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec is the wire encoding negotiated for a connection
+type Codec int
+
+const (
+	CodecJSON    Codec = iota // Text frames, newline-batched (default)
+	CodecMsgpack              // Binary frames, one message each
+)
+
+// Subprotocol names offered in Sec-WebSocket-Protocol
+const (
+	SubprotocolJSON    = "goat.json"
+	SubprotocolMsgpack = "goat.msgpack"
+)
+
+// errInvalidPayload rejects a binary payload that does not hold JSON
+var errInvalidPayload = errors.New("payload is not JSON")
+
+// Subprotocols lists the supported subprotocols in server preference order
+var Subprotocols = []string{SubprotocolMsgpack, SubprotocolJSON}
+
+// codecForSubprotocol maps the negotiated subprotocol to a codec
+// Clients that offer none (or an unknown one) get JSON
+func codecForSubprotocol(subprotocol string) Codec {
+	if subprotocol == SubprotocolMsgpack {
+		return CodecMsgpack
+	}
+	return CodecJSON
+}
+
+// msgpackToJSON re-encodes an inbound MessagePack document as JSON
+// The payload may be a map or, as the server sends it, binary holding JSON
+func msgpackToJSON(data []byte) ([]byte, error) {
+	var v map[string]interface{}
+	if err := msgpack.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	if raw, ok := v["payload"].([]byte); ok {
+		if !json.Valid(raw) {
+			return nil, errInvalidPayload
+		}
+		v["payload"] = json.RawMessage(raw)
+	}
+	return json.Marshal(v)
+}
@@ -0,0 +1,182 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestCodecForSubprotocol(t *testing.T) {
+	tests := []struct {
+		subprotocol string
+		expected    Codec
+	}{
+		{SubprotocolMsgpack, CodecMsgpack},
+		{SubprotocolJSON, CodecJSON},
+		{"", CodecJSON},
+		{"something-else", CodecJSON},
+	}
+
+	for _, tc := range tests {
+		if got := codecForSubprotocol(tc.subprotocol); got != tc.expected {
+			t.Errorf("codecForSubprotocol(%q) = %v, expected %v", tc.subprotocol, got, tc.expected)
+		}
+	}
+}
+
+func TestFrame_MsgpackCarriesPayloadAsJSON(t *testing.T) {
+	frame := NewFrame(domain.Message{ID: "m1", Type: domain.MessageTypeDice, Payload: json.RawMessage(`{"max":6,"result":3}`)})
+	frame.stampSeq(7)
+
+	var decoded map[string]interface{}
+	if err := msgpack.Unmarshal(frame.Bytes(CodecMsgpack), &decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if decoded["type"] != string(domain.MessageTypeDice) || decoded["id"] != "m1" {
+		t.Errorf("Unexpected envelope: %v", decoded)
+	}
+	if _, ok := decoded["seq"]; !ok {
+		t.Error("Expected the stamped seq in the MessagePack frame")
+	}
+	raw, ok := decoded["payload"].([]byte)
+	if !ok {
+		t.Fatalf("Expected payload as binary, got %T", decoded["payload"])
+	}
+	var payload domain.DicePayload
+	if err := json.Unmarshal(raw, &payload); err != nil || payload.Result != 3 {
+		t.Errorf("Expected payload JSON to round-trip, got %s", raw)
+	}
+}
+
+func TestClient_SendReencodesJSONForMsgpack(t *testing.T) {
+	c := &Client{send: make(chan []byte, 1), codec: CodecMsgpack}
+	c.Send([]byte(`{"id":"m1","type":"chat","payload":{"text":"hi"},"seq":3}`))
+
+	var msg domain.Message
+	if err := msgpack.Unmarshal(<-c.send, &msg); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if msg.Type != domain.MessageTypeChat || msg.Seq != 3 || string(msg.Payload) != `{"text":"hi"}` {
+		t.Errorf("Unexpected message: %+v", msg)
+	}
+}
+
+func TestMsgpackToJSON(t *testing.T) {
+	packed, _ := msgpack.Marshal(map[string]interface{}{"type": "chat", "payload": map[string]interface{}{"text": "hi"}})
+
+	data, err := msgpackToJSON(packed)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var msg struct {
+		Type    string            `json:"type"`
+		Payload map[string]string `json:"payload"`
+	}
+	json.Unmarshal(data, &msg)
+	if msg.Type != "chat" || msg.Payload["text"] != "hi" {
+		t.Errorf("Unexpected round trip: %s", data)
+	}
+
+	if _, err := msgpackToJSON([]byte{0xc1}); err == nil {
+		t.Error("Expected error for invalid MessagePack")
+	}
+
+	// Clients may echo the server's binary payload form back
+	packed, _ = msgpack.Marshal(map[string]interface{}{"type": "chat", "payload": []byte(`{"text":"hi"}`)})
+	data, err = msgpackToJSON(packed)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	json.Unmarshal(data, &msg)
+	if msg.Payload["text"] != "hi" {
+		t.Errorf("Expected binary payload to decode as JSON, got %s", data)
+	}
+
+	packed, _ = msgpack.Marshal(map[string]interface{}{"type": "chat", "payload": []byte("not json")})
+	if _, err := msgpackToJSON(packed); err == nil {
+		t.Error("Expected error for a binary payload that is not JSON")
+	}
+}
+
+func TestFrame_EncodesOncePerCodec(t *testing.T) {
+	frame := NewFrame(domain.Message{ID: "m1", Type: domain.MessageTypeChat, Payload: json.RawMessage(`{"text":"hi"}`)})
+
+	if frame.Type != domain.MessageTypeChat {
+		t.Errorf("Expected frame type chat, got %s", frame.Type)
+	}
+	first := frame.Bytes(CodecMsgpack)
+	second := frame.Bytes(CodecMsgpack)
+	if &first[0] != &second[0] {
+		t.Error("Expected MessagePack encoding to be cached")
+	}
+	if !bytes.HasPrefix(frame.Bytes(CodecJSON), []byte("{")) {
+		t.Error("Expected JSON encoding")
+	}
+}
+
+func TestHub_PublishSkipsMsgpackForNonMessage(t *testing.T) {
+	hub := NewHub()
+	plain := newMockClient(hub, "Plain")
+	packed := newMockClient(hub, "Packed")
+	packed.codec = CodecMsgpack
+	setupRoom(t, hub, plain, packed)
+	drainAll(plain, packed)
+
+	hub.mu.Lock()
+	hub.publishLocked(&Frame{data: []byte(`"raw"`)})
+	hub.mu.Unlock()
+
+	if len(packed.send) != 0 {
+		t.Errorf("Expected no frame for the MessagePack client, got %q", <-packed.send)
+	}
+	if len(plain.send) != 1 {
+		t.Errorf("Expected the JSON client to get the raw frame, got %d", len(plain.send))
+	}
+}
+
+func TestHub_BroadcastRefusesNonMessage(t *testing.T) {
+	hub := NewHub()
+	if err := hub.Broadcast([]byte("not json")); err != ErrNotMessage {
+		t.Errorf("Expected ErrNotMessage, got %v", err)
+	}
+	if err := hub.Broadcast([]byte(`{"id":"m1","type":"chat"}`)); err != nil {
+		t.Errorf("Expected a message to be accepted, got %v", err)
+	}
+}
+
+func TestHub_BroadcastUsesClientCodec(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	plain := newMockClient(hub, "Plain")
+	packed := newMockClient(hub, "Packed")
+	packed.codec = CodecMsgpack
+	hub.Register(plain)
+	hub.Register(packed)
+	if !waitForClients(hub, 2) {
+		t.Fatal("Clients did not register")
+	}
+	drainAll(plain)
+	drainAll(packed)
+
+	hub.BroadcastMessage(domain.Message{ID: "m1", Type: domain.MessageTypeChat, Payload: json.RawMessage(`{"text":"hi"}`)})
+
+	if _, ok := drainForType(plain, domain.MessageTypeChat); !ok {
+		t.Error("JSON client should receive the chat as JSON")
+	}
+
+	for i := 0; i < 20; i++ {
+		data := <-packed.send
+		var frame map[string]interface{}
+		if err := msgpack.Unmarshal(data, &frame); err != nil {
+			t.Fatalf("MessagePack client got a non-MessagePack frame: %s", data)
+		}
+		if frame["type"] == string(domain.MessageTypeChat) {
+			return
+		}
+	}
+	t.Error("MessagePack client should receive the chat")
+}
@@ -0,0 +1,174 @@
+package ws
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+	"golang.org/x/time/rate"
+)
+
+// FloodConfig configures inbound message rate limiting per user
+// Types costing more than DefaultCost each get a bucket of their own; the rest share one
+type FloodConfig struct {
+	Rate            rate.Limit                 // Tokens refilled per second, per bucket
+	Burst           int                        // Size of each bucket
+	Costs           map[domain.MessageType]int // Tokens per message type (0 = free)
+	DefaultCost     int                        // Cost of types not listed in Costs
+	WarnStrikes     int                        // Dropped messages before a warning
+	TimeoutStrikes  int                        // Dropped messages before an auto-timeout
+	StrikeWindow    time.Duration              // Quiet period that clears strikes
+	TimeoutDuration time.Duration              // Length of an auto-timeout
+	ChaosCooldown   time.Duration              // Room-wide gap between chaos messages
+}
+
+// DefaultFloodConfig returns the default flood protection settings
+// Phone-shaking and screen-filling types cost more than chat
+func DefaultFloodConfig() FloodConfig {
+	return FloodConfig{
+		Rate:  domain.FloodRate,
+		Burst: domain.FloodBurst,
+		Costs: map[domain.MessageType]int{
+			domain.MessageTypeStatusUpdate:   0,
+			domain.MessageTypeTyping:         0,
+			domain.MessageTypeChat:           1,
+			domain.MessageTypeReaction:       1,
+			domain.MessageTypeHistoryRequest: 2,
+			domain.MessageTypeGif:            2,
+			domain.MessageTypeTts:            3,
+			domain.MessageTypeConfetti:       3,
+			domain.MessageTypeVibrate:        5,
+			domain.MessageTypeChaos:          5,
+		},
+		DefaultCost:     1,
+		WarnStrikes:     domain.FloodWarnStrikes,
+		TimeoutStrikes:  domain.FloodTimeoutStrikes,
+		StrikeWindow:    domain.FloodStrikeWindow,
+		TimeoutDuration: domain.FloodTimeoutDuration,
+		ChaosCooldown:   domain.ChaosCooldown,
+	}
+}
+
+// cost returns the token cost of a message type, capped at the bucket size
+func (cfg FloodConfig) cost(msgType domain.MessageType) int {
+	cost, ok := cfg.Costs[msgType]
+	if !ok {
+		cost = cfg.DefaultCost
+	}
+	if cost > cfg.Burst {
+		cost = cfg.Burst
+	}
+	return cost
+}
+
+// floodState is one user's inbound budget
+// It is keyed like moderation, so reconnecting neither refills the buckets nor clears strikes
+type floodState struct {
+	shared     *rate.Limiter                        // Cheap types share one bucket
+	perType    map[domain.MessageType]*rate.Limiter // Each expensive type has its own
+	strikes    int
+	lastStrike time.Time
+}
+
+// bucket returns the limiter a message type is charged to, creating it on first use
+func (s *floodState) bucket(cfg FloodConfig, msgType domain.MessageType, cost int) *rate.Limiter {
+	if cost <= cfg.DefaultCost {
+		if s.shared == nil {
+			s.shared = rate.NewLimiter(cfg.Rate, cfg.Burst)
+		}
+		return s.shared
+	}
+	limiter, ok := s.perType[msgType]
+	if !ok {
+		limiter = rate.NewLimiter(cfg.Rate, cfg.Burst)
+		s.perType[msgType] = limiter
+	}
+	return limiter
+}
+
+// floodStateLocked returns the flood state for a moderation key, creating it on first use
+// NOTE: Caller must hold h.floodMu
+func (h *Hub) floodStateLocked(key string) *floodState {
+	state, ok := h.floodStates[key]
+	if !ok {
+		state = &floodState{perType: make(map[domain.MessageType]*rate.Limiter)}
+		h.floodStates[key] = state
+	}
+	return state
+}
+
+// resetFlood forgets every user's inbound budget (room emptied)
+func (h *Hub) resetFlood() {
+	h.floodMu.Lock()
+	h.floodStates = make(map[string]*floodState)
+	h.floodMu.Unlock()
+}
+
+// allowInbound applies the user's token buckets and the room-wide chaos cooldown
+// Over-budget clients are dropped, then warned, then timed out
+func (h *Hub) allowInbound(c *Client, msgType domain.MessageType) bool {
+	cfg := h.flood
+	cost := cfg.cost(msgType)
+	if cost == 0 {
+		return true
+	}
+
+	h.floodMu.Lock()
+	allowed := h.floodStateLocked(moderationKey(c)).bucket(cfg, msgType, cost).AllowN(time.Now(), cost)
+	h.floodMu.Unlock()
+
+	if !allowed {
+		h.recordStrike(c)
+		// Clients tracking this message get told so they can retry
+		if id, _ := c.inflightID.Load().(string); id != "" {
+			h.sendError(c, domain.ErrCodeRateLimited, "Pesan terlalu cepat, coba lagi")
+		}
+		return false
+	}
+
+	if msgType == domain.MessageTypeChaos && cfg.ChaosCooldown > 0 {
+		h.mu.Lock()
+		cooling := time.Since(h.lastChaos) < cfg.ChaosCooldown
+		if !cooling {
+			h.lastChaos = time.Now()
+		}
+		h.mu.Unlock()
+
+		if cooling {
+			h.sendError(c, domain.ErrCodeRateLimited, "Chaos masih cooldown, tunggu sebentar")
+			return false
+		}
+	}
+
+	return true
+}
+
+// recordStrike escalates a dropped message: silent drop, then warning, then auto-timeout
+func (h *Hub) recordStrike(c *Client) {
+	cfg := h.flood
+	now := time.Now()
+
+	h.floodMu.Lock()
+	state := h.floodStateLocked(moderationKey(c))
+	if now.Sub(state.lastStrike) > cfg.StrikeWindow {
+		state.strikes = 0
+	}
+	state.lastStrike = now
+	state.strikes++
+	strikes := state.strikes
+	timedOut := cfg.TimeoutStrikes > 0 && strikes >= cfg.TimeoutStrikes
+	if timedOut {
+		state.strikes = 0
+	}
+	h.floodMu.Unlock()
+
+	switch {
+	case timedOut:
+		h.mu.Lock()
+		duration := h.silenceLocked(c, domain.MessageTypeTimeout, cfg.TimeoutDuration)
+		h.notifyUser(c, fmt.Sprintf("⏳ Kamu otomatis di-timeout selama %s karena spam", formatModerationDuration(duration)))
+		h.mu.Unlock()
+	case strikes == cfg.WarnStrikes:
+		h.sendSystemNotice(c, "⚠️ Pelan-pelan! Pesan kamu terlalu cepat dan sebagian dibuang")
+	}
+}
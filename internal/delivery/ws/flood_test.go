@@ -0,0 +1,153 @@
+package ws
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+func TestFloodConfig_Cost(t *testing.T) {
+	cfg := DefaultFloodConfig()
+
+	if cfg.cost(domain.MessageTypeChaos) <= cfg.cost(domain.MessageTypeChat) {
+		t.Error("Expected chaos to cost more than chat")
+	}
+	if cfg.cost(domain.MessageTypeVibrate) <= cfg.cost(domain.MessageTypeChat) {
+		t.Error("Expected vibrate to cost more than chat")
+	}
+	if cfg.cost(domain.MessageTypeStatusUpdate) != 0 {
+		t.Error("Expected status updates to be free")
+	}
+	if cfg.cost("unknown") != cfg.DefaultCost {
+		t.Error("Expected unknown types to use the default cost")
+	}
+
+	cfg.Costs[domain.MessageTypeChaos] = cfg.Burst + 10
+	if cfg.cost(domain.MessageTypeChaos) != cfg.Burst {
+		t.Error("Expected cost to be capped at burst")
+	}
+}
+
+func TestHub_AllowInbound_Bucket(t *testing.T) {
+	cfg := DefaultFloodConfig()
+	cfg.Rate = 0.001
+	cfg.Burst = 10
+	cfg.ChaosCooldown = 0
+	hub := NewHub(WithFloodConfig(cfg))
+
+	client := newMockClient(hub, "Spammer")
+
+	// Vibrate costs 5: two fit in a burst of 10
+	if !hub.allowInbound(client, domain.MessageTypeVibrate) || !hub.allowInbound(client, domain.MessageTypeVibrate) {
+		t.Fatal("Expected vibrates within burst to be allowed")
+	}
+	if hub.allowInbound(client, domain.MessageTypeVibrate) {
+		t.Error("Expected vibrate over budget to be dropped")
+	}
+	if !hub.allowInbound(client, domain.MessageTypeStatusUpdate) {
+		t.Error("Expected free types to always pass")
+	}
+
+	// Expensive types have buckets of their own, so chat still has its budget
+	if !hub.allowInbound(client, domain.MessageTypeChat) {
+		t.Error("Expected chat to be charged to a separate bucket")
+	}
+	if !hub.allowInbound(client, domain.MessageTypeChaos) {
+		t.Error("Expected chaos to be charged to a separate bucket")
+	}
+}
+
+func TestHub_AllowInbound_SurvivesReconnect(t *testing.T) {
+	cfg := DefaultFloodConfig()
+	cfg.Rate = 0.001
+	cfg.Burst = 1
+	cfg.WarnStrikes = 2
+	hub := NewHub(WithFloodConfig(cfg))
+
+	client := newMockClient(hub, "Spammer")
+	client.SetOrigin("10.0.0.9", "lineage-spammer")
+	hub.allowInbound(client, domain.MessageTypeChat) // Uses the only token
+	hub.allowInbound(client, domain.MessageTypeChat) // First strike
+
+	// A new connection in the same token lineage keeps the empty bucket and the strike
+	again := reconnectMockClient(hub, client)
+	again.SetOrigin("10.0.0.9", "lineage-spammer")
+	if hub.allowInbound(again, domain.MessageTypeChat) {
+		t.Error("Expected reconnecting not to refill the bucket")
+	}
+	msg, ok := drainForType(again, domain.MessageTypeSystem)
+	if !ok || !strings.Contains(msg.FromName, "terlalu cepat") {
+		t.Errorf("Expected the second strike across reconnects to warn, got %q", msg.FromName)
+	}
+}
+
+func TestHub_AllowInbound_Escalation(t *testing.T) {
+	cfg := DefaultFloodConfig()
+	cfg.Rate = 0.001
+	cfg.Burst = 1
+	cfg.WarnStrikes = 2
+	cfg.TimeoutStrikes = 4
+	hub := NewHub(WithFloodConfig(cfg))
+
+	client := newMockClient(hub, "Spammer")
+	hub.allowInbound(client, domain.MessageTypeChat) // Uses the only token
+
+	// First strike: silent drop
+	hub.allowInbound(client, domain.MessageTypeChat)
+	if len(client.send) != 0 {
+		t.Fatal("Expected first strike to be a silent drop")
+	}
+
+	// Second strike: warning
+	hub.allowInbound(client, domain.MessageTypeChat)
+	msg, ok := drainForType(client, domain.MessageTypeSystem)
+	if !ok || !strings.Contains(msg.FromName, "terlalu cepat") {
+		t.Fatalf("Expected flood warning, got %q", msg.FromName)
+	}
+
+	// Fourth strike: auto-timeout
+	hub.allowInbound(client, domain.MessageTypeChat)
+	hub.allowInbound(client, domain.MessageTypeChat)
+	if !hub.Silenced(client, domain.MessageTypeChat) {
+		t.Error("Expected repeated flooding to trigger a timeout")
+	}
+	msg, ok = drainForType(client, domain.MessageTypeSystem)
+	if !ok || !strings.Contains(msg.FromName, "otomatis") || strings.Contains(msg.FromName, "host") {
+		t.Errorf("Expected an automatic timeout notice, got %q", msg.FromName)
+	}
+}
+
+func TestWithFloodConfig_IgnoresEmptyBucket(t *testing.T) {
+	hub := NewHub(WithFloodConfig(FloodConfig{}))
+	if hub.flood.Burst != domain.FloodBurst {
+		t.Errorf("Expected the default burst to survive an empty config, got %d", hub.flood.Burst)
+	}
+}
+
+func TestHub_AllowInbound_ChaosCooldown(t *testing.T) {
+	cfg := DefaultFloodConfig()
+	cfg.ChaosCooldown = 50 * time.Millisecond
+	hub := NewHub(WithFloodConfig(cfg))
+
+	alice := withAllFeatures(newMockClient(hub, "Alice"))
+	bob := withAllFeatures(newMockClient(hub, "Bob"))
+
+	if !hub.allowInbound(alice, domain.MessageTypeChaos) {
+		t.Fatal("Expected first chaos to be allowed")
+	}
+
+	// Cooldown is room-wide, not per client
+	if hub.allowInbound(bob, domain.MessageTypeChaos) {
+		t.Error("Expected chaos from another client during cooldown to be dropped")
+	}
+	if _, ok := drainForType(bob, domain.MessageTypeError); !ok {
+		t.Error("Expected cooldown error frame")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if !hub.allowInbound(bob, domain.MessageTypeChaos) {
+		t.Error("Expected chaos after cooldown to be allowed")
+	}
+}
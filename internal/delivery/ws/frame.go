@@ -0,0 +1,91 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ErrNotMessage is returned when broadcast data does not decode as a message
+var ErrNotMessage = errors.New("broadcast data is not a message")
+
+// Frame is an outbound message with its type known up front
+// The hub routes and stores frames without re-parsing them, and each
+// encoding is built at most once no matter how many clients receive it
+type Frame struct {
+	Type domain.MessageType
+	msg  domain.Message // Encoded on first use, so the hub can number it first
+
+	jsonOnce sync.Once
+	data     []byte // JSON encoding
+
+	msgpackOnce sync.Once
+	msgpack     []byte
+
+	// fallback is delivered instead to clients lacking fallbackFeature
+	fallback        *Frame
+	fallbackFeature domain.Feature
+}
+
+// NewFrame wraps a message; it is encoded once, when first sent
+func NewFrame(msg domain.Message) *Frame {
+	return &Frame{Type: msg.Type, msg: msg}
+}
+
+// newJSONFrame wraps an already-encoded JSON message
+// It is decoded here, by the sender, so the hub never parses it under its lock;
+// anything that is not a message is passed through untouched
+func newJSONFrame(data []byte) *Frame {
+	var msg domain.Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return &Frame{data: data}
+	}
+	return NewFrame(msg)
+}
+
+// WithFallback sets the message sent instead to clients that did not negotiate feature
+// History keeps the primary frame
+func (f *Frame) WithFallback(feature domain.Feature, msg domain.Message) *Frame {
+	f.fallback = NewFrame(msg)
+	f.fallbackFeature = feature
+	return f
+}
+
+// For picks the variant of the frame a client should receive
+func (f *Frame) For(c *Client) *Frame {
+	if f.fallback != nil && !c.HasFeature(f.fallbackFeature) {
+		return f.fallback
+	}
+	return f
+}
+
+// stampSeq sets the history sequence on the frame and its fallback
+// NOTE: Must be called before the frame is encoded for any client
+func (f *Frame) stampSeq(seq uint64) {
+	f.msg.Seq = seq
+	if f.fallback != nil {
+		f.fallback.msg.Seq = seq
+	}
+}
+
+// Bytes returns the frame in the given encoding
+// A frame that is not a message has no MessagePack form and returns nil
+func (f *Frame) Bytes(codec Codec) []byte {
+	if codec == CodecMsgpack {
+		f.msgpackOnce.Do(func() {
+			if f.msg.Type != "" {
+				f.msgpack, _ = msgpack.Marshal(&f.msg)
+			}
+		})
+		return f.msgpack
+	}
+	f.jsonOnce.Do(func() {
+		if f.data == nil {
+			f.data, _ = json.Marshal(f.msg)
+		}
+	})
+	return f.data
+}
@@ -0,0 +1,96 @@
+package ws
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+// flipMap maps characters to their upside-down counterparts
+var flipMap = map[rune]string{
+	'a': "ɐ", 'b': "q", 'c': "ɔ", 'd': "p", 'e': "ǝ", 'f': "ɟ", 'g': "ƃ", 'h': "ɥ",
+	'i': "ᴉ", 'j': "ɾ", 'k': "ʞ", 'l': "l", 'm': "ɯ", 'n': "u", 'o': "o", 'p': "d",
+	'q': "b", 'r': "ɹ", 's': "s", 't': "ʇ", 'u': "n", 'v': "ʌ", 'w': "ʍ", 'x': "x",
+	'y': "ʎ", 'z': "z", 'A': "∀", 'B': "q", 'C': "Ɔ", 'D': "p", 'E': "Ǝ", 'F': "Ⅎ",
+	'G': "⅁", 'H': "H", 'I': "I", 'J': "ſ", 'K': "ʞ", 'L': "˥", 'M': "W", 'N': "N",
+	'O': "O", 'P': "Ԁ", 'Q': "Q", 'R': "ɹ", 'S': "S", 'T': "⊥", 'U': "∩", 'V': "Λ",
+	'W': "M", 'X': "X", 'Y': "⅄", 'Z': "Z", '1': "Ɩ", '2': "ᄅ", '3': "Ɛ", '4': "ㄣ",
+	'5': "ϛ", '6': "9", '7': "ㄥ", '8': "8", '9': "6", '0': "0", '.': "˙", ',': "'",
+	'!': "¡", '?': "¿", '\'': ",", '"': ",,", '(': ")", ')': "(", '[': "]", ']': "[",
+	'{': "}", '}': "{", '<': ">", '>': "<", '&': "⅋", '_': "‾",
+}
+
+// truths is the server-side pool of Truth questions
+var truths = []string{
+	"Apa rahasia yang belum pernah kamu ceritakan ke siapapun?",
+	"Siapa crush terakhir kamu?",
+	"Apa hal paling memalukan yang pernah kamu lakukan?",
+	"Kalau bisa jadi invisible 1 hari, apa yang akan kamu lakukan?",
+	"Apa kebohongan terbesar yang pernah kamu bilang ke orang tua?",
+	"Siapa di grup ini yang menurut kamu paling ganteng/cantik?",
+	"Apa ketakutan terbesarmu?",
+	"Pernahkah kamu stalking sosmed mantan? Kapan terakhir?",
+	"Apa kebiasaan aneh yang kamu sembunyikan?",
+	"Kalau harus pilih satu orang di grup ini untuk jadi pasangan, siapa?",
+}
+
+// dares is the server-side pool of Dare challenges
+var dares = []string{
+	"Kirim voice note nyanyi lagu anak-anak!",
+	"Ganti foto profil jadi foto jelek selama 1 jam!",
+	"Bilang 'Aku sayang kalian' dengan 10 emoji hati!",
+	"Ceritakan pengalaman memalukan dengan detail!",
+	"Kirim chat ke grup keluarga bilang kangen mereka!",
+	"Tirukan suara hewan selama 10 detik!",
+	"Bilang 'Aku ganteng/cantik banget' 3x!",
+	"Screenshot wallpaper HP dan share di sini!",
+	"Kirim selfie dengan ekspresi konyol!",
+	"Puji 3 orang di grup ini dengan tulus!",
+}
+
+// secureIntn returns a uniformly distributed random int in [0, n) using crypto/rand
+func secureIntn(n int) int {
+	if n <= 1 {
+		return 0
+	}
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0
+	}
+	return int(v.Int64())
+}
+
+// rollDice returns a random value in [1, max]
+func rollDice(max int) int {
+	return secureIntn(max) + 1
+}
+
+// flipText turns text upside down (mapped and reversed)
+func flipText(text string) string {
+	runes := []rune(text)
+	flipped := make([]byte, 0, len(text))
+	for i := len(runes) - 1; i >= 0; i-- {
+		if f, ok := flipMap[runes[i]]; ok {
+			flipped = append(flipped, f...)
+		} else {
+			flipped = append(flipped, string(runes[i])...)
+		}
+	}
+	return string(flipped)
+}
+
+// pickTod picks a Truth or Dare question
+// todType may be "truth" or "dare"; anything else picks one at random
+func pickTod(todType string) (string, string) {
+	if todType != "truth" && todType != "dare" {
+		todType = "truth"
+		if secureIntn(2) == 1 {
+			todType = "dare"
+		}
+	}
+
+	pool := truths
+	if todType == "dare" {
+		pool = dares
+	}
+	return todType, pool[secureIntn(len(pool))]
+}
@@ -0,0 +1,506 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+// A handoff moves every room from a process that is shutting down to its replacement,
+// entirely in memory (see the handoff package for the transport). Rooms keep their
+// code, access settings, history, music, nobar, polls, suit matches, roles and bans;
+// session tokens travel with them so clients resume with their own persona. Lobby
+// connections cannot move, but everyone waiting gets their place back on reconnect.
+
+// handoffVersion guards against handing state to a build that reads it differently
+const handoffVersion = 1
+
+// handoffState is everything the old process sends
+type handoffState struct {
+	Version  int            `json:"version"`
+	Rooms    []roomSnapshot `json:"rooms"`
+	Sessions []SessionToken `json:"sessions"`
+}
+
+// roomSnapshot is one room and its hub
+type roomSnapshot struct {
+	Code         string               `json:"code"`
+	Name         string               `json:"name"`
+	PassSalt     []byte               `json:"pass_salt,omitempty"`
+	PassHash     []byte               `json:"pass_hash,omitempty"`
+	Invites      []Invite             `json:"invites,omitempty"`
+	Tickets      map[string]time.Time `json:"tickets,omitempty"`
+	MaxOccupancy int                  `json:"max_occupancy"`
+	KnockToEnter bool                 `json:"knock_to_enter"`
+
+	HostID        string              `json:"host_id"`
+	Moderators    []string            `json:"moderators,omitempty"`
+	AdmittedUsers []string            `json:"admitted_users,omitempty"`
+	Online        []domain.OnlineUser `json:"online,omitempty"`
+
+	History    [][]byte            `json:"history,omitempty"`
+	HistorySeq uint64              `json:"history_seq"`
+	Whispers   map[string][][]byte `json:"whispers,omitempty"`
+
+	Music         *domain.MusicPayload    `json:"music,omitempty"`
+	MusicQueue    []domain.MusicQueueItem `json:"music_queue,omitempty"`
+	PendingQueue  []domain.MusicQueueItem `json:"pending_queue,omitempty"`
+	Nobar         *domain.NobarPayload    `json:"nobar,omitempty"`
+	NobarRequests []domain.NobarQueueItem `json:"nobar_requests,omitempty"`
+	NobarQueue    []domain.NobarQueueItem `json:"nobar_queue,omitempty"`
+	PartyMode     string                  `json:"party_mode"`
+
+	Polls       []pollSnapshot                     `json:"polls,omitempty"`
+	SuitMatches []suitSnapshot                     `json:"suit_matches,omitempty"`
+	Knocks      map[string]time.Time               `json:"knocks,omitempty"`
+	Scoreboard  map[string]*domain.ScoreboardEntry `json:"scoreboard,omitempty"`
+	Bans        []banSnapshot                      `json:"bans,omitempty"`
+	Moderation  []moderationSnapshot               `json:"moderation,omitempty"`
+}
+
+// pollSnapshot is a pollState with exported fields, oldest first
+type pollSnapshot struct {
+	Payload  domain.PollPayload `json:"payload"`
+	Votes    map[string]int     `json:"votes,omitempty"` // user ID -> option index
+	ClosesAt time.Time          `json:"closes_at"`
+}
+
+// suitSnapshot is a suitMatch with its hidden moves
+type suitSnapshot struct {
+	Payload        domain.SuitPayload `json:"payload"`
+	ChallengerMove string             `json:"challenger_move,omitempty"`
+	OpponentMove   string             `json:"opponent_move,omitempty"`
+}
+
+// banSnapshot is a roomBan with exported fields
+type banSnapshot struct {
+	ID          string    `json:"id"`
+	PersonaName string    `json:"persona_name"`
+	Lineage     string    `json:"lineage"`
+	IP          string    `json:"ip"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// moderationSnapshot is an active mute or timeout
+type moderationSnapshot struct {
+	Key   string             `json:"key"`
+	Kind  domain.MessageType `json:"kind"`
+	Until time.Time          `json:"until"`
+}
+
+// personaReserver is a PersonaReleaser that can also hold a name for someone not connected yet
+type personaReserver interface {
+	Reserve(name string)
+}
+
+// sessionSnapshotter is a session store whose tokens can travel with a handoff
+type sessionSnapshotter interface {
+	sessions() []SessionToken
+	load(sessions []SessionToken) int
+}
+
+// WriteHandoff writes every room and session token to w
+// Sessions minted after it runs stay behind, so the caller keeps that window short
+func (rm *RoomManager) WriteHandoff(w io.Writer) error {
+	state := handoffState{Version: handoffVersion}
+
+	// Hubs take rm.mu while holding h.mu, so never hold both the other way round
+	rm.mu.RLock()
+	rooms := make([]*Room, 0, len(rm.rooms))
+	for _, room := range rm.rooms {
+		rooms = append(rooms, room)
+	}
+	rm.mu.RUnlock()
+
+	for _, room := range rooms {
+		state.Rooms = append(state.Rooms, room.snapshot())
+	}
+	if store, ok := GlobalSessionStore.(sessionSnapshotter); ok {
+		state.Sessions = store.sessions()
+	}
+
+	return json.NewEncoder(w).Encode(state)
+}
+
+// ReadHandoff restores rooms and session tokens written by WriteHandoff, returning the room count
+// Rooms whose code is already taken are skipped
+func (rm *RoomManager) ReadHandoff(r io.Reader) (int, error) {
+	var state handoffState
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return 0, err
+	}
+	if state.Version != handoffVersion {
+		return 0, fmt.Errorf("handoff version %d, want %d", state.Version, handoffVersion)
+	}
+
+	if store, ok := GlobalSessionStore.(sessionSnapshotter); ok {
+		store.load(state.Sessions)
+	}
+
+	restored := 0
+	for _, snap := range state.Rooms {
+		if rm.restoreRoom(snap) {
+			restored++
+		}
+	}
+	return restored, nil
+}
+
+// DisconnectAll closes every connection with 1012 (service restart) so clients reconnect
+// Used after a handoff; the replacement process is already serving by then
+func (rm *RoomManager) DisconnectAll() {
+	rm.mu.RLock()
+	hubs := make([]*Hub, 0, len(rm.rooms))
+	for _, room := range rm.rooms {
+		hubs = append(hubs, room.Hub)
+	}
+	rm.mu.RUnlock()
+
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseServiceRestart, "server restart")
+	for _, hub := range hubs {
+		hub.mu.RLock()
+		var conns []*Client
+		for c := range hub.allConns() {
+			conns = append(conns, c)
+		}
+		for _, entry := range hub.lobby {
+			conns = append(conns, entry.client)
+		}
+		hub.mu.RUnlock()
+
+		for _, c := range conns {
+			if c.conn == nil {
+				continue
+			}
+			c.conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+			c.conn.Close()
+		}
+	}
+}
+
+// snapshot copies the room's state for a handoff
+func (r *Room) snapshot() roomSnapshot {
+	snap := roomSnapshot{
+		Code: r.Code,
+		Name: r.Name,
+	}
+
+	r.accessMu.Lock()
+	r.pruneAccessLocked()
+	snap.PassSalt = r.passSalt
+	snap.PassHash = r.passHash
+	for _, invite := range r.invites {
+		snap.Invites = append(snap.Invites, *invite)
+	}
+	snap.Tickets = make(map[string]time.Time, len(r.tickets))
+	for ticket, expiresAt := range r.tickets {
+		snap.Tickets[ticket] = expiresAt
+	}
+	r.accessMu.Unlock()
+
+	h := r.Hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	snap.MaxOccupancy = h.maxOccupancy
+	snap.KnockToEnter = h.knockToEnter
+	snap.HostID = h.hostID
+	for id := range h.moderators {
+		snap.Moderators = append(snap.Moderators, id)
+	}
+	for id := range h.admittedUsers {
+		snap.AdmittedUsers = append(snap.AdmittedUsers, id)
+	}
+	for _, c := range h.clients {
+		snap.Online = append(snap.Online, h.onlineUser(c))
+	}
+	// Users still inside the rejoin window of an earlier handoff count as online too
+	for _, u := range h.handoffUsers {
+		snap.Online = append(snap.Online, u)
+	}
+
+	snap.History = h.messageHistory.GetAll()
+	snap.HistorySeq = h.messageHistory.LastSeq()
+	snap.Whispers = make(map[string][][]byte, len(h.whisperHistory))
+	for id, rb := range h.whisperHistory {
+		snap.Whispers[id] = rb.GetAll()
+	}
+
+	// Copied, because clients keep changing the room until they are disconnected
+	if h.currentMusic != nil {
+		music := *h.currentMusic
+		snap.Music = &music
+	}
+	snap.MusicQueue = slices.Clone(h.musicQueue)
+	snap.PendingQueue = slices.Clone(h.pendingQueue)
+	if h.currentNobar != nil {
+		nobar := *h.currentNobar
+		snap.Nobar = &nobar
+	}
+	snap.NobarRequests = slices.Clone(h.nobarRequests)
+	snap.NobarQueue = slices.Clone(h.nobarQueue)
+	snap.PartyMode = h.currentPartyMode
+	for _, pollID := range h.pollOrder {
+		poll, ok := h.polls[pollID]
+		if !ok {
+			continue
+		}
+		votes := make(map[string]int, len(poll.votes))
+		for userID, vote := range poll.votes {
+			votes[userID] = vote.optionIndex
+		}
+		snap.Polls = append(snap.Polls, pollSnapshot{
+			Payload:  poll.payload,
+			Votes:    votes,
+			ClosesAt: poll.closesAt,
+		})
+	}
+	for _, match := range h.suitMatches {
+		snap.SuitMatches = append(snap.SuitMatches, suitSnapshot{
+			Payload:        match.payload,
+			ChallengerMove: match.challengerMove,
+			OpponentMove:   match.opponentMove,
+		})
+	}
+	// Knockers carried over by an earlier handoff that have not come back keep their place too
+	snap.Knocks = maps.Clone(h.handoffKnocks)
+	for _, entry := range h.lobby {
+		if snap.Knocks == nil {
+			snap.Knocks = make(map[string]time.Time, len(h.lobby))
+		}
+		snap.Knocks[entry.client.ID] = entry.knockedAt
+	}
+	snap.Scoreboard = make(map[string]*domain.ScoreboardEntry, len(h.scoreboard))
+	for persona, entry := range h.scoreboard {
+		copied := *entry
+		snap.Scoreboard[persona] = &copied
+	}
+
+	h.pruneBansLocked()
+	for _, ban := range h.bans {
+		snap.Bans = append(snap.Bans, banSnapshot{
+			ID:          ban.id,
+			PersonaName: ban.personaName,
+			Lineage:     ban.lineage,
+			IP:          ban.ip,
+			CreatedAt:   ban.createdAt,
+			ExpiresAt:   ban.expiresAt,
+		})
+	}
+	now := time.Now()
+	for key, state := range h.moderation {
+		if now.Before(state.until) {
+			snap.Moderation = append(snap.Moderation, moderationSnapshot{
+				Key:   key,
+				Kind:  state.kind,
+				Until: state.until,
+			})
+		}
+	}
+
+	return snap
+}
+
+// restoreRoom recreates a handed-off room and starts its hub
+func (rm *RoomManager) restoreRoom(snap roomSnapshot) bool {
+	room := &Room{
+		Code:     snap.Code,
+		Name:     snap.Name,
+		passSalt: snap.PassSalt,
+		passHash: snap.PassHash,
+		invites:  make(map[string]*Invite, len(snap.Invites)),
+		tickets:  make(map[string]time.Time, len(snap.Tickets)),
+	}
+	for i := range snap.Invites {
+		invite := snap.Invites[i]
+		room.invites[invite.Token] = &invite
+	}
+	for ticket, expiresAt := range snap.Tickets {
+		room.tickets[ticket] = expiresAt
+	}
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if snap.Code == "" || rm.rooms[snap.Code] != nil {
+		return false
+	}
+
+	room.Hub = rm.newHub(snap.Code, RoomOptions{
+		MaxOccupancy: snap.MaxOccupancy,
+		KnockToEnter: snap.KnockToEnter,
+	})
+	room.Hub.restore(snap)
+
+	rm.rooms[snap.Code] = room
+	go room.Hub.Run()
+
+	return true
+}
+
+// restore loads a snapshot into a hub that has not started running yet
+// Users who were online get handoffWindow to reconnect before they are announced as gone
+func (h *Hub) restore(snap roomSnapshot) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.hostID = snap.HostID
+	for _, id := range snap.Moderators {
+		h.moderators[id] = true
+	}
+	for _, id := range snap.AdmittedUsers {
+		h.admittedUsers[id] = true
+	}
+	// Their personas stay taken while they reconnect, so nobody new is handed the same name
+	reserver, _ := h.personaReleaser.(personaReserver)
+	for _, u := range snap.Online {
+		h.handoffUsers[u.ID] = u
+		if reserver != nil {
+			reserver.Reserve(u.Persona)
+		}
+	}
+
+	for _, msg := range snap.History {
+		h.messageHistory.Add(msg)
+	}
+	// Keep numbering where the old process left off, so clients resume by seq
+	if snap.HistorySeq > h.messageHistory.lastSeq {
+		h.messageHistory.lastSeq = snap.HistorySeq
+	}
+	for id, whispers := range snap.Whispers {
+		for _, msg := range whispers {
+			h.addWhisperHistory(id, msg)
+		}
+	}
+
+	h.currentMusic = snap.Music
+	if snap.MusicQueue != nil {
+		h.musicQueue = snap.MusicQueue
+	}
+	if snap.PendingQueue != nil {
+		h.pendingQueue = snap.PendingQueue
+	}
+	h.currentNobar = snap.Nobar
+	if snap.NobarRequests != nil {
+		h.nobarRequests = snap.NobarRequests
+	}
+	if snap.NobarQueue != nil {
+		h.nobarQueue = snap.NobarQueue
+	}
+	if snap.PartyMode != "" {
+		h.currentPartyMode = snap.PartyMode
+	}
+	// Timers pick up where they stopped; anything already overdue closes right away
+	for _, p := range snap.Polls {
+		poll := &pollState{
+			payload: p.Payload,
+			votes:   make(map[string]pollVote, len(p.Votes)),
+		}
+		for userID, index := range p.Votes {
+			if index >= 0 && index < len(poll.payload.Options) {
+				poll.votes[userID] = pollVote{optionIndex: index}
+			}
+		}
+		if !poll.payload.Closed && !p.ClosesAt.IsZero() {
+			h.startPollTimer(poll, time.Until(p.ClosesAt))
+		}
+		h.polls[poll.payload.PollID] = poll
+		h.pollOrder = append(h.pollOrder, poll.payload.PollID)
+	}
+	for _, s := range snap.SuitMatches {
+		match := &suitMatch{
+			payload:        s.Payload,
+			challengerMove: s.ChallengerMove,
+			opponentMove:   s.OpponentMove,
+		}
+		h.suitMatches[match.payload.ID] = match
+		h.startSuitTimer(match, time.Until(s.Payload.ExpiresAt))
+	}
+	for id, knockedAt := range snap.Knocks {
+		h.handoffKnocks[id] = knockedAt
+	}
+	if snap.Scoreboard != nil {
+		h.scoreboard = snap.Scoreboard
+	}
+
+	for _, ban := range snap.Bans {
+		h.bans[ban.ID] = &roomBan{
+			id:          ban.ID,
+			personaName: ban.PersonaName,
+			lineage:     ban.Lineage,
+			ip:          ban.IP,
+			createdAt:   ban.CreatedAt,
+			expiresAt:   ban.ExpiresAt,
+		}
+	}
+	for _, m := range snap.Moderation {
+		if time.Now().Before(m.Until) {
+			h.armModerationLocked(m.Key, m.Kind, m.Until)
+		}
+	}
+
+	if len(h.handoffUsers) == 0 {
+		// Empty room that was already waiting out its grace period
+		h.scheduleShutdown()
+		return
+	}
+	time.AfterFunc(h.handoffWindow, h.expireHandoffUsers)
+}
+
+// expireHandoffUsers announces handed-off users who never reconnected as gone
+// A host who did not come back hands the room to someone who did; a room nobody came back to closes
+func (h *Hub) expireHandoffUsers() {
+	h.mu.Lock()
+	// Knockers who did not come back in time start at the end of the queue like anyone new
+	clear(h.handoffKnocks)
+	if len(h.handoffUsers) == 0 {
+		// Everyone came back; a room they all left since is already closing
+		h.mu.Unlock()
+		return
+	}
+
+	count := len(h.clients)
+	var frames []*Frame
+	var gone []string
+	for id, u := range h.handoffUsers {
+		delete(h.handoffUsers, id)
+		if h.personaReleaser != nil {
+			h.personaReleaser.Release(u.Persona)
+		}
+
+		userID, err := uuid.Parse(id)
+		if err != nil {
+			continue
+		}
+		// Leave events only need who left, so a connectionless stand-in will do
+		frames = append(frames, h.leaveFrame(&Client{
+			ID:   id,
+			User: domain.RestoreUser(userID, u.Persona, u.Color),
+		}, count))
+		gone = append(gone, id)
+	}
+
+	if count == 0 {
+		h.resetEmptyRoom()
+	} else if _, back := h.clients[h.hostID]; !back {
+		h.pickNewHost()
+	}
+	h.publishPresence(domain.UserDeltaPayload{Removed: gone}, nil, nil)
+	if count == 1 && h.roomCode != "" {
+		h.sendLastUserWarning()
+	}
+	h.mu.Unlock()
+
+	// Queued without the lock, since Run needs it to drain the channel
+	for _, frame := range frames {
+		h.broadcast <- frame
+	}
+}
@@ -0,0 +1,362 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+// handOffRoom moves old's rooms into a new manager through WriteHandoff/ReadHandoff,
+// the way the replacement process receives them (with its own session store)
+func handOffRoom(t *testing.T, old *RoomManager, opts ...HubOption) *RoomManager {
+	t.Helper()
+	next := NewRoomManager(opts...)
+	handOffInto(t, old, next)
+	return next
+}
+
+// handOffInto is handOffRoom into a manager the test has already set up
+func handOffInto(t *testing.T, old, next *RoomManager) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := old.WriteHandoff(&buf); err != nil {
+		t.Fatalf("WriteHandoff: %v", err)
+	}
+
+	previous := GlobalSessionStore
+	GlobalSessionStore = NewSessionStore()
+	t.Cleanup(func() {
+		GlobalSessionStore.Close()
+		GlobalSessionStore = previous
+	})
+
+	if _, err := next.ReadHandoff(&buf); err != nil {
+		t.Fatalf("ReadHandoff: %v", err)
+	}
+}
+
+// takenPersonas stands in for the persona generator, remembering which names are taken
+type takenPersonas struct {
+	mu    sync.Mutex
+	names map[string]bool
+}
+
+func (p *takenPersonas) Reserve(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.names[name] = true
+}
+
+func (p *takenPersonas) Release(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.names, name)
+}
+
+func (p *takenPersonas) taken(name string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.names[name]
+}
+
+// waitForHistory waits until the hub has stored seq messages
+func waitForHistory(hub *Hub, seq uint64) bool {
+	for i := 0; i < 50; i++ {
+		hub.mu.RLock()
+		last := hub.messageHistory.LastSeq()
+		hub.mu.RUnlock()
+		if last >= seq {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return false
+}
+
+func TestRoomManager_HandoffRoundTrip(t *testing.T) {
+	old := NewRoomManager()
+	room := old.CreateRoomWithOptions("Ruang Kita", RoomOptions{Passphrase: "rahasia", MaxOccupancy: 8})
+	hub := room.Hub
+
+	host := newMockClient(hub, "Host")
+	hub.Register(host)
+	member := newMockClient(hub, "Member")
+	hub.Register(member)
+	waitForClients(hub, 2)
+
+	broadcastChat(hub, "satu")
+	broadcastChat(hub, "dua")
+	if !waitForHistory(hub, 2) {
+		t.Fatal("History was not stored")
+	}
+
+	hub.mu.Lock()
+	hub.moderators[member.ID] = true
+	hub.currentMusic = &domain.MusicPayload{VideoID: "abc123", IsPlaying: true}
+	hub.musicQueue = append(hub.musicQueue, domain.MusicQueueItem{ID: "q1", VideoID: "def456"})
+	hub.currentNobar = &domain.NobarPayload{VideoID: "nobar1", CurrentTime: 42}
+	hub.silenceLocked(member, domain.MessageTypeMute, time.Minute)
+	lastSeq := hub.messageHistory.LastSeq()
+	hub.mu.Unlock()
+
+	token := GlobalSessionStore.GenerateToken(host.ID, "Host", "#000000", room.Code)
+
+	next := handOffRoom(t, old)
+	restored := next.GetRoom(room.Code)
+	if restored == nil {
+		t.Fatal("Expected the room to be restored under the same code")
+	}
+	if restored.Name != "Ruang Kita" || !restored.CheckPassphrase("rahasia") {
+		t.Error("Expected name and passphrase to carry over")
+	}
+
+	rh := restored.Hub
+	rh.mu.RLock()
+	defer rh.mu.RUnlock()
+
+	if rh.maxOccupancy != 8 {
+		t.Errorf("Expected max occupancy 8, got %d", rh.maxOccupancy)
+	}
+	if rh.hostID != host.ID {
+		t.Errorf("Expected host %s, got %s", host.ID, rh.hostID)
+	}
+	if !rh.moderators[member.ID] {
+		t.Error("Expected moderator role to carry over")
+	}
+	if rh.messageHistory.LastSeq() != lastSeq || rh.messageHistory.Len() != int(lastSeq) {
+		t.Errorf("Expected history up to seq %d, got %d (%d messages)", lastSeq, rh.messageHistory.LastSeq(), rh.messageHistory.Len())
+	}
+	if rh.currentMusic == nil || rh.currentMusic.VideoID != "abc123" || len(rh.musicQueue) != 1 {
+		t.Error("Expected music and queue to carry over")
+	}
+	if rh.currentNobar == nil || rh.currentNobar.CurrentTime != 42 {
+		t.Error("Expected nobar position to carry over")
+	}
+	if _, muted := rh.moderation[moderationKey(member)]; !muted {
+		t.Error("Expected mute to carry over")
+	}
+	if len(rh.handoffUsers) != 2 {
+		t.Errorf("Expected 2 users waiting to rejoin, got %d", len(rh.handoffUsers))
+	}
+
+	if _, ok := GlobalSessionStore.ConsumeToken(token); !ok {
+		t.Error("Expected the session token to resume on the new process")
+	}
+}
+
+func TestRoomManager_HandoffUsersRejoinQuietly(t *testing.T) {
+	old := NewRoomManager()
+	room := old.CreateRoom("Ruang")
+	host := withAllFeatures(newMockClient(room.Hub, "Host"))
+	room.Hub.Register(host)
+	member := withAllFeatures(newMockClient(room.Hub, "Member"))
+	room.Hub.Register(member)
+	waitForClients(room.Hub, 2)
+
+	next := handOffRoom(t, old)
+	hub := next.GetRoom(room.Code).Hub
+
+	returningHost := reconnectMockClient(hub, host)
+	hub.Register(returningHost)
+	waitForClients(hub, 1)
+	drainAll(returningHost)
+
+	returningMember := reconnectMockClient(hub, member)
+	hub.Register(returningMember)
+	waitForClients(hub, 2)
+
+	// The host hears the member is back, but the room is not told anyone joined
+	delta, ok := drainForType(returningHost, domain.MessageTypeUserDelta)
+	if !ok {
+		t.Fatal("Expected a presence delta for the returning member")
+	}
+	if !bytes.Contains(delta.Payload, []byte(member.ID)) {
+		t.Error("Expected the delta to add the returning member")
+	}
+	if _, joined := drainForType(returningHost, domain.MessageTypeUserJoin); joined {
+		t.Error("Returning users must not be announced as joining")
+	}
+
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	if hub.hostID != host.ID {
+		t.Error("Expected the returning host to keep the role")
+	}
+	if len(hub.handoffUsers) != 0 {
+		t.Errorf("Expected nobody left waiting, got %d", len(hub.handoffUsers))
+	}
+}
+
+func TestRoomManager_HandoffHostWhoStaysAwayIsReplaced(t *testing.T) {
+	old := NewRoomManager()
+	room := old.CreateRoom("Ruang")
+	host := newMockClient(room.Hub, "Host")
+	room.Hub.Register(host)
+	member := newMockClient(room.Hub, "Member")
+	room.Hub.Register(member)
+	waitForClients(room.Hub, 2)
+
+	next := handOffRoom(t, old, WithHandoffRejoinWindow(50*time.Millisecond))
+	hub := next.GetRoom(room.Code).Hub
+
+	returningMember := reconnectMockClient(hub, member)
+	hub.Register(returningMember)
+	waitForClients(hub, 1)
+	drainAll(returningMember)
+
+	time.Sleep(100 * time.Millisecond)
+
+	if _, ok := drainForType(returningMember, domain.MessageTypeHostChange); !ok {
+		t.Error("Expected a host change once the old host's rejoin window ran out")
+	}
+
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	if hub.hostID != member.ID {
+		t.Errorf("Expected the member to become host, got %s", hub.hostID)
+	}
+	if len(hub.handoffUsers) != 0 {
+		t.Errorf("Expected the absent host to be dropped, got %d waiting", len(hub.handoffUsers))
+	}
+}
+
+func TestRoomManager_HandoffReservesPersonasUntilExpiry(t *testing.T) {
+	old := NewRoomManager()
+	room := old.CreateRoom("Ruang")
+	room.Hub.Register(newMockClient(room.Hub, "Host"))
+	room.Hub.Register(newMockClient(room.Hub, "Member"))
+	waitForClients(room.Hub, 2)
+
+	personas := &takenPersonas{names: make(map[string]bool)}
+	next := NewRoomManager(WithHandoffRejoinWindow(50*time.Millisecond), WithShutdownGracePeriod(50*time.Millisecond))
+	next.SetPersonaReleaser(personas)
+	handOffInto(t, old, next)
+
+	if !personas.taken("Host") || !personas.taken("Member") {
+		t.Error("Expected handed-off personas to stay taken during the rejoin window")
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	if personas.taken("Host") || personas.taken("Member") {
+		t.Error("Expected personas to be released once nobody came back")
+	}
+	if next.GetRoom(room.Code) != nil {
+		t.Error("Expected a room nobody came back to to close")
+	}
+}
+
+func TestRoomManager_HandoffCarriesPollsAndSuitMatches(t *testing.T) {
+	old := NewRoomManager()
+	room := old.CreateRoom("Ruang")
+	host := newMockClient(room.Hub, "Host")
+	room.Hub.Register(host)
+	member := newMockClient(room.Hub, "Member")
+	room.Hub.Register(member)
+	waitForClients(room.Hub, 2)
+
+	pollID := createTestPoll(t, room.Hub, host, domain.PollPayload{
+		Question:    "Makan?",
+		Options:     []string{"Bakso", "Soto"},
+		DurationSec: 60,
+	})
+	sendVote(room.Hub, member, pollID, 1)
+	matchID := challengeSuit(t, room.Hub, host, member)
+	sendSuit(room.Hub, host, domain.SuitPayload{Action: "move", ID: matchID, Move: "rock"})
+
+	next := handOffRoom(t, old)
+	hub := next.GetRoom(room.Code).Hub
+
+	hub.mu.RLock()
+	poll, ok := hub.polls[pollID]
+	if !ok {
+		hub.mu.RUnlock()
+		t.Fatal("Expected the poll to carry over")
+	}
+	if tally := hub.pollSnapshot(poll).Votes; tally["Soto"] != 1 || tally["Bakso"] != 0 {
+		t.Errorf("Expected the vote to carry over, got %v", tally)
+	}
+	if poll.timer == nil || time.Until(poll.closesAt) <= 0 {
+		t.Error("Expected the poll to keep closing on its own")
+	}
+	_, pending := hub.suitMatches[matchID]
+	hub.mu.RUnlock()
+	if !pending {
+		t.Fatal("Expected the suit match to carry over")
+	}
+
+	returningMember := reconnectMockClient(hub, member)
+	hub.Register(returningMember)
+	waitForClients(hub, 1)
+	drainAll(returningMember)
+
+	sendSuit(hub, returningMember, domain.SuitPayload{Action: "accept", ID: matchID})
+	sendSuit(hub, returningMember, domain.SuitPayload{Action: "move", ID: matchID, Move: "paper"})
+
+	state, ok := lastSuitState(returningMember)
+	if !ok || state.Status != "completed" || state.Winner != member.ID {
+		t.Errorf("Expected the member to win with the challenger's move kept, got %+v", state)
+	}
+}
+
+func TestRoomManager_HandoffKeepsKnockOrder(t *testing.T) {
+	old := NewRoomManager()
+	room := old.CreateRoomWithOptions("Ruang", RoomOptions{KnockToEnter: true})
+	host := withAllFeatures(newMockClient(room.Hub, "Host"))
+	room.Hub.Register(host)
+	waitForClients(room.Hub, 1)
+
+	first := withAllFeatures(newMockClient(room.Hub, "First"))
+	room.Hub.Register(first)
+	second := withAllFeatures(newMockClient(room.Hub, "Second"))
+	room.Hub.Register(second)
+	for i := 0; i < 50 && lobbySize(room.Hub) < 2; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	next := handOffRoom(t, old)
+	hub := next.GetRoom(room.Code).Hub
+
+	returningHost := reconnectMockClient(hub, host)
+	hub.Register(returningHost)
+	waitForClients(hub, 1)
+
+	// The later knocker happens to reconnect first
+	hub.Register(reconnectMockClient(hub, second))
+	returningFirst := reconnectMockClient(hub, first)
+	hub.Register(returningFirst)
+	for i := 0; i < 50 && lobbySize(hub) < 2; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	knock, ok := drainForType(returningFirst, domain.MessageTypeKnock)
+	if !ok {
+		t.Fatal("Expected the first knocker to wait again")
+	}
+	var status domain.KnockPayload
+	json.Unmarshal(knock.Payload, &status)
+	if status.Status != "waiting" || status.Position != 1 {
+		t.Errorf("Expected the first knocker back at position 1, got %+v", status)
+	}
+
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	if len(hub.lobby) != 2 || hub.lobby[0].client.ID != first.ID || hub.lobby[1].client.ID != second.ID {
+		t.Error("Expected the lobby in the order people knocked before the handoff")
+	}
+}
+
+func TestRoomManager_HandoffRejectsOtherVersions(t *testing.T) {
+	next := NewRoomManager()
+	if _, err := next.ReadHandoff(bytes.NewBufferString(`{"version":99}`)); err == nil {
+		t.Error("Expected a handoff from an unknown version to be rejected")
+	}
+	if next.GetRoomCount() != 0 {
+		t.Error("Expected no rooms from a rejected handoff")
+	}
+}
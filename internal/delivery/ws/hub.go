@@ -20,28 +20,55 @@ type Hub struct {
 	mu              sync.RWMutex
 	leaveDelay      time.Duration
 	hostTransferDelay time.Duration
+	suitAcceptTimeout time.Duration
+	suitMoveTimeout   time.Duration
+	handoffWindow     time.Duration
 
-	clients         map[string]*Client
-	broadcast       chan []byte
+	clients         map[string]*Client   // user ID -> representative connection (one per logical user)
+	conns           map[string][]*Client // user ID -> every open connection (tabs, devices)
+	broadcast       chan *Frame
 	register        chan *Client
 	unregister      chan *Client
 	personaReleaser PersonaReleaser
 	messageHistory  *RingBuffer
+	maxHistorySize  int
+	historyBurst    int   // Recent messages replayed on join
+	maxMessageSize  int64 // Inbound frame limit for this room's clients
+	shutdownGrace   time.Duration
 	roomManager     *RoomManager
 	roomCode        string
 	roomName        string
-	hostID          string
-	hostPersona     string // Persistent host identity
+	hostID          string // Stable user ID, kept while the host is reconnecting
 	shutdownTimer   *time.Timer
 	currentMusic    *domain.MusicPayload
 	musicQueue      []domain.MusicQueueItem
 	pendingQueue    []domain.MusicQueueItem
 	currentNobar    *domain.NobarPayload
-	delayedLeavers  map[string]*time.Timer // Spam prevention
+	delayedLeavers  map[string]*time.Timer // user ID -> pending leave (spam prevention)
 	nobarRequests   []domain.NobarQueueItem
 	nobarQueue      []domain.NobarQueueItem
 	nobarViewers    map[string]domain.NobarViewer
 	currentPartyMode string
+	whisperHistory  map[string]*RingBuffer // user ID -> private whispers
+	polls           map[string]*pollState
+	pollOrder       []string // poll IDs, oldest first
+	suitMatches     map[string]*suitMatch
+	scoreboard      map[string]*domain.ScoreboardEntry // persona -> mini-game record
+	maxOccupancy    int  // 0 = unlimited
+	knockToEnter    bool // New clients wait in the lobby for host approval
+	lobby           []*lobbyEntry
+	admittedUsers   map[string]bool // User IDs the host already let in
+	bans            map[string]*roomBan // ban ID -> ban
+	moderation      map[string]*moderationState // moderation key -> active mute/timeout
+	moderators      map[string]bool // user ID -> moderator role
+	rolePermissions map[domain.Role]map[domain.Permission]bool
+	flood           FloodConfig
+	floodMu         sync.Mutex
+	floodStates     map[string]*floodState // moderation key -> inbound budget, kept across reconnects
+	lastChaos       time.Time // Room-wide chaos cooldown
+	presenceVersion uint64    // Bumped once per user_delta
+	handoffUsers    map[string]domain.OnlineUser // user ID -> online before a handoff, not reconnected yet
+	handoffKnocks   map[string]time.Time         // user ID -> knocked before a handoff, not back in the lobby yet
 }
 
 // MusicState tracks the current playing song
@@ -53,16 +80,108 @@ type MusicState struct {
 	IsPlaying bool      `json:"is_playing"`
 }
 
-// NewHub creates a new Hub
-func NewHub() *Hub {
-	return &Hub{
+// HubOption overrides a per-room limit or timing when creating a Hub
+type HubOption func(*Hub)
+
+// WithMaxHistorySize sets how many messages the room keeps in history
+func WithMaxHistorySize(n int) HubOption {
+	return func(h *Hub) {
+		if n > 0 {
+			h.maxHistorySize = n
+		}
+	}
+}
+
+// WithHistoryJoinBurst sets how many recent messages new clients get on join
+func WithHistoryJoinBurst(n int) HubOption {
+	return func(h *Hub) {
+		if n >= 0 {
+			h.historyBurst = n
+		}
+	}
+}
+
+// WithMaxMessageSize sets the largest inbound frame the room's clients may send
+func WithMaxMessageSize(n int) HubOption {
+	return func(h *Hub) {
+		if n > 0 {
+			h.maxMessageSize = int64(n)
+		}
+	}
+}
+
+// WithShutdownGracePeriod sets how long an empty room waits for reconnects before it is deleted
+func WithShutdownGracePeriod(d time.Duration) HubOption {
+	return func(h *Hub) {
+		if d > 0 {
+			h.shutdownGrace = d
+		}
+	}
+}
+
+// WithLeaveDelay sets how long a disconnect waits before user_leave is announced
+func WithLeaveDelay(d time.Duration) HubOption {
+	return func(h *Hub) {
+		if d > 0 {
+			h.leaveDelay = d
+		}
+	}
+}
+
+// WithHostTransferDelay sets how long a disconnected host has to come back
+func WithHostTransferDelay(d time.Duration) HubOption {
+	return func(h *Hub) {
+		if d > 0 {
+			h.hostTransferDelay = d
+		}
+	}
+}
+
+// WithHandoffRejoinWindow sets how long users carried over by a handoff have to reconnect
+func WithHandoffRejoinWindow(d time.Duration) HubOption {
+	return func(h *Hub) {
+		if d > 0 {
+			h.handoffWindow = d
+		}
+	}
+}
+
+// WithFloodConfig sets the room's inbound rate limiting; a config without a bucket is ignored
+func WithFloodConfig(cfg FloodConfig) HubOption {
+	return func(h *Hub) {
+		if cfg.Burst > 0 && cfg.Rate > 0 {
+			h.flood = cfg
+		}
+	}
+}
+
+// WithRolePermissions replaces the permission sets of the given roles; roles left out keep the defaults
+// Rank still applies, so kick and the other actions on people only reach lower roles
+func WithRolePermissions(perms map[domain.Role][]domain.Permission) HubOption {
+	return func(h *Hub) {
+		for role, list := range perms {
+			h.rolePermissions[role] = permissionSet(list)
+		}
+	}
+}
+
+// NewHub creates a new Hub, using the domain defaults for anything opts leave unset
+func NewHub(opts ...HubOption) *Hub {
+	h := &Hub{
 		clients:        make(map[string]*Client),
-		broadcast:      make(chan []byte, 256),
+		conns:          make(map[string][]*Client),
+		broadcast:      make(chan *Frame, 256),
 		register:       make(chan *Client),
 		unregister:     make(chan *Client),
 		leaveDelay:     domain.LeaveDelay,
 		hostTransferDelay: domain.HostTransferDelay,
-		messageHistory: NewRingBuffer(domain.MaxHistorySize),
+		suitAcceptTimeout: domain.SuitAcceptTimeout,
+		suitMoveTimeout:   domain.SuitMoveTimeout,
+		handoffWindow:     domain.HandoffRejoinWindow,
+		maxHistorySize: domain.MaxHistorySize,
+		historyBurst:   domain.HistoryJoinBurst,
+		maxMessageSize: domain.MaxMessageSize,
+		shutdownGrace:  domain.ShutdownGracePeriod,
 		hostID:         "",
 		musicQueue:     make([]domain.MusicQueueItem, 0),
 		pendingQueue:   make([]domain.MusicQueueItem, 0),
@@ -71,7 +190,25 @@ func NewHub() *Hub {
 		nobarQueue:     make([]domain.NobarQueueItem, 0),
 		nobarViewers:   make(map[string]domain.NobarViewer),
 		currentPartyMode: "normal",
+		whisperHistory: make(map[string]*RingBuffer),
+		polls:          make(map[string]*pollState),
+		suitMatches:    make(map[string]*suitMatch),
+		scoreboard:     make(map[string]*domain.ScoreboardEntry),
+		admittedUsers:  make(map[string]bool),
+		bans:           make(map[string]*roomBan),
+		moderation:     make(map[string]*moderationState),
+		moderators:     make(map[string]bool),
+		handoffUsers:   make(map[string]domain.OnlineUser),
+		handoffKnocks:  make(map[string]time.Time),
+		rolePermissions: newRolePermissions(),
+		floodStates:    make(map[string]*floodState),
+		flood:          DefaultFloodConfig(),
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.messageHistory = NewRingBuffer(h.maxHistorySize)
+	return h
 }
 
 // SetPersonaReleaser sets the persona releaser for cleanup
@@ -90,8 +227,8 @@ func (h *Hub) cancelShutdown() {
 // scheduleShutdown starts the grace period timer
 func (h *Hub) scheduleShutdown() {
 	if h.roomManager != nil && h.roomCode != "" {
-		// Wait 60 seconds before destroying empty room to allow reconnects
-		h.shutdownTimer = time.AfterFunc(60*time.Second, func() {
+		// Wait before destroying empty room to allow reconnects
+		h.shutdownTimer = time.AfterFunc(h.shutdownGrace, func() {
 			h.mu.Lock()
 			defer h.mu.Unlock()
 			if len(h.clients) == 0 {
@@ -109,85 +246,107 @@ func (h *Hub) Run() {
 		select {
 		case client := <-h.register:
 			h.mu.Lock()
-			h.cancelShutdown()
-
-			h.clients[client.ID] = client
-			
-			// Host assignment logic:
-			// 1. If hostPersona is empty (first user), assign host
-			// 2. If user's persona matches hostPersona (reconnecting host), reclaim host
-			// 3. Otherwise, keep existing host
-			if h.hostPersona == "" {
-				h.hostID = client.ID
-				h.hostPersona = client.User.PersonaName
-			} else if client.User.PersonaName == h.hostPersona {
-				// Reconnecting host reclaims their role
-				h.hostID = client.ID
+			// Capacity and knock-to-enter checks
+			if !h.admitLocked(client) {
+				h.mu.Unlock()
+				continue
 			}
+			h.cancelShutdown()
 
 			// Check if this is a silent rejoin (user reconnected quickly)
 			silentRejoin := false
-			if timer, ok := h.delayedLeavers[client.User.PersonaName]; ok {
+			if timer, ok := h.delayedLeavers[client.ID]; ok {
 				timer.Stop()
-				delete(h.delayedLeavers, client.User.PersonaName)
+				delete(h.delayedLeavers, client.ID)
+				silentRejoin = true
+			}
+
+			// Users carried over by a handoff never left as far as the room knows
+			_, resumed := h.handoffUsers[client.ID]
+			if resumed {
+				delete(h.handoffUsers, client.ID)
 				silentRejoin = true
 			}
 
+			// Another tab or device of a user who is already here joins silently
+			if _, ok := h.clients[client.ID]; ok {
+				silentRejoin = true
+				// It may have come in on the token another of its tabs was holding
+				h.reissueSpentTokens(client.ID)
+			} else {
+				h.clients[client.ID] = client
+			}
+			h.conns[client.ID] = append(h.conns[client.ID], client)
+			
+			// Host assignment logic:
+			// 1. If there is no host (first user), assign host
+			// 2. A reconnecting host keeps the role, because the user ID survives reconnects
+			// 3. Otherwise, keep existing host
+			if h.hostID == "" {
+				h.hostID = client.ID
+			}
+
 			count := len(h.clients) // Get count AFTER adding
 			
+			// Presence clients get one snapshot instead of a list in every user event
+			presence := client.HasFeature(domain.FeaturePresence)
+
 			// Send IDENTITY message first (Critical for reconnects)
-			identityMsg := h.buildUserEventMessage(client, domain.MessageTypeIdentity, count)	
-			select {
-			case client.send <- identityMsg:
-			default:
+			if presence {
+				client.SendMessage(h.buildSlimUserEvent(client, domain.MessageTypeIdentity, count))
+			} else {
+				client.SendFrame(h.buildUserEventMessage(client, domain.MessageTypeIdentity, count))
 			}
 
-			// Build user join message
-			joinMsg := h.buildUserEventMessage(client, domain.MessageTypeUserJoin, count)
-			
-			// Send message history to new client FIRST
-			for _, histMsg := range h.messageHistory.GetAll() {
-				select {
-				case client.send <- histMsg:
-				default:
+			// Tell everyone else first, so the snapshot below already counts this join
+			if !silentRejoin {
+				joinMsg := h.buildUserEventMessage(client, domain.MessageTypeUserJoin, count)
+				slimJoin := NewFrame(h.buildSlimUserEvent(client, domain.MessageTypeUserJoin, count))
+				for c := range h.allConns() {
+					if c.ID != client.ID && c.HasFeature(domain.FeaturePresence) {
+						c.SendFrame(slimJoin)
+					}
 				}
+				h.publishPresence(domain.UserDeltaPayload{
+					Added: []domain.OnlineUser{h.onlineUser(client)},
+				}, client, joinMsg)
+			} else if resumed {
+				// No join message, but lists rebuilt after the handoff still need this user
+				syncMsg := h.buildUserEventMessage(client, domain.MessageTypeUserSync, count)
+				h.publishPresence(domain.UserDeltaPayload{
+					Added: []domain.OnlineUser{h.onlineUser(client)},
+				}, client, syncMsg)
+			}
+
+			if presence {
+				h.sendPresenceSnapshot(client)
 			}
 			
-			// If NOT silent rejoin, send join event to self and others
-			if !silentRejoin {
-				// Send join event to the new client
-				select {
-				case client.send <- joinMsg:
-				default:
-				}
-				
-				h.mu.Unlock()
+			// Send message history to new client FIRST (only what it missed when resuming)
+			h.sendHistory(client)
 
-				// Broadcast user join to ALL OTHER clients
-				h.mu.RLock()
-				for _, c := range h.clients {
-					if c.ID != client.ID {
-						select {
-						case c.send <- joinMsg:
-						default:
-						}
-					}
+			// Replay private whispers addressed to or sent by this persona
+			h.sendWhisperHistoryToClient(client)
+			
+			// If NOT silent rejoin, send join event to self (others were told above)
+			if !silentRejoin {
+				if presence {
+					client.SendMessage(h.buildSlimUserEvent(client, domain.MessageTypeUserJoin, count))
+				} else {
+					client.SendFrame(h.buildUserEventMessage(client, domain.MessageTypeUserJoin, count))
 				}
-			} else {
+			} else if !presence {
 				// Silent rejoin: Just send UserSync to SELF so their list updates
 				// But do NOT broadcast to others (they think user never left)
 				// Note: We hold Lock here, so buildUserEventMessage is safe.
 				syncMsg := h.buildUserEventMessage(client, domain.MessageTypeUserSync, len(h.clients))
 				
-				select {
-				case client.send <- syncMsg:
-				default:
-				}
-				
-				h.mu.Unlock()
-				h.mu.RLock()
+				client.SendFrame(syncMsg)
 			}
 
+			h.mu.Unlock()
+			h.mu.RLock()
+
 			// Send current music state to new client if playing
 			if h.currentMusic != nil && h.currentMusic.IsPlaying {
 				payloadBytes, _ := json.Marshal(h.currentMusic)
@@ -197,11 +356,7 @@ func (h *Hub) Run() {
 					Payload:   payloadBytes,
 					CreatedAt: time.Now(),
 				}
-				data, _ := json.Marshal(syncMsg)
-				select {
-				case client.send <- data:
-				default:
-				}
+				client.SendMessage(syncMsg)
 			}
 
 			// Send queue state to new client
@@ -214,6 +369,19 @@ func (h *Hub) Run() {
 				h.sendNobarSyncToClient(client)
 			}
 
+			// Send mini-game leaderboard to new client
+			if len(h.scoreboard) > 0 {
+				h.sendScoreboardToClient(client)
+			}
+
+			// Send current poll tallies to new client
+			h.sendPollStateToClient(client)
+
+			// Send waiting room to a (re)joining host or moderator
+			if h.can(client, domain.PermApprovals) && len(h.lobby) > 0 {
+				h.sendLobbySync(client)
+			}
+
 			// Send party mode to new client
 			if h.currentPartyMode != "" && h.currentPartyMode != "normal" {
 				payloadBytes, _ := json.Marshal(domain.PartyModePayload{
@@ -225,11 +393,7 @@ func (h *Hub) Run() {
 					Payload:   payloadBytes,
 					CreatedAt: time.Now(),
 				}
-				data, _ := json.Marshal(syncMsg)
-				select {
-				case client.send <- data:
-				default:
-				}
+				client.SendMessage(syncMsg)
 			}
 
 			h.mu.RUnlock()
@@ -237,7 +401,7 @@ func (h *Hub) Run() {
 
 			
 			// Send a delayed sync to ensure client has accurate user list after any race conditions settle
-			go func(c *Client, clientID string) {
+			go func(c *Client) {
 				defer func() {
 					if r := recover(); r != nil {
 						// Client disconnected before sync, ignore
@@ -248,156 +412,218 @@ func (h *Hub) Run() {
 				
 				h.mu.RLock()
 				// Check if client is still registered
-				if _, ok := h.clients[clientID]; !ok {
+				// Presence clients are kept current by versioned deltas instead
+				if !h.hasConn(c) || c.HasFeature(domain.FeaturePresence) {
 					h.mu.RUnlock()
 					return
 				}
 				syncMsg := h.buildUserEventMessage(c, domain.MessageTypeUserSync, len(h.clients))
 				h.mu.RUnlock()
 				
-				select {
-				case c.send <- syncMsg:
-				default:
-				}
-			}(client, client.ID)
+				c.SendFrame(syncMsg)
+			}(client)
 
 		case client := <-h.unregister:
 			h.mu.Lock()
 			// Check if client exists - prevent double unregister
-			if _, ok := h.clients[client.ID]; !ok {
+			if !h.hasConn(client) {
+				client.left = true
+				h.leaveLobby(client) // Waiting client gave up
 				h.mu.Unlock()
 				continue // Client already unregistered, skip
 			}
+			h.dropConn(client)
+			h.mu.Unlock()
 
-			delete(h.clients, client.ID)
-			
-			// Clean up from nobar viewers if present
-			if _, ok := h.nobarViewers[client.ID]; ok {
-				delete(h.nobarViewers, client.ID)
-				h.broadcastNobarViewers()
-			}
+		case frame := <-h.broadcast:
+			h.mu.Lock()
+			h.publishLocked(frame)
+			h.mu.Unlock()
+		}
+	}
+}
 
-			close(client.send)
-			
-			// Delay leave broadcast to prevent spam on refresh
-			personaName := client.User.PersonaName
-			clientToCheck := client
-			
-			leaveTimer := time.AfterFunc(h.leaveDelay, func() {
-				h.mu.Lock()
-				defer h.mu.Unlock()
-				
-				// Make sure we are still tracking this leave
-				if _, ok := h.delayedLeavers[personaName]; !ok {
-					return
-				}
-				delete(h.delayedLeavers, personaName)
+// publishLocked stores a frame in history when it belongs there and delivers it to every connection
+// NOTE: Caller must hold h.mu.Lock
+func (h *Hub) publishLocked(frame *Frame) {
+	// Store message in history using ring buffer (O(1)) - Filter ephemeral types
+	// The frame carries its type, so nothing is re-parsed here
+	switch frame.Type {
+	case domain.MessageTypeChat, domain.MessageTypeSystem,
+		domain.MessageTypeUserJoin, domain.MessageTypeUserLeave,
+		domain.MessageTypeDice, domain.MessageTypeFlip, domain.MessageTypeSpin,
+		domain.MessageTypeGif, domain.MessageTypeSuit,
+		domain.MessageTypeTod, domain.MessageTypePoll,
+		domain.MessageTypeYoutube, domain.MessageTypeHostChange,
+		domain.MessageTypeVibrate, domain.MessageTypeChaos, domain.MessageTypeConfetti,
+		domain.MessageTypeTts:
+		// Numbered so reconnecting clients can resume where they left off
+		frame.stampSeq(h.messageHistory.NextSeq())
+		h.messageHistory.Add(frame.Bytes(CodecJSON))
+	}
 
-				// Release persona name
-				if h.personaReleaser != nil {
-					h.personaReleaser.Release(personaName)
-				}
+	h.deliverLocked(frame)
+}
 
-				count := len(h.clients)
-				
-				// Check if room is now empty
-				if count == 0 {
-					h.hostID = ""
-					h.hostPersona = "" // Reset host persona
-					h.handleStop() // Stop music and clear queue
-					h.scheduleShutdown()
-				} else if personaName == h.hostPersona {
-					// Host left.
-					// Note: Host transfer is handled by a separate goroutine (line 348)
-					// which waits for hostTransferDelay (15s) to allow for reconnects.
-				}
+// deliverLocked sends a frame to every connection without storing it in history
+// NOTE: Caller must hold h.mu.Lock
+func (h *Hub) deliverLocked(frame *Frame) {
+	// Broadcast to every connection that understands the frame, each in its own encoding
+	var slow []*Client
+	for client := range h.allConns() {
+		out := frame.For(client)
+		if !client.Supports(out.Type) {
+			continue
+		}
+		// A frame that is not a message has no MessagePack form
+		data := out.Bytes(client.codec)
+		if data == nil {
+			continue
+		}
+		if !client.enqueue(data) {
+			slow = append(slow, client)
+		}
+	}
+	// Client buffer full: drop the connection as if it had disconnected
+	// (after the loop, since dropping changes h.conns)
+	for _, client := range slow {
+		h.dropConn(client)
+	}
+}
 
-				// Broadcast user leave with accurate count
-				// Fix Deadlock: Do NOT call broadcastUserEventWithCount because it acquires RLock while we hold Lock
-				data := h.buildUserEventMessage(clientToCheck, domain.MessageTypeUserLeave, count)
-				h.Broadcast(data)
-				
-				// Warn last user that room will be destroyed if they leave
-				if count == 1 && h.roomCode != "" {
-					h.sendLastUserWarning()
-				}
-			})
-			
-			h.delayedLeavers[personaName] = leaveTimer
-			
-			// Host transfer check (separate 15s timer for ROLE persistence)
-			// This runs immediately upon disconnect, parallel to the detailed leave timer
-			if count := len(h.clients); count > 0 && client.User.PersonaName == h.hostPersona {
-				previousHostPersona := h.hostPersona
-				
-				go func(personaToCheck string) {
-					time.Sleep(h.hostTransferDelay)
-					
-					h.mu.Lock()
-					defer h.mu.Unlock()
-					
-					// If host persona changed in the meantime, abort
-					if h.hostPersona != personaToCheck {
-						return
-					}
+// announceLeave tells the room a user is gone for good, releasing their persona
+// The last user out resets the room and starts the shutdown grace period
+// NOTE: Caller must hold h.mu.Lock
+func (h *Hub) announceLeave(client *Client) {
+	// Release persona name
+	if h.personaReleaser != nil {
+		h.personaReleaser.Release(client.User.PersonaName)
+	}
 
-					// Check if original host is back
-					isBack := false
-					for _, c := range h.clients {
-						if c.User.PersonaName == personaToCheck {
-							isBack = true
-							h.hostID = c.ID // Ensure ID is correct
-							break
-						}
-					}
-					
-					if !isBack {
-						// Host really left. Pick new host.
-						// Now we UPDATE hostPersona because the old host is gone for good
-						for id, c := range h.clients {
-							h.hostID = id
-							h.hostPersona = c.User.PersonaName
-							break
-						}
-						
-						// Only broadcast if we actually found a new host
-						if h.hostID != "" {
-							h.broadcastHostChange()
-						}
-					}
-				}(previousHostPersona)
-			}
-			h.mu.Unlock()
+	count := len(h.clients)
+
+	// Check if room is now empty
+	// Host leaving a non-empty room is handled by the host transfer check,
+	// which waits for hostTransferDelay to allow for reconnects
+	if count == 0 {
+		h.resetEmptyRoom()
+	}
+
+	// Broadcast user leave with accurate count
+	// Fix Deadlock: Do NOT call broadcastUserEventWithCount because it acquires RLock while we hold Lock
+	h.broadcast <- h.leaveFrame(client, count)
+	h.publishPresence(domain.UserDeltaPayload{
+		Removed: []string{client.User.ID.String()},
+	}, nil, nil)
+
+	// Warn last user that room will be destroyed if they leave
+	if count == 1 && h.roomCode != "" {
+		h.sendLastUserWarning()
+	}
+}
+
+// resetEmptyRoom clears everything tied to the people who were here and schedules the room's removal
+// NOTE: Caller must hold h.mu.Lock
+func (h *Hub) resetEmptyRoom() {
+	h.hostID = ""
+	h.handleStop() // Stop music and clear queue
+	h.whisperHistory = make(map[string]*RingBuffer)
+	h.resetPolls()
+	h.resetSuitMatches()
+	h.clearLobby("Room sudah kosong")
+	h.admittedUsers = make(map[string]bool)
+	h.resetModeration()
+	h.resetFlood()
+	h.moderators = make(map[string]bool)
+	h.scheduleShutdown()
+}
+
+// leaveFrame builds the user_leave broadcast for client, with count users still here
+// History keeps the slim event; legacy clients still get the full list, live and on replay
+// NOTE: Caller must hold at least h.mu.RLock
+func (h *Hub) leaveFrame(client *Client, count int) *Frame {
+	leaveMsg := h.buildSlimUserEvent(client, domain.MessageTypeUserLeave, count)
+	fullLeave := h.buildUserEvent(client, domain.MessageTypeUserLeave, count)
+	return NewFrame(leaveMsg).WithFallback(domain.FeaturePresence, fullLeave)
+}
+
+// pickNewHost hands the room to someone still here, preferring a moderator
+// NOTE: Caller must hold h.mu.Lock
+func (h *Hub) pickNewHost() {
+	previous := h.hostID
+	for id := range h.clients {
+		h.hostID = id
+		if h.moderators[id] {
+			break
+		}
+	}
+	delete(h.moderators, h.hostID) // Owner role supersedes moderator
 
-		case message := <-h.broadcast:
+	// Only broadcast if we actually found a new host
+	if h.hostID != previous {
+		h.broadcastHostChange()
+	}
+}
+
+// dropConn closes one connection and, when it was the user's last, starts the delayed leave
+// and the host transfer check
+// NOTE: Caller must hold h.mu.Lock and the connection must be registered
+func (h *Hub) dropConn(client *Client) {
+	h.removeConn(client)
+	client.closeSend()
+
+	// The user is still here on another tab or device: nobody left
+	if len(h.conns[client.ID]) > 0 {
+		return
+	}
+
+	delete(h.clients, client.ID)
+	
+	// Clean up from nobar viewers if present
+	if _, ok := h.nobarViewers[client.ID]; ok {
+		delete(h.nobarViewers, client.ID)
+		h.broadcastNobarViewers()
+	}
+	
+	// Delay leave broadcast to prevent spam on refresh
+	userID := client.ID
+	clientToCheck := client
+	
+	leaveTimer := time.AfterFunc(h.leaveDelay, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		
+		// Make sure we are still tracking this leave
+		if _, ok := h.delayedLeavers[userID]; !ok {
+			return
+		}
+		delete(h.delayedLeavers, userID)
+
+		h.announceLeave(clientToCheck)
+	})
+	
+	h.delayedLeavers[userID] = leaveTimer
+	
+	// Host transfer check (separate 15s timer for ROLE persistence)
+	// This runs immediately upon disconnect, parallel to the detailed leave timer
+	if count := len(h.clients); count > 0 && client.ID == h.hostID {
+		go func(hostToCheck string) {
+			time.Sleep(h.hostTransferDelay)
+			
 			h.mu.Lock()
-			// Store message in history using ring buffer (O(1)) - Filter ephemeral types
-			var msgH domain.Message
-			if err := json.Unmarshal(message, &msgH); err == nil {
-				switch msgH.Type {
-				case domain.MessageTypeChat, domain.MessageTypeSystem, 
-					domain.MessageTypeUserJoin, domain.MessageTypeUserLeave,
-					domain.MessageTypeDice, domain.MessageTypeFlip, domain.MessageTypeSpin,
-					domain.MessageTypeWhisper, domain.MessageTypeGif, domain.MessageTypeSuit,
-					domain.MessageTypeTod, domain.MessageTypePoll, domain.MessageTypeVote,
-					domain.MessageTypeYoutube, domain.MessageTypeHostChange,
-					domain.MessageTypeVibrate, domain.MessageTypeChaos, domain.MessageTypeConfetti,
-					domain.MessageTypeTts:
-					h.messageHistory.Add(message)
-				}
-			}
+			defer h.mu.Unlock()
 			
-			// Broadcast to all clients
-			for _, client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					// Client buffer full, close connection and remove client
-					close(client.send)
-					delete(h.clients, client.ID)
-				}
+			// If host changed in the meantime, abort
+			if h.hostID != hostToCheck {
+				return
 			}
-			h.mu.Unlock()
-		}
+
+			// Check if original host is back
+			if _, isBack := h.clients[hostToCheck]; isBack {
+				return
+			}
+
+			h.pickNewHost()
+		}(client.ID)
 	}
 }
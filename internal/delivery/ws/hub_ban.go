@@ -0,0 +1,209 @@
+package ws
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+// roomBan keeps a user out of the room by token lineage and IP
+type roomBan struct {
+	id          string
+	personaName string
+	lineage     string
+	ip          string
+	createdAt   time.Time
+	expiresAt   time.Time // Zero = until the room closes
+}
+
+// expired reports whether a timed ban has run out
+func (b *roomBan) expired(now time.Time) bool {
+	return !b.expiresAt.IsZero() && now.After(b.expiresAt)
+}
+
+// matches reports whether a connection falls under this ban
+func (b *roomBan) matches(lineage, ip string) bool {
+	return (lineage != "" && b.lineage == lineage) || (ip != "" && b.ip == ip)
+}
+
+// IsBanned reports whether a connection with this token lineage or IP is banned
+func (h *Hub) IsBanned(lineage, ip string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	now := time.Now()
+	for _, ban := range h.bans {
+		if !ban.expired(now) && ban.matches(lineage, ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// BanUser bans a user from the room, only if requester is host or a moderator
+// duration <= 0 keeps the ban until the room closes
+func (h *Hub) BanUser(requesterID, targetID string, duration time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	requester, ok := h.clients[requesterID]
+	if !ok {
+		return
+	}
+
+	targetClient, exists := h.clients[targetID]
+	if !exists || !h.canActOn(requester, targetClient, domain.PermKick) {
+		return
+	}
+
+	h.banClientLocked(targetClient, duration)
+	h.ejectClient(targetClient, "You have been banned by the host.")
+	h.sendBanList(requester)
+}
+
+// Unban lifts a ban, only if requester is host or a moderator
+func (h *Hub) Unban(requesterID, banID string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	requester, ok := h.clients[requesterID]
+	if !ok || !h.can(requester, domain.PermKick) {
+		return false
+	}
+
+	if _, exists := h.bans[banID]; !exists {
+		return false
+	}
+	delete(h.bans, banID)
+	h.sendBanList(requester)
+	return true
+}
+
+// banClientLocked revokes the client's session tokens and records a ban on its token lineage and IP
+// NOTE: Caller must hold h.mu.Lock
+func (h *Hub) banClientLocked(target *Client, duration time.Duration) {
+	GlobalSessionStore.RevokeLineage(target.lineage)
+	GlobalSessionStore.RemoveByUserID(target.User.ID.String())
+
+	if duration > domain.BanMaxDuration {
+		duration = domain.BanMaxDuration
+	}
+
+	now := time.Now()
+	ban := &roomBan{
+		id:          uuid.New().String(),
+		personaName: target.User.PersonaName,
+		lineage:     target.lineage,
+		ip:          target.remoteIP,
+		createdAt:   now,
+	}
+	if duration > 0 {
+		ban.expiresAt = now.Add(duration)
+	}
+
+	h.pruneBansLocked()
+	h.bans[ban.id] = ban
+}
+
+// pruneBansLocked drops expired bans
+// NOTE: Caller must hold h.mu.Lock
+func (h *Hub) pruneBansLocked() {
+	now := time.Now()
+	for id, ban := range h.bans {
+		if ban.expired(now) {
+			delete(h.bans, id)
+		}
+	}
+}
+
+// HandleBan - Host or moderator bans a user, optionally for a limited time
+func (h *Hub) HandleBan(c *Client, msg domain.Message) {
+	var payload domain.BanPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil || payload.TargetID == "" {
+		h.sendError(c, domain.ErrCodeInvalidPayload, "Format ban tidak valid")
+		return
+	}
+
+	h.mu.RLock()
+	allowed := h.can(c, domain.PermKick)
+	h.mu.RUnlock()
+
+	if !allowed {
+		h.sendError(c, domain.ErrCodeForbidden, "Kamu tidak bisa ban")
+		return
+	}
+
+	h.BanUser(c.ID, payload.TargetID, time.Duration(payload.DurationSec)*time.Second)
+}
+
+// HandleUnban - Host or moderator lifts a ban
+func (h *Hub) HandleUnban(c *Client, msg domain.Message) {
+	var payload domain.BanPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil || payload.BanID == "" {
+		h.sendError(c, domain.ErrCodeInvalidPayload, "Format unban tidak valid")
+		return
+	}
+
+	h.mu.RLock()
+	allowed := h.can(c, domain.PermKick)
+	h.mu.RUnlock()
+
+	if !allowed {
+		h.sendError(c, domain.ErrCodeForbidden, "Kamu tidak bisa unban")
+		return
+	}
+
+	if !h.Unban(c.ID, payload.BanID) {
+		h.sendError(c, domain.ErrCodeBanNotFound, "Ban tidak ditemukan")
+	}
+}
+
+// HandleBanList - Host or moderator asks for the active bans
+func (h *Hub) HandleBanList(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.can(c, domain.PermKick) {
+		h.sendError(c, domain.ErrCodeForbidden, "Kamu tidak bisa melihat daftar ban")
+		return
+	}
+
+	h.sendBanList(c)
+}
+
+// sendBanList sends the active bans to a host or moderator
+// NOTE: Caller must hold h.mu.Lock
+func (h *Hub) sendBanList(client *Client) {
+	h.pruneBansLocked()
+	bans := make([]domain.BanEntry, 0, len(h.bans))
+	for _, ban := range h.bans {
+		entry := domain.BanEntry{
+			ID:          ban.id,
+			PersonaName: ban.personaName,
+			CreatedAt:   ban.createdAt,
+		}
+		if !ban.expiresAt.IsZero() {
+			expiresAt := ban.expiresAt
+			entry.ExpiresAt = &expiresAt
+		}
+		bans = append(bans, entry)
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].CreatedAt.Before(bans[j].CreatedAt)
+	})
+
+	payload, _ := json.Marshal(domain.BanListPayload{
+		Bans: bans,
+	})
+	listMsg := domain.Message{
+		ID:        uuid.New().String(),
+		Type:      domain.MessageTypeBanList,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}
+
+	h.sendFrame(client, NewFrame(listMsg))
+}
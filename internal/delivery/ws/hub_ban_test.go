@@ -0,0 +1,132 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+func TestHub_KickRevokesSessionAndBans(t *testing.T) {
+	hub := NewHub()
+	host := withAllFeatures(newMockClient(hub, "TheHost"))
+	victim := withAllFeatures(newMockClient(hub, "TheVictim"))
+	victim.SetOrigin("10.0.0.9", "lineage-victim")
+	setupRoom(t, hub, host, victim)
+
+	token := GlobalSessionStore.GenerateTokenInLineage(victim.User.ID.String(), "TheVictim", "#000000", "ROOM", "lineage-victim")
+
+	hub.KickUser(host.ID, victim.ID)
+
+	if _, valid := GlobalSessionStore.ValidateToken(token); valid {
+		t.Error("Expected kicked user's session token to be revoked")
+	}
+	if !hub.IsBanned("lineage-victim", "") {
+		t.Error("Expected kicked lineage to be banned")
+	}
+	if !hub.IsBanned("", "10.0.0.9") {
+		t.Error("Expected kicked IP to be banned")
+	}
+	if hub.IsBanned("lineage-other", "10.0.0.10") {
+		t.Error("Expected unrelated connection to be allowed")
+	}
+
+	hub.mu.RLock()
+	for _, ban := range hub.bans {
+		if ban.expiresAt.IsZero() {
+			t.Error("Expected kick ban to be timed")
+		}
+	}
+	hub.mu.RUnlock()
+}
+
+func TestHub_BanAndUnban(t *testing.T) {
+	hub := NewHub()
+	host := withAllFeatures(newMockClient(hub, "TheHost"))
+	victim := withAllFeatures(newMockClient(hub, "TheVictim"))
+	victim.SetOrigin("10.0.0.9", "lineage-victim")
+	setupRoom(t, hub, host, victim)
+
+	payload, _ := json.Marshal(domain.BanPayload{TargetID: victim.ID})
+	hub.HandleBan(host, domain.Message{Payload: payload})
+
+	list, ok := drainForType(host, domain.MessageTypeBanList)
+	if !ok {
+		t.Fatal("Expected ban list for host")
+	}
+	var bans domain.BanListPayload
+	json.Unmarshal(list.Payload, &bans)
+	if len(bans.Bans) != 1 || bans.Bans[0].PersonaName != "TheVictim" {
+		t.Fatalf("Expected one ban for TheVictim, got %+v", bans.Bans)
+	}
+	if bans.Bans[0].ExpiresAt != nil {
+		t.Error("Expected ban without duration to be permanent")
+	}
+	if !hub.IsBanned("", "10.0.0.9") {
+		t.Fatal("Expected victim IP to be banned")
+	}
+
+	payload, _ = json.Marshal(domain.BanPayload{BanID: bans.Bans[0].ID})
+	hub.HandleUnban(host, domain.Message{Payload: payload})
+
+	if hub.IsBanned("lineage-victim", "10.0.0.9") {
+		t.Error("Expected unban to lift the ban")
+	}
+
+	// Unknown ban
+	hub.HandleUnban(host, domain.Message{Payload: payload})
+	msg, ok := drainForType(host, domain.MessageTypeError)
+	if !ok {
+		t.Fatal("Expected error for unknown ban")
+	}
+	var errPayload domain.ErrorPayload
+	json.Unmarshal(msg.Payload, &errPayload)
+	if errPayload.Code != domain.ErrCodeBanNotFound {
+		t.Errorf("Expected %s, got %s", domain.ErrCodeBanNotFound, errPayload.Code)
+	}
+}
+
+func TestHub_BanNotHost(t *testing.T) {
+	hub := NewHub()
+	host := withAllFeatures(newMockClient(hub, "TheHost"))
+	victim := withAllFeatures(newMockClient(hub, "TheVictim"))
+	victim.SetOrigin("10.0.0.9", "lineage-victim")
+	setupRoom(t, hub, host, victim)
+
+	payload, _ := json.Marshal(domain.BanPayload{TargetID: host.ID})
+	hub.HandleBan(victim, domain.Message{Payload: payload})
+
+	if _, ok := drainForType(victim, domain.MessageTypeError); !ok {
+		t.Error("Expected forbidden error for non-host ban")
+	}
+	hub.mu.RLock()
+	count := len(hub.bans)
+	hub.mu.RUnlock()
+	if count != 0 {
+		t.Errorf("Expected no bans, got %d", count)
+	}
+}
+
+func TestHub_TimedBanExpires(t *testing.T) {
+	hub := NewHub()
+	host := withAllFeatures(newMockClient(hub, "TheHost"))
+	victim := withAllFeatures(newMockClient(hub, "TheVictim"))
+	victim.SetOrigin("10.0.0.9", "lineage-victim")
+	setupRoom(t, hub, host, victim)
+
+	hub.BanUser(host.ID, victim.ID, time.Hour)
+	if !hub.IsBanned("", "10.0.0.9") {
+		t.Fatal("Expected timed ban to be active")
+	}
+
+	hub.mu.Lock()
+	for _, ban := range hub.bans {
+		ban.expiresAt = time.Now().Add(-time.Second)
+	}
+	hub.mu.Unlock()
+
+	if hub.IsBanned("", "10.0.0.9") {
+		t.Error("Expected expired ban to be ignored")
+	}
+}
@@ -8,30 +8,52 @@ import (
 	"github.com/mmuslimabdulj/goat-chat/internal/domain"
 )
 
-// buildUserEventMessage creates a user event message as JSON bytes
+// buildUserEventMessage creates a user event frame carrying the full online list
 // NOTE: Caller must hold at least RLock when calling this
-func (h *Hub) buildUserEventMessage(client *Client, eventType domain.MessageType, count int) []byte {
-	// Build list of all online users
-	onlineUsers := make([]map[string]interface{}, 0, len(h.clients))
+func (h *Hub) buildUserEventMessage(client *Client, eventType domain.MessageType, count int) *Frame {
+	return NewFrame(h.buildUserEvent(client, eventType, count))
+}
+
+// buildUserEvent creates a user event message carrying the full online list
+// NOTE: Caller must hold at least RLock when calling this
+func (h *Hub) buildUserEvent(client *Client, eventType domain.MessageType, count int) domain.Message {
+	return h.userEvent(client, eventType, count, h.onlineUsers())
+}
+
+// onlineUsers lists everyone in the room
+// NOTE: Caller must hold at least RLock when calling this
+func (h *Hub) onlineUsers() []domain.OnlineUser {
+	onlineUsers := make([]domain.OnlineUser, 0, len(h.clients))
 	for _, c := range h.clients {
-		onlineUsers = append(onlineUsers, map[string]interface{}{
-			"id":      c.User.ID.String(),
-			"persona": c.User.PersonaName,
-			"color":   c.User.PersonaColor,
-			"battery": c.User.BatteryLevel,
-		})
+		onlineUsers = append(onlineUsers, h.onlineUser(c))
 	}
+	return onlineUsers
+}
 
-	payload, _ := json.Marshal(map[string]interface{}{
-		"user_id":      client.User.ID.String(),
-		"persona":      client.User.PersonaName,
-		"color":        client.User.PersonaColor,
-		"user_count":   count,
-		"host_id":      h.hostID,
-		"online_users": onlineUsers,
-	})
+// buildSlimUserEvent creates a user event message without the online list
+// Sent to clients that keep their list up to date from user_delta frames
+// NOTE: Caller must hold at least RLock when calling this
+func (h *Hub) buildSlimUserEvent(client *Client, eventType domain.MessageType, count int) domain.Message {
+	return h.userEvent(client, eventType, count, nil)
+}
 
-	msg := domain.Message{
+// userEvent builds a user event, omitting online_users when the list is nil
+// NOTE: Caller must hold at least RLock when calling this
+func (h *Hub) userEvent(client *Client, eventType domain.MessageType, count int, onlineUsers []domain.OnlineUser) domain.Message {
+	fields := map[string]interface{}{
+		"user_id":    client.User.ID.String(),
+		"persona":    client.User.PersonaName,
+		"color":      client.User.PersonaColor,
+		"role":       h.roleOf(client),
+		"user_count": count,
+		"host_id":    h.hostID,
+	}
+	if onlineUsers != nil {
+		fields["online_users"] = onlineUsers
+	}
+	payload, _ := json.Marshal(fields)
+
+	return domain.Message{
 		ID:        uuid.New().String(),
 		Type:      eventType,
 		FromID:    client.User.ID.String(),
@@ -40,9 +62,6 @@ func (h *Hub) buildUserEventMessage(client *Client, eventType domain.MessageType
 		Payload:   payload,
 		CreatedAt: time.Now(),
 	}
-
-	data, _ := json.Marshal(msg)
-	return data
 }
 
 // broadcastHostChange sends a host change event to all clients
@@ -65,11 +84,13 @@ func (h *Hub) broadcastHostChange() {
 		CreatedAt: time.Now(),
 	}
 
-	data, _ := json.Marshal(msg)
-	h.Broadcast(data)
-}
-
+	h.BroadcastMessage(msg)
 
+	// New host takes over the waiting room
+	if len(h.lobby) > 0 {
+		h.sendLobbySyncToApprovers()
+	}
+}
 
 // sendLastUserWarning sends a warning to the last remaining user
 func (h *Hub) sendLastUserWarning() {
@@ -80,15 +101,61 @@ func (h *Hub) sendLastUserWarning() {
 		CreatedAt: time.Now(),
 	}
 
-	data, _ := json.Marshal(warningMsg)
+	frame := NewFrame(warningMsg)
 
-	// Send directly to clients (should be only 1)
+	// Send directly to the last user's connections
 	// Do NOT use h.Broadcast() because that stores in history
 	// Note: Caller (timer callback) already holds h.mu Lock
-	for _, client := range h.clients {
-		select {
-		case client.send <- data:
-		default:
-		}
+	for client := range h.allConns() {
+		client.SendFrame(frame)
 	}
 }
+
+// sendSystemNotice sends a system message to a single client without storing it in history
+func (h *Hub) sendSystemNotice(client *Client, text string) {
+	noticeMsg := domain.Message{
+		ID:        uuid.New().String(),
+		Type:      domain.MessageTypeSystem,
+		FromName:  text,
+		CreatedAt: time.Now(),
+	}
+
+	client.SendMessage(noticeMsg)
+}
+
+// sendError sends an error frame to a single client
+// Echoes the client_msg_id of the message being handled, if any
+// Does not touch hub state, so it is safe to call with or without h.mu held
+func (h *Hub) sendError(client *Client, code, message string) {
+	payload, _ := json.Marshal(domain.ErrorPayload{
+		Code:        code,
+		Message:     message,
+		ClientMsgID: client.failRequest(),
+	})
+
+	errMsg := domain.Message{
+		ID:        uuid.New().String(),
+		Type:      domain.MessageTypeError,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}
+
+	h.sendFrame(client, NewFrame(errMsg))
+}
+
+// sendAck confirms to a single client that its message was accepted
+func (h *Hub) sendAck(client *Client, clientMsgID, messageID string) {
+	payload, _ := json.Marshal(domain.AckPayload{
+		ClientMsgID: clientMsgID,
+		MessageID:   messageID,
+	})
+
+	ackMsg := domain.Message{
+		ID:        uuid.New().String(),
+		Type:      domain.MessageTypeAck,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}
+
+	h.sendFrame(client, NewFrame(ackMsg))
+}
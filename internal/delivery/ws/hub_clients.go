@@ -1,5 +1,11 @@
 package ws
 
+import (
+	"iter"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
 // Register adds a client to the hub
 func (h *Hub) Register(c *Client) {
 	h.register <- c
@@ -10,9 +16,91 @@ func (h *Hub) Unregister(c *Client) {
 	h.unregister <- c
 }
 
-// Broadcast sends a message to all connected clients
-func (h *Hub) Broadcast(msg []byte) {
-	h.broadcast <- msg
+// allConns yields every open connection of every user in the room
+// NOTE: Caller must hold at least RLock while iterating
+func (h *Hub) allConns() iter.Seq[*Client] {
+	return func(yield func(*Client) bool) {
+		for _, conns := range h.conns {
+			for _, c := range conns {
+				if !yield(c) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// connsOf returns every open connection of a client's user (just the client if it is not registered)
+// NOTE: Caller must hold at least RLock
+func (h *Hub) connsOf(c *Client) []*Client {
+	if conns := h.conns[c.ID]; len(conns) > 0 {
+		return conns
+	}
+	return []*Client{c}
+}
+
+// hasConn reports whether this particular connection is registered
+// NOTE: Caller must hold at least RLock
+func (h *Hub) hasConn(c *Client) bool {
+	for _, conn := range h.conns[c.ID] {
+		if conn == c {
+			return true
+		}
+	}
+	return false
+}
+
+// removeConn forgets one connection of a user, reporting whether it was registered
+// If it represented the user, another of the user's connections takes its place
+// NOTE: Caller must hold h.mu.Lock
+func (h *Hub) removeConn(c *Client) bool {
+	conns := h.conns[c.ID]
+	for i, conn := range conns {
+		if conn != c {
+			continue
+		}
+		conns = append(conns[:i:i], conns[i+1:]...)
+		if len(conns) == 0 {
+			delete(h.conns, c.ID)
+			return true
+		}
+		h.conns[c.ID] = conns
+		if h.clients[c.ID] == c {
+			h.clients[c.ID] = conns[0]
+		}
+		return true
+	}
+	return false
+}
+
+// sendToUser delivers a frame to every connection of a client's user
+// NOTE: Caller must hold at least RLock
+func (h *Hub) sendToUser(c *Client, frame *Frame) {
+	for _, conn := range h.connsOf(c) {
+		conn.SendFrame(frame)
+	}
+}
+
+// Broadcast sends a JSON-encoded message to all connected clients
+// Data that does not decode as a message is refused with ErrNotMessage
+// Prefer BroadcastMessage, which does not need to read the type back out
+func (h *Hub) Broadcast(msg []byte) error {
+	frame := newJSONFrame(msg)
+	if frame.Type == "" {
+		return ErrNotMessage
+	}
+	h.broadcast <- frame
+	return nil
+}
+
+// BroadcastMessage encodes a message once and sends it to all connected clients
+func (h *Hub) BroadcastMessage(msg domain.Message) {
+	h.broadcast <- NewFrame(msg)
+}
+
+// MaxMessageSize returns the largest inbound frame the room's clients may send
+func (h *Hub) MaxMessageSize() int64 {
+	return h.maxMessageSize
 }
 
 // ClientCount returns the number of connected clients
@@ -22,6 +110,13 @@ func (h *Hub) ClientCount() int {
 	return len(h.clients)
 }
 
+// IsFull reports whether the room is at its occupancy limit
+func (h *Hub) IsFull() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return !h.hasSeatLocked("")
+}
+
 // SetRoomInfo sets the room information for this hub
 func (h *Hub) SetRoomInfo(code, name string, rm *RoomManager) {
 	h.roomCode = code
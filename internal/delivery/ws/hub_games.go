@@ -0,0 +1,86 @@
+package ws
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+// HandleDice rolls the dice server-side and broadcasts the result
+// Any client-supplied result is ignored
+func (h *Hub) HandleDice(c *Client, msg domain.Message) {
+	var req domain.DicePayload
+	if err := json.Unmarshal(msg.Payload, &req); err != nil {
+		h.sendError(c, domain.ErrCodeInvalidPayload, "Format dadu tidak valid")
+		return
+	}
+
+	max := req.Max
+	if max < 2 || max > domain.DiceMaxSides {
+		max = domain.DiceDefaultSides
+	}
+
+	result := rollDice(max)
+	payloadBytes, _ := json.Marshal(domain.DicePayload{
+		Max:    max,
+		Result: result,
+	})
+	msg.Payload = payloadBytes
+
+	h.BroadcastMessage(msg)
+
+	h.mu.Lock()
+	h.recordRoll(c.User.PersonaName, max, result)
+	h.broadcastScoreEntries(c.User.PersonaName)
+	h.mu.Unlock()
+}
+
+// HandleFlip flips the text server-side and broadcasts the result
+func (h *Hub) HandleFlip(c *Client, msg domain.Message) {
+	var req domain.FlipPayload
+	if err := json.Unmarshal(msg.Payload, &req); err != nil {
+		h.sendError(c, domain.ErrCodeInvalidPayload, "Format flip tidak valid")
+		return
+	}
+
+	original := strings.TrimSpace(req.Original)
+	if original == "" {
+		h.sendError(c, domain.ErrCodeInvalidPayload, "Teks flip tidak boleh kosong")
+		return
+	}
+
+	payloadBytes, _ := json.Marshal(domain.FlipPayload{
+		Original: original,
+		Flipped:  flipText(original),
+	})
+	msg.Payload = payloadBytes
+
+	h.BroadcastMessage(msg)
+}
+
+// HandleTod picks a Truth or Dare question server-side and broadcasts it
+// The client may request "truth" or "dare"; the question itself is always server-chosen
+func (h *Hub) HandleTod(c *Client, msg domain.Message) {
+	var req domain.TodPayload
+	if len(msg.Payload) > 0 {
+		if err := json.Unmarshal(msg.Payload, &req); err != nil {
+			h.sendError(c, domain.ErrCodeInvalidPayload, "Format truth or dare tidak valid")
+			return
+		}
+	}
+
+	todType, question := pickTod(req.Type)
+	payloadBytes, _ := json.Marshal(domain.TodPayload{
+		Type:     todType,
+		Question: question,
+	})
+	msg.Payload = payloadBytes
+
+	h.BroadcastMessage(msg)
+
+	h.mu.Lock()
+	h.recordTod(c.User.PersonaName, todType)
+	h.broadcastScoreEntries(c.User.PersonaName)
+	h.mu.Unlock()
+}
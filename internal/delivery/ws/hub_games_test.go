@@ -0,0 +1,179 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+// === SERVER-AUTHORITATIVE GAME TESTS ===
+
+func TestRollDice_InRange(t *testing.T) {
+	for i := 0; i < 500; i++ {
+		v := rollDice(6)
+		if v < 1 || v > 6 {
+			t.Fatalf("rollDice(6) returned out of range value %d", v)
+		}
+	}
+}
+
+func TestFlipText(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"abc", "ɔqɐ"},
+		{"Hi!", "¡ᴉH"},
+		{"", ""},
+		{"😀a", "ɐ😀"},
+	}
+
+	for _, tc := range tests {
+		if got := flipText(tc.input); got != tc.expected {
+			t.Errorf("flipText(%q) = %q, expected %q", tc.input, got, tc.expected)
+		}
+	}
+}
+
+func TestPickTod(t *testing.T) {
+	todType, question := pickTod("truth")
+	if todType != "truth" || question == "" {
+		t.Errorf("Expected truth question, got %s: %q", todType, question)
+	}
+
+	todType, _ = pickTod("dare")
+	if todType != "dare" {
+		t.Errorf("Expected dare, got %s", todType)
+	}
+
+	todType, _ = pickTod("anything")
+	if todType != "truth" && todType != "dare" {
+		t.Errorf("Expected random truth or dare, got %s", todType)
+	}
+}
+
+func TestHub_HandleDice_IgnoresClientResult(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	client := newMockClient(hub, "Roller")
+	hub.Register(client)
+	time.Sleep(30 * time.Millisecond)
+
+	payload, _ := json.Marshal(domain.DicePayload{Max: 20, Result: 9999})
+	hub.HandleDice(client, domain.Message{ID: "d1", Type: domain.MessageTypeDice, Payload: payload})
+
+	msg, ok := drainForType(client, domain.MessageTypeDice)
+	if !ok {
+		t.Fatal("Expected dice result to be broadcast")
+	}
+	var result domain.DicePayload
+	json.Unmarshal(msg.Payload, &result)
+	if result.Max != 20 {
+		t.Errorf("Expected max 20, got %d", result.Max)
+	}
+	if result.Result < 1 || result.Result > 20 {
+		t.Errorf("Expected server-generated result in [1,20], got %d", result.Result)
+	}
+}
+
+func TestHub_HandleDice_ClampsInvalidMax(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	client := newMockClient(hub, "Roller")
+	hub.Register(client)
+	time.Sleep(30 * time.Millisecond)
+
+	payload, _ := json.Marshal(domain.DicePayload{Max: -5})
+	hub.HandleDice(client, domain.Message{ID: "d1", Type: domain.MessageTypeDice, Payload: payload})
+
+	msg, ok := drainForType(client, domain.MessageTypeDice)
+	if !ok {
+		t.Fatal("Expected dice result to be broadcast")
+	}
+	var result domain.DicePayload
+	json.Unmarshal(msg.Payload, &result)
+	if result.Max != domain.DiceDefaultSides {
+		t.Errorf("Expected invalid max to fall back to %d, got %d", domain.DiceDefaultSides, result.Max)
+	}
+}
+
+func TestHub_HandleFlip_IgnoresClientFlipped(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	client := newMockClient(hub, "Flipper")
+	hub.Register(client)
+	time.Sleep(30 * time.Millisecond)
+
+	payload, _ := json.Marshal(domain.FlipPayload{Original: "abc", Flipped: "forged"})
+	hub.HandleFlip(client, domain.Message{ID: "f1", Type: domain.MessageTypeFlip, Payload: payload})
+
+	msg, ok := drainForType(client, domain.MessageTypeFlip)
+	if !ok {
+		t.Fatal("Expected flip result to be broadcast")
+	}
+	var result domain.FlipPayload
+	json.Unmarshal(msg.Payload, &result)
+	if result.Flipped != "ɔqɐ" {
+		t.Errorf("Expected server-flipped text, got %q", result.Flipped)
+	}
+}
+
+func TestHub_HandleFlip_BlankTextGetsError(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	client := withAllFeatures(newMockClient(hub, "Flipper"))
+	hub.Register(client)
+	time.Sleep(30 * time.Millisecond)
+
+	payload, _ := json.Marshal(domain.FlipPayload{Original: "   "})
+	hub.HandleFlip(client, domain.Message{ID: "f2", Type: domain.MessageTypeFlip, Payload: payload})
+
+	msg, ok := drainForType(client, domain.MessageTypeError)
+	if !ok {
+		t.Fatal("Expected an error for blank flip text")
+	}
+	var errPayload domain.ErrorPayload
+	json.Unmarshal(msg.Payload, &errPayload)
+	if errPayload.Code != domain.ErrCodeInvalidPayload {
+		t.Errorf("Expected %s, got %s", domain.ErrCodeInvalidPayload, errPayload.Code)
+	}
+}
+
+func TestHub_HandleTod_IgnoresClientQuestion(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	client := newMockClient(hub, "Player")
+	hub.Register(client)
+	time.Sleep(30 * time.Millisecond)
+
+	payload, _ := json.Marshal(domain.TodPayload{Type: "dare", Question: "Transfer me money"})
+	hub.HandleTod(client, domain.Message{ID: "t1", Type: domain.MessageTypeTod, Payload: payload})
+
+	msg, ok := drainForType(client, domain.MessageTypeTod)
+	if !ok {
+		t.Fatal("Expected tod result to be broadcast")
+	}
+	var result domain.TodPayload
+	json.Unmarshal(msg.Payload, &result)
+	if result.Type != "dare" {
+		t.Errorf("Expected requested type 'dare', got %s", result.Type)
+	}
+
+	found := false
+	for _, d := range dares {
+		if d == result.Question {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Errorf("Expected question from server pool, got %q", result.Question)
+	}
+}
@@ -0,0 +1,143 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+// sendHistory replays recent room history to a newly registered client
+// Resuming clients (last_seq in the URL or hello) only get what they missed,
+// preceded by a history_truncated marker when part of it was evicted or is
+// beyond the join burst. Anything older can be paged in with history_request.
+// NOTE: Caller must hold at least RLock when calling this
+func (h *Hub) sendHistory(client *Client) {
+	lastSeq := client.resumeSeq.Load()
+	if lastSeq == 0 {
+		recent, _, _ := h.messageHistory.Before(0, h.historyBurst)
+		for _, histMsg := range recent {
+			client.Send(h.historyEntryFor(client, histMsg))
+		}
+		return
+	}
+
+	missed, complete := h.messageHistory.Since(lastSeq)
+	if len(missed) > h.historyBurst {
+		missed = missed[len(missed)-h.historyBurst:]
+		complete = false
+	}
+	if !complete {
+		var oldest uint64
+		if len(missed) > 0 {
+			oldest = h.messageHistory.LastSeq() - uint64(len(missed)) + 1
+		}
+		h.sendHistoryTruncated(client, lastSeq, oldest)
+	}
+	for _, histMsg := range missed {
+		client.Send(h.historyEntryFor(client, histMsg))
+	}
+}
+
+// userLeaveMarker finds user_leave entries without decoding every history entry
+var userLeaveMarker = []byte(`"type":"` + string(domain.MessageTypeUserLeave) + `"`)
+
+// historyEntryFor returns a history entry as the client expects it
+// History keeps user_leave slim, so clients without the presence feature get
+// the online list added back (the current one; the sync after replay matches it)
+// NOTE: Caller must hold at least RLock when calling this
+func (h *Hub) historyEntryFor(c *Client, data []byte) []byte {
+	if c.HasFeature(domain.FeaturePresence) || !bytes.Contains(data, userLeaveMarker) {
+		return data
+	}
+
+	var msg domain.Message
+	if err := json.Unmarshal(data, &msg); err != nil || msg.Type != domain.MessageTypeUserLeave {
+		return data
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(msg.Payload, &fields); err != nil {
+		return data
+	}
+	fields["online_users"] = h.onlineUsers()
+	msg.Payload, _ = json.Marshal(fields)
+
+	full, err := json.Marshal(msg)
+	if err != nil {
+		return data
+	}
+	return full
+}
+
+// sendHistoryTruncated tells a resuming client that it missed messages between lastSeq and oldest
+// NOTE: Caller must hold at least RLock when calling this
+func (h *Hub) sendHistoryTruncated(client *Client, lastSeq, oldest uint64) {
+	payload, _ := json.Marshal(domain.HistoryTruncatedPayload{
+		LastSeq:   lastSeq,
+		OldestSeq: oldest,
+	})
+
+	truncatedMsg := domain.Message{
+		ID:        uuid.New().String(),
+		Type:      domain.MessageTypeHistoryTruncated,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}
+
+	client.SendMessage(truncatedMsg)
+}
+
+// HandleHistoryRequest sends a page of history older than the request's cursor
+// The page goes out in HistoryChunkSize frames so a big page takes a few send
+// slots instead of one per message
+func (h *Hub) HandleHistoryRequest(c *Client, msg domain.Message) {
+	var req domain.HistoryRequestPayload
+	if err := json.Unmarshal(msg.Payload, &req); err != nil {
+		h.sendError(c, domain.ErrCodeInvalidPayload, "Format history tidak valid")
+		return
+	}
+
+	h.mu.RLock()
+	page, first, more := h.messageHistory.Before(req.Before, req.Limit)
+	for i, m := range page {
+		page[i] = h.historyEntryFor(c, m)
+	}
+	h.mu.RUnlock()
+
+	var next uint64
+	if more {
+		next = first
+	}
+
+	// An empty page still gets one (final) frame so the client stops waiting
+	for start := 0; start == 0 || start < len(page); start += domain.HistoryChunkSize {
+		end := start + domain.HistoryChunkSize
+		if end > len(page) {
+			end = len(page)
+		}
+
+		chunk := make([]json.RawMessage, 0, end-start)
+		for _, m := range page[start:end] {
+			chunk = append(chunk, m)
+		}
+
+		payload, _ := json.Marshal(domain.HistoryPagePayload{
+			Before:   req.Before,
+			Messages: chunk,
+			Final:    end == len(page),
+			HasMore:  more,
+			Next:     next,
+		})
+
+		pageMsg := domain.Message{
+			ID:        uuid.New().String(),
+			Type:      domain.MessageTypeHistoryPage,
+			Payload:   payload,
+			CreatedAt: time.Now(),
+		}
+
+		h.sendFrame(c, NewFrame(pageMsg))
+	}
+}
@@ -0,0 +1,306 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+func broadcastChat(hub *Hub, text string) {
+	payload, _ := json.Marshal(domain.ChatPayload{Text: text})
+	hub.BroadcastMessage(domain.Message{
+		ID:        uuid.New().String(),
+		Type:      domain.MessageTypeChat,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	})
+}
+
+// broadcastChats sends chats to the room and waits for them to reach history
+func broadcastChats(hub *Hub, texts ...string) {
+	for _, text := range texts {
+		broadcastChat(hub, text)
+	}
+	time.Sleep(50 * time.Millisecond)
+}
+
+// historyChats registers a client and returns the chats replayed to it, plus any truncation marker
+func historyChats(t *testing.T, hub *Hub, c *Client, clients int) ([]domain.Message, *domain.HistoryTruncatedPayload) {
+	t.Helper()
+	hub.Register(c)
+	if !waitForClients(hub, clients) {
+		t.Fatal("Client did not register")
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	var chats []domain.Message
+	var truncated *domain.HistoryTruncatedPayload
+	for {
+		select {
+		case data := <-c.send:
+			var m domain.Message
+			json.Unmarshal(data, &m)
+			switch m.Type {
+			case domain.MessageTypeChat:
+				chats = append(chats, m)
+			case domain.MessageTypeHistoryTruncated:
+				truncated = &domain.HistoryTruncatedPayload{}
+				json.Unmarshal(m.Payload, truncated)
+			}
+		default:
+			return chats, truncated
+		}
+	}
+}
+
+// setupHistoryRoomWith is setupHistoryRoom for a hub built with the given options
+func TestHub_History_MessagesCarrySeq(t *testing.T) {
+	hub := NewHub(WithMaxHistorySize(10))
+	setupRoom(t, hub, newMockClient(hub, "Host"))
+	broadcastChats(hub, "satu", "dua")
+
+	chats, _ := historyChats(t, hub, newMockClient(hub, "Reader"), 2)
+	if len(chats) != 2 {
+		t.Fatalf("Expected 2 chats, got %d", len(chats))
+	}
+	if chats[0].Seq == 0 || chats[1].Seq != chats[0].Seq+1 {
+		t.Errorf("Expected consecutive seqs, got %d and %d", chats[0].Seq, chats[1].Seq)
+	}
+}
+
+func TestHub_History_FallbackCarriesSeq(t *testing.T) {
+	hub := NewHub(WithMaxHistorySize(10))
+	setupRoom(t, hub, newMockClient(hub, "Host"))
+	legacy := newLegacyClient(hub, "Legacy")
+	hub.Register(legacy)
+	if !waitForClients(hub, 2) {
+		t.Fatal("Client did not register")
+	}
+	time.Sleep(50 * time.Millisecond)
+	drainAll(legacy)
+
+	frame := NewFrame(domain.Message{Type: domain.MessageTypeUserLeave, FromID: "gone"}).
+		WithFallback(domain.FeaturePresence, domain.Message{Type: domain.MessageTypeUserLeave, FromID: "gone", FromName: "Gone"})
+	hub.broadcast <- frame
+
+	msg, ok := drainForType(legacy, domain.MessageTypeUserLeave)
+	if !ok || msg.FromName != "Gone" {
+		t.Fatalf("Expected the fallback, got %+v", msg)
+	}
+	hub.mu.RLock()
+	lastSeq := hub.messageHistory.LastSeq()
+	hub.mu.RUnlock()
+	if msg.Seq == 0 || msg.Seq != lastSeq {
+		t.Errorf("Expected the fallback to carry seq %d, got %d", lastSeq, msg.Seq)
+	}
+}
+
+func TestHub_History_ResumeSendsOnlyMissed(t *testing.T) {
+	hub := NewHub(WithMaxHistorySize(10))
+	setupRoom(t, hub, newMockClient(hub, "Host"))
+	broadcastChats(hub, "satu", "dua", "tiga")
+
+	hub.mu.RLock()
+	last := hub.messageHistory.LastSeq()
+	hub.mu.RUnlock()
+
+	c := newMockClient(hub, "Blip")
+	c.SetResumeSeq(last - 1)
+	chats, truncated := historyChats(t, hub, c, 2)
+
+	if truncated != nil {
+		t.Errorf("Unexpected truncation marker: %+v", truncated)
+	}
+	if len(chats) != 1 || chats[0].Seq != last {
+		t.Fatalf("Expected only seq %d, got %+v", last, chats)
+	}
+	var p domain.ChatPayload
+	json.Unmarshal(chats[0].Payload, &p)
+	if p.Text != "tiga" {
+		t.Errorf("Expected 'tiga', got %q", p.Text)
+	}
+}
+
+func TestHub_History_ResumeUpToDateSendsNothing(t *testing.T) {
+	hub := NewHub(WithMaxHistorySize(10))
+	setupRoom(t, hub, newMockClient(hub, "Host"))
+	broadcastChats(hub, "satu", "dua")
+
+	hub.mu.RLock()
+	last := hub.messageHistory.LastSeq()
+	hub.mu.RUnlock()
+
+	c := newMockClient(hub, "Blip")
+	c.SetResumeSeq(last)
+	chats, truncated := historyChats(t, hub, c, 2)
+
+	if truncated != nil || len(chats) != 0 {
+		t.Errorf("Expected no replay, got %d chats, truncated=%+v", len(chats), truncated)
+	}
+}
+
+func TestHub_History_ResumeAfterEvictionSendsMarker(t *testing.T) {
+	hub := NewHub(WithMaxHistorySize(2))
+	setupRoom(t, hub, newMockClient(hub, "Host"))
+	broadcastChats(hub, "satu", "dua", "tiga", "empat")
+
+	c := newMockClient(hub, "Sleeper")
+	c.SetResumeSeq(1)
+	chats, truncated := historyChats(t, hub, c, 2)
+
+	if truncated == nil {
+		t.Fatal("Expected history_truncated marker")
+	}
+	if truncated.LastSeq != 1 || len(chats) != 2 || truncated.OldestSeq != chats[0].Seq {
+		t.Errorf("Unexpected marker %+v for %d chats", truncated, len(chats))
+	}
+}
+
+func TestHub_Hello_LastSeqBeforeAdmission(t *testing.T) {
+	hub := NewHub(WithMaxHistorySize(10))
+	setupRoom(t, hub, newMockClient(hub, "Host"))
+	broadcastChats(hub, "satu", "dua")
+
+	hub.mu.RLock()
+	last := hub.messageHistory.LastSeq()
+	hub.mu.RUnlock()
+
+	// Not registered yet, like a client still knocking
+	c := newMockClient(hub, "Knocker")
+	hello := domain.HelloPayload{Version: domain.ProtocolVersion, LastSeq: last}
+	payload, _ := json.Marshal(hello)
+	hub.HandleHello(c, domain.Message{Type: domain.MessageTypeHello, Payload: payload})
+	drainAll(c)
+
+	chats, _ := historyChats(t, hub, c, 2)
+	if len(chats) != 0 {
+		t.Errorf("Expected no replay after hello last_seq, got %d chats", len(chats))
+	}
+}
+
+func historyPages(t *testing.T, c *Client) []domain.HistoryPagePayload {
+	t.Helper()
+	var pages []domain.HistoryPagePayload
+	for {
+		msg, ok := drainForType(c, domain.MessageTypeHistoryPage)
+		if !ok {
+			t.Fatal("Expected history_page frames until final")
+		}
+		var page domain.HistoryPagePayload
+		json.Unmarshal(msg.Payload, &page)
+		pages = append(pages, page)
+		if page.Final {
+			return pages
+		}
+	}
+}
+
+func TestHub_History_JoinBurstIsLimited(t *testing.T) {
+	hub := NewHub(WithMaxHistorySize(10), WithHistoryJoinBurst(2))
+	setupRoom(t, hub, newMockClient(hub, "Host"))
+	broadcastChats(hub, "satu", "dua", "tiga", "empat")
+
+	chats, _ := historyChats(t, hub, newMockClient(hub, "Reader"), 2)
+	if len(chats) != 2 {
+		t.Fatalf("Expected 2 chats in the burst, got %d", len(chats))
+	}
+	var p domain.ChatPayload
+	json.Unmarshal(chats[1].Payload, &p)
+	if p.Text != "empat" {
+		t.Errorf("Expected the newest chats, last was %q", p.Text)
+	}
+}
+
+func TestHub_History_ResumeBeyondBurstIsTruncated(t *testing.T) {
+	hub := NewHub(WithMaxHistorySize(10), WithHistoryJoinBurst(2))
+	setupRoom(t, hub, newMockClient(hub, "Host"))
+	broadcastChats(hub, "satu", "dua", "tiga", "empat")
+
+	c := newMockClient(hub, "Sleeper")
+	c.SetResumeSeq(1)
+	chats, truncated := historyChats(t, hub, c, 2)
+
+	if truncated == nil || len(chats) != 2 || truncated.OldestSeq != chats[0].Seq {
+		t.Errorf("Expected marker and the 2 newest chats, got %+v and %d chats", truncated, len(chats))
+	}
+}
+
+func TestHub_HistoryRequest_PagesInChunks(t *testing.T) {
+	texts := make([]string, 0, 45)
+	for i := 0; i < 45; i++ {
+		texts = append(texts, "pesan")
+	}
+	hub := NewHub(WithMaxHistorySize(100))
+	setupRoom(t, hub, newMockClient(hub, "Host"))
+	broadcastChats(hub, texts...)
+
+	reader := withAllFeatures(newMockClient(hub, "Reader"))
+	historyChats(t, hub, reader, 2)
+
+	hub.mu.RLock()
+	last := hub.messageHistory.LastSeq()
+	hub.mu.RUnlock()
+
+	dispatchInbound(reader, domain.MessageTypeHistoryRequest, fmt.Sprintf(`{"before":%d,"limit":41}`, last), "")
+	pages := historyPages(t, reader)
+
+	wantChunks := (41 + domain.HistoryChunkSize - 1) / domain.HistoryChunkSize
+	if len(pages) != wantChunks {
+		t.Fatalf("Expected %d chunks, got %d", wantChunks, len(pages))
+	}
+	total := 0
+	for _, p := range pages {
+		if len(p.Messages) > domain.HistoryChunkSize {
+			t.Errorf("Chunk has %d messages, max %d", len(p.Messages), domain.HistoryChunkSize)
+		}
+		total += len(p.Messages)
+	}
+	if total != 41 {
+		t.Errorf("Expected 41 messages, got %d", total)
+	}
+
+	final := pages[len(pages)-1]
+	if !final.HasMore || final.Next != last-41 || final.Before != last {
+		t.Errorf("Unexpected cursor: %+v", final)
+	}
+
+	var first domain.Message
+	json.Unmarshal(pages[0].Messages[0], &first)
+	if first.Seq != final.Next {
+		t.Errorf("Expected page to start at seq %d, got %d", final.Next, first.Seq)
+	}
+}
+
+func TestHub_HistoryRequest_EmptyPageIsFinal(t *testing.T) {
+	hub := NewHub(WithMaxHistorySize(10))
+	setupRoom(t, hub, newMockClient(hub, "Host"))
+	broadcastChats(hub, "satu")
+
+	reader := withAllFeatures(newMockClient(hub, "Reader"))
+	historyChats(t, hub, reader, 2)
+
+	dispatchInbound(reader, domain.MessageTypeHistoryRequest, `{"before":1}`, "")
+	pages := historyPages(t, reader)
+
+	if len(pages) != 1 || len(pages[0].Messages) != 0 || pages[0].HasMore {
+		t.Errorf("Expected one empty final page, got %+v", pages)
+	}
+}
+
+func TestHub_HistoryRequest_NeedsFeature(t *testing.T) {
+	hub := NewHub(WithMaxHistorySize(10))
+	setupRoom(t, hub, newMockClient(hub, "Host"))
+	broadcastChats(hub, "satu")
+
+	legacy := newLegacyClient(hub, "Legacy")
+	historyChats(t, hub, legacy, 2)
+
+	dispatchInbound(legacy, domain.MessageTypeHistoryRequest, `{}`, "")
+	if _, ok := drainForType(legacy, domain.MessageTypeHistoryPage); ok {
+		t.Error("Client without the history feature should not get history_page")
+	}
+}
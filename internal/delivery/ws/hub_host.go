@@ -8,29 +8,44 @@ import (
 	"github.com/mmuslimabdulj/goat-chat/internal/domain"
 )
 
-// KickUser removes a user from the room, only if requester is host
+// KickUser removes a user from the room, only if requester is host or a moderator
 func (h *Hub) KickUser(requesterID, targetID string) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	// Verify requester is host
-	if h.hostID != requesterID {
-		return nil // Ignore unauthorized kick attempts
-	}
-
-	// Cannot kick yourself
-	if requesterID == targetID {
+	requester, ok := h.clients[requesterID]
+	if !ok {
 		return nil
 	}
 
 	targetClient, exists := h.clients[targetID]
 	if !exists {
+		h.sendError(requester, domain.ErrCodeUserOffline, "User tidak ditemukan")
 		return nil // User already gone
 	}
 
+	// Requester must have kick permission and outrank the target (also rules out kicking yourself)
+	if !h.canActOn(requester, targetClient, domain.PermKick) {
+		h.sendError(requester, domain.ErrCodeForbidden, "Kamu tidak bisa kick user ini")
+		return nil
+	}
+
+	// Kicked users cannot come straight back. A lineage ban alone is dodged by
+	// joining as a fresh persona, so the IP is banned too; the ban only lasts
+	// KickBanDuration and sits in the ban list, so others behind the same NAT
+	// are only briefly locked out and the host can unban them
+	h.banClientLocked(targetClient, domain.KickBanDuration)
+	h.ejectClient(targetClient, "You have been kicked by the host.")
+
+	return nil
+}
+
+// ejectClient sends a kick notification to every connection of the user and unregisters them shortly after
+// NOTE: Caller must hold h.mu.Lock
+func (h *Hub) ejectClient(targetClient *Client, reason string) {
 	// Send kick notification to target
 	payload, _ := json.Marshal(map[string]string{
-		"reason": "You have been kicked by the host.",
+		"reason": reason,
 	})
 
 	kickMsg := domain.Message{
@@ -40,16 +55,22 @@ func (h *Hub) KickUser(requesterID, targetID string) error {
 		CreatedAt: time.Now(),
 	}
 
-	data, _ := json.Marshal(kickMsg)
-	targetClient.send <- data
+	frame := NewFrame(kickMsg)
+	conns := append([]*Client(nil), h.connsOf(targetClient)...)
+	for _, c := range conns {
+		c.SendFrame(frame)
+	}
+
+	// Kicked users have to knock again
+	delete(h.admittedUsers, targetClient.ID)
 
 	// Give more time for message to send, then unregister
-	go func(c *Client) {
+	go func() {
 		time.Sleep(500 * time.Millisecond)
-		h.unregister <- c
-	}(targetClient)
-
-	return nil
+		for _, c := range conns {
+			h.unregister <- c
+		}
+	}()
 }
 
 // TransferHost transfers host role to another user
@@ -57,34 +78,73 @@ func (h *Hub) TransferHost(requesterID, newHostID string) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	requester, ok := h.clients[requesterID]
+	if !ok {
+		return
+	}
+
 	// Verify requester is host
 	if h.hostID != requesterID {
+		h.sendError(requester, domain.ErrCodeForbidden, "Hanya host yang bisa transfer host")
 		return
 	}
 
 	// Verify new host exists
 	newHostClient, exists := h.clients[newHostID]
 	if !exists {
+		h.sendError(requester, domain.ErrCodeUserOffline, "User tidak ditemukan")
 		return
 	}
 
 	h.hostID = newHostID
-	h.hostPersona = newHostClient.User.PersonaName
+	delete(h.moderators, h.hostID) // Owner role supersedes moderator
 	h.broadcastHostChange()
 
-	// Force full sync to ensure clients have correct host ID
+	// Sync both changed roles so clients have the correct host ID
 	// We build message directly since we already hold the lock (to avoid deadlock)
-	syncData := h.buildUserEventMessage(newHostClient, domain.MessageTypeUserSync, len(h.clients))
-	h.Broadcast(syncData)
+	h.broadcastUserUpdate(newHostClient, newHostClient, requester)
 }
 
-// ReclaimHost allows a reconnecting user to reclaim host if their persona matches
-func (h *Hub) ReclaimHost(clientID, personaName string) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
+// HandleInvite mints a room invite token, only if requester can approve entries
+func (h *Hub) HandleInvite(c *Client, msg domain.Message) {
+	h.mu.RLock()
+	allowed := h.can(c, domain.PermApprovals)
+	h.mu.RUnlock()
+
+	if !allowed {
+		h.sendError(c, domain.ErrCodeForbidden, "Kamu tidak bisa membuat undangan")
+		return
+	}
 
-	if personaName == h.hostPersona && h.hostID != clientID {
-		h.hostID = clientID
-		h.broadcastHostChange()
+	var req domain.InvitePayload
+	if len(msg.Payload) > 0 {
+		if err := json.Unmarshal(msg.Payload, &req); err != nil {
+			h.sendError(c, domain.ErrCodeInvalidPayload, "Format undangan tidak valid")
+			return
+		}
+	}
+
+	var room *Room
+	if h.roomManager != nil {
+		room = h.roomManager.GetRoom(h.roomCode)
+	}
+	if room == nil {
+		return
 	}
+
+	invite := room.CreateInvite(req.SingleUse, time.Duration(req.TTLSec)*time.Second)
+	payload, _ := json.Marshal(domain.InvitePayload{
+		Token:     invite.Token,
+		SingleUse: invite.SingleUse,
+		ExpiresAt: invite.ExpiresAt,
+	})
+
+	inviteMsg := domain.Message{
+		ID:        uuid.New().String(),
+		Type:      domain.MessageTypeInvite,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}
+
+	h.sendFrame(c, NewFrame(inviteMsg))
 }
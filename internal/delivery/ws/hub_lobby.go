@@ -0,0 +1,256 @@
+package ws
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+// lobbyEntry is a client parked in the knock-to-enter lobby
+type lobbyEntry struct {
+	client    *Client
+	knockedAt time.Time
+}
+
+// admitLocked decides whether a registering client enters now, waits in the lobby, or is turned away
+// NOTE: Caller must hold h.mu.Lock
+func (h *Hub) admitLocked(client *Client) bool {
+	if client.left {
+		return false // Disconnected while an approval was in flight
+	}
+
+	_, rejoining := h.delayedLeavers[client.ID]
+	_, connected := h.clients[client.ID] // Resumed before the old socket timed out
+	returning := rejoining || connected || client.ID == h.hostID
+
+	if !h.hasSeatLocked(client.ID) {
+		h.rejectClient(client, domain.ErrCodeRoomFull, "Room sudah penuh")
+		return false
+	}
+
+	// First user of a fresh room becomes host, nobody to knock on
+	if h.knockToEnter && h.hostID != "" && !returning && !h.admittedUsers[client.ID] {
+		h.parkClient(client)
+		return false
+	}
+
+	client.waiting.Store(false)
+	if h.knockToEnter {
+		h.admittedUsers[client.ID] = true
+	}
+	return true
+}
+
+// HasSeatFor reports whether the room can take this user (empty for a new user)
+// Checked before the upgrade; the hub checks again on register
+func (h *Hub) HasSeatFor(userID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.hasSeatLocked(userID)
+}
+
+// hasSeatLocked reports whether the user fits under the occupancy limit
+// Returning users and leavers still inside the leave delay keep their seat
+// NOTE: Caller must hold at least RLock
+func (h *Hub) hasSeatLocked(userID string) bool {
+	if h.maxOccupancy <= 0 {
+		return true
+	}
+	_, rejoining := h.delayedLeavers[userID]
+	_, connected := h.clients[userID]
+	if rejoining || connected || (userID != "" && userID == h.hostID) {
+		return true
+	}
+	return len(h.clients)+len(h.delayedLeavers) < h.maxOccupancy
+}
+
+// parkClient puts a client in the lobby until the host decides
+// NOTE: Caller must hold h.mu.Lock
+func (h *Hub) parkClient(client *Client) {
+	if len(h.lobby) >= domain.MaxLobbySize {
+		h.rejectClient(client, domain.ErrCodeRoomFull, "Antrian masuk sudah penuh")
+		return
+	}
+
+	// Someone who was waiting before a handoff gets their old place back
+	knockedAt := time.Now()
+	if earlier, ok := h.handoffKnocks[client.ID]; ok {
+		knockedAt = earlier
+		delete(h.handoffKnocks, client.ID)
+	}
+
+	pos := len(h.lobby)
+	for pos > 0 && h.lobby[pos-1].knockedAt.After(knockedAt) {
+		pos--
+	}
+	h.lobby = slices.Insert(h.lobby, pos, &lobbyEntry{
+		client:    client,
+		knockedAt: knockedAt,
+	})
+	h.syncLobby()
+}
+
+// rejectClient sends an error frame and closes the connection of a client that never joined
+// NOTE: Caller must hold h.mu.Lock
+func (h *Hub) rejectClient(client *Client, code, message string) {
+	h.sendError(client, code, message)
+	client.closeSend()
+}
+
+// takeFromLobby removes a waiting client by ID
+// NOTE: Caller must hold h.mu.Lock
+func (h *Hub) takeFromLobby(clientID string) *Client {
+	for i, entry := range h.lobby {
+		if entry.client.ID == clientID {
+			h.lobby = append(h.lobby[:i], h.lobby[i+1:]...)
+			return entry.client
+		}
+	}
+	return nil
+}
+
+// leaveLobby drops a waiting client that disconnected
+// NOTE: Caller must hold h.mu.Lock
+func (h *Hub) leaveLobby(client *Client) {
+	if h.takeFromLobby(client.ID) == nil {
+		return
+	}
+	client.closeSend()
+	h.syncLobby()
+}
+
+// clearLobby turns away everyone still waiting (room emptied)
+// NOTE: Caller must hold h.mu.Lock
+func (h *Hub) clearLobby(reason string) {
+	for _, entry := range h.lobby {
+		h.sendKnockStatus(entry.client, "denied", 0, reason)
+		entry.client.closeSend()
+	}
+	h.lobby = nil
+}
+
+// HandleAdmitApprove - Host or moderator lets a waiting client in
+func (h *Hub) HandleAdmitApprove(c *Client, msg domain.Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.can(c, domain.PermApprovals) {
+		h.sendError(c, domain.ErrCodeForbidden, "Kamu tidak bisa menyetujui")
+		return
+	}
+
+	var payload domain.AdmitPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		h.sendError(c, domain.ErrCodeInvalidPayload, "Format persetujuan tidak valid")
+		return
+	}
+
+	waiting := h.takeFromLobby(payload.ClientID)
+	if waiting == nil {
+		return // Already left or decided
+	}
+
+	h.admittedUsers[waiting.ID] = true
+	h.sendKnockStatus(waiting, "approved", 0, "")
+	h.syncLobby()
+
+	// Re-enter the normal join path from the event loop
+	go func() {
+		h.register <- waiting
+	}()
+}
+
+// HandleAdmitDeny - Host or moderator turns a waiting client away
+func (h *Hub) HandleAdmitDeny(c *Client, msg domain.Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.can(c, domain.PermApprovals) {
+		h.sendError(c, domain.ErrCodeForbidden, "Kamu tidak bisa menolak")
+		return
+	}
+
+	var payload domain.AdmitPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		h.sendError(c, domain.ErrCodeInvalidPayload, "Format penolakan tidak valid")
+		return
+	}
+
+	waiting := h.takeFromLobby(payload.ClientID)
+	if waiting == nil {
+		return
+	}
+
+	reason := payload.Reason
+	if reason == "" {
+		reason = "Host menolak permintaan masuk"
+	}
+	h.sendKnockStatus(waiting, "denied", 0, reason)
+	waiting.closeSend()
+	h.syncLobby()
+}
+
+// syncLobby tells every waiting client its position and sends the list to approvers
+// NOTE: Caller must hold at least RLock when calling this
+func (h *Hub) syncLobby() {
+	for i, entry := range h.lobby {
+		h.sendKnockStatus(entry.client, "waiting", i+1, "")
+	}
+	h.sendLobbySyncToApprovers()
+}
+
+// sendLobbySyncToApprovers sends the waiting room to everyone who can approve
+// NOTE: Caller must hold at least RLock when calling this
+func (h *Hub) sendLobbySyncToApprovers() {
+	for c := range h.allConns() {
+		if h.can(c, domain.PermApprovals) {
+			h.sendLobbySync(c)
+		}
+	}
+}
+
+// sendLobbySync sends the waiting room to a single client
+// NOTE: Caller must hold at least RLock when calling this
+func (h *Hub) sendLobbySync(client *Client) {
+	waiting := make([]domain.LobbyEntry, 0, len(h.lobby))
+	for _, entry := range h.lobby {
+		waiting = append(waiting, domain.LobbyEntry{
+			ID:           entry.client.ID,
+			PersonaName:  entry.client.User.PersonaName,
+			PersonaColor: entry.client.User.PersonaColor,
+			KnockedAt:    entry.knockedAt,
+		})
+	}
+
+	payload, _ := json.Marshal(domain.LobbySyncPayload{
+		Waiting: waiting,
+	})
+	syncMsg := domain.Message{
+		ID:        uuid.New().String(),
+		Type:      domain.MessageTypeLobbySync,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}
+
+	h.sendFrame(client, NewFrame(syncMsg))
+}
+
+// sendKnockStatus sends a lobby status update to a waiting client
+func (h *Hub) sendKnockStatus(client *Client, status string, position int, reason string) {
+	payload, _ := json.Marshal(domain.KnockPayload{
+		Status:   status,
+		Position: position,
+		Reason:   reason,
+	})
+	knockMsg := domain.Message{
+		ID:        uuid.New().String(),
+		Type:      domain.MessageTypeKnock,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}
+
+	h.sendFrame(client, NewFrame(knockMsg))
+}
@@ -0,0 +1,188 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+func lobbySize(hub *Hub) int {
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	return len(hub.lobby)
+}
+
+func admitMessage(clientID string) domain.Message {
+	payload, _ := json.Marshal(domain.AdmitPayload{ClientID: clientID})
+	return domain.Message{Payload: payload}
+}
+
+func TestHub_MaxOccupancy(t *testing.T) {
+	hub := NewHub()
+	hub.maxOccupancy = 2
+	go hub.Run()
+
+	hub.Register(withAllFeatures(newMockClient(hub, "One")))
+	hub.Register(withAllFeatures(newMockClient(hub, "Two")))
+	if !waitForClients(hub, 2) {
+		t.Fatalf("Expected 2 clients, got %d", hub.ClientCount())
+	}
+	if !hub.IsFull() {
+		t.Error("Expected room to report full")
+	}
+
+	extra := withAllFeatures(newMockClient(hub, "Three"))
+	hub.Register(extra)
+
+	msg, ok := drainForType(extra, domain.MessageTypeError)
+	if !ok {
+		t.Fatal("Expected error frame for client over capacity")
+	}
+	var payload domain.ErrorPayload
+	json.Unmarshal(msg.Payload, &payload)
+	if payload.Code != domain.ErrCodeRoomFull {
+		t.Errorf("Expected code %s, got %s", domain.ErrCodeRoomFull, payload.Code)
+	}
+	if hub.ClientCount() != 2 {
+		t.Errorf("Expected occupancy to stay at 2, got %d", hub.ClientCount())
+	}
+}
+
+func TestHub_RejectedClientCanStillSend(t *testing.T) {
+	hub := NewHub()
+	hub.maxOccupancy = 1
+	go hub.Run()
+
+	hub.Register(withAllFeatures(newMockClient(hub, "One")))
+	if !waitForClients(hub, 1) {
+		t.Fatal("Expected first client to enter")
+	}
+
+	extra := withAllFeatures(newMockClient(hub, "Two"))
+	hub.Register(extra)
+	if _, ok := drainForType(extra, domain.MessageTypeError); !ok {
+		t.Fatal("Expected error frame for client over capacity")
+	}
+
+	// ReadPump and the handler keep using a rejected client until its socket closes
+	hub.HandleHello(extra, domain.Message{Payload: json.RawMessage(`{"version":1}`)})
+	extra.Send([]byte(`{}`))
+	hub.Unregister(extra)
+	extra.closeSend()
+}
+
+func TestHub_KnockToEnter_Approve(t *testing.T) {
+	hub := NewHub()
+	hub.knockToEnter = true
+	go hub.Run()
+
+	host := withAllFeatures(newMockClient(hub, "Host"))
+	hub.Register(host)
+	if !waitForClients(hub, 1) {
+		t.Fatal("Expected host to enter without knocking")
+	}
+
+	guest := withAllFeatures(newMockClient(hub, "Guest"))
+	guest.waiting.Store(true)
+	hub.Register(guest)
+
+	knock, ok := drainForType(guest, domain.MessageTypeKnock)
+	if !ok {
+		t.Fatal("Expected knock status for waiting guest")
+	}
+	var status domain.KnockPayload
+	json.Unmarshal(knock.Payload, &status)
+	if status.Status != "waiting" || status.Position != 1 {
+		t.Errorf("Expected waiting at position 1, got %+v", status)
+	}
+
+	sync, ok := drainForType(host, domain.MessageTypeLobbySync)
+	if !ok {
+		t.Fatal("Expected lobby sync for host")
+	}
+	var lobby domain.LobbySyncPayload
+	json.Unmarshal(sync.Payload, &lobby)
+	if len(lobby.Waiting) != 1 || lobby.Waiting[0].ID != guest.ID {
+		t.Fatalf("Expected guest in lobby, got %+v", lobby.Waiting)
+	}
+	if hub.ClientCount() != 1 {
+		t.Errorf("Expected waiting guest outside the room, got %d clients", hub.ClientCount())
+	}
+
+	hub.HandleAdmitApprove(host, admitMessage(guest.ID))
+
+	if !waitForClients(hub, 2) {
+		t.Fatalf("Expected approved guest to join, got %d clients", hub.ClientCount())
+	}
+	if guest.waiting.Load() {
+		t.Error("Expected approved guest to stop waiting")
+	}
+	if lobbySize(hub) != 0 {
+		t.Errorf("Expected empty lobby, got %d", lobbySize(hub))
+	}
+}
+
+func TestHub_KnockToEnter_Deny(t *testing.T) {
+	hub := NewHub()
+	hub.knockToEnter = true
+	go hub.Run()
+
+	host := withAllFeatures(newMockClient(hub, "Host"))
+	hub.Register(host)
+	waitForClients(hub, 1)
+
+	guest := withAllFeatures(newMockClient(hub, "Guest"))
+	hub.Register(guest)
+	for i := 0; i < 50 && lobbySize(hub) == 0; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Only the host decides
+	hub.HandleAdmitDeny(guest, admitMessage(guest.ID))
+	if lobbySize(hub) != 1 {
+		t.Fatal("Expected non-host deny to be ignored")
+	}
+
+	hub.HandleAdmitDeny(host, admitMessage(guest.ID))
+
+	var denied bool
+	for data := range guest.send {
+		var m domain.Message
+		if json.Unmarshal(data, &m) == nil && m.Type == domain.MessageTypeKnock {
+			var status domain.KnockPayload
+			json.Unmarshal(m.Payload, &status)
+			denied = status.Status == "denied"
+		}
+	}
+	if !denied {
+		t.Error("Expected denied status before connection close")
+	}
+	if hub.ClientCount() != 1 {
+		t.Errorf("Expected denied guest to stay out, got %d clients", hub.ClientCount())
+	}
+}
+
+func TestHub_KnockToEnter_WaitingClientLeaves(t *testing.T) {
+	hub := NewHub()
+	hub.knockToEnter = true
+	go hub.Run()
+
+	host := newMockClient(hub, "Host")
+	hub.Register(host)
+	waitForClients(hub, 1)
+
+	guest := newMockClient(hub, "Guest")
+	hub.Register(guest)
+	for i := 0; i < 50 && lobbySize(hub) == 0; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	hub.Unregister(guest)
+	time.Sleep(20 * time.Millisecond)
+
+	if lobbySize(hub) != 0 {
+		t.Errorf("Expected lobby to drop disconnected guest, got %d", lobbySize(hub))
+	}
+}
@@ -0,0 +1,233 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+// chatLikeTypes are the message types a mute silences: everything a user posts for the room to see
+// Room controls (music, nobar, moderation), protocol control and private queries stay available
+var chatLikeTypes = map[domain.MessageType]bool{
+	domain.MessageTypeChat:      true,
+	domain.MessageTypeWhisper:   true,
+	domain.MessageTypeGif:       true,
+	domain.MessageTypeTts:       true,
+	domain.MessageTypeYoutube:   true,
+	domain.MessageTypeSpin:      true,
+	domain.MessageTypeFlip:      true,
+	domain.MessageTypeVibrate:   true,
+	domain.MessageTypeChaos:     true,
+	domain.MessageTypeConfetti:  true,
+	domain.MessageTypeReaction:  true,
+	domain.MessageTypeTyping:    true,
+	domain.MessageTypeDice:      true,
+	domain.MessageTypeTod:       true,
+	domain.MessageTypePoll:      true,
+	domain.MessageTypeVote:      true,
+	domain.MessageTypePollClose: true,
+	domain.MessageTypeSuit:      true,
+}
+
+// timeoutAllowedTypes are all a timed-out client may still send: device status,
+// protocol upkeep and private queries. Room controls are blocked along with chat,
+// so a timed-out moderator cannot keep steering music, nobar or moderation
+var timeoutAllowedTypes = map[domain.MessageType]bool{
+	domain.MessageTypeStatusUpdate:   true,
+	domain.MessageTypeLogout:         true,
+	domain.MessageTypePresenceResync: true,
+	domain.MessageTypeHistoryRequest: true,
+	domain.MessageTypeSuitScoreboard: true,
+	domain.MessageTypeScoreboard:     true,
+	domain.MessageTypeBanList:        true,
+}
+
+// moderationState is an active mute or timeout on a client
+type moderationState struct {
+	kind  domain.MessageType // MessageTypeMute or MessageTypeTimeout
+	until time.Time
+	timer *time.Timer
+}
+
+// moderationKey identifies a client across reconnects (session lineage when known)
+func moderationKey(c *Client) string {
+	if c.lineage != "" {
+		return c.lineage
+	}
+	return "client:" + c.ID
+}
+
+// Silenced reports whether a muted or timed-out client may not send this message type
+// A mute blocks chat-like messages; a timeout blocks everything but timeoutAllowedTypes
+func (h *Hub) Silenced(c *Client, msgType domain.MessageType) bool {
+	kind, ok := h.restriction(c)
+	if !ok {
+		return false
+	}
+	if kind == domain.MessageTypeTimeout {
+		return !timeoutAllowedTypes[msgType]
+	}
+	return chatLikeTypes[msgType]
+}
+
+// restriction returns the kind of the client's active mute or timeout, if any
+func (h *Hub) restriction(c *Client) (domain.MessageType, bool) {
+	h.mu.RLock()
+	state, ok := h.moderation[moderationKey(c)]
+	h.mu.RUnlock()
+
+	if !ok || time.Now().After(state.until) {
+		return "", false
+	}
+	return state.kind, true
+}
+
+// sendSilencedError tells a client its message was dropped by a mute or timeout
+func (h *Hub) sendSilencedError(c *Client) {
+	if kind, _ := h.restriction(c); kind == domain.MessageTypeTimeout {
+		h.sendError(c, domain.ErrCodeMuted, "Kamu sedang di-timeout")
+		return
+	}
+	h.sendError(c, domain.ErrCodeMuted, "Kamu sedang di-mute")
+}
+
+// HandleModeration - Host or moderator mutes, times out, or unmutes a user
+func (h *Hub) HandleModeration(c *Client, msg domain.Message) {
+	var payload domain.ModerationPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil || payload.TargetID == "" {
+		h.sendError(c, domain.ErrCodeInvalidPayload, "Format moderasi tidak valid")
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.can(c, domain.PermKick) {
+		h.sendError(c, domain.ErrCodeForbidden, "Kamu tidak bisa moderasi")
+		return
+	}
+
+	// Cannot moderate yourself
+	if payload.TargetID == c.ID {
+		return
+	}
+
+	target, exists := h.clients[payload.TargetID]
+	if !exists {
+		h.sendError(c, domain.ErrCodeUserOffline, "User tidak ditemukan")
+		return
+	}
+
+	// Moderators cannot act on the owner or each other
+	if !h.canActOn(c, target, domain.PermKick) {
+		h.sendError(c, domain.ErrCodeForbidden, "Kamu tidak bisa moderasi user ini")
+		return
+	}
+
+	if msg.Type == domain.MessageTypeUnmute {
+		if h.clearModerationLocked(moderationKey(target)) {
+			h.notifyUser(target, "🔊 Host mencabut mute kamu")
+		}
+		return
+	}
+
+	duration := h.silenceLocked(target, msg.Type, time.Duration(payload.DurationSec)*time.Second)
+	if msg.Type == domain.MessageTypeTimeout {
+		h.notifyUser(target, fmt.Sprintf("⏳ Kamu di-timeout host selama %s", formatModerationDuration(duration)))
+	} else {
+		h.notifyUser(target, fmt.Sprintf("🔇 Kamu di-mute host selama %s", formatModerationDuration(duration)))
+	}
+}
+
+// silenceLocked applies a mute or timeout, replacing any previous one
+// Returns the duration actually applied; the caller tells the user why they were silenced
+// NOTE: Caller must hold h.mu.Lock
+func (h *Hub) silenceLocked(target *Client, kind domain.MessageType, duration time.Duration) time.Duration {
+	defaultDuration, maxDuration := domain.MuteDefaultDuration, domain.MuteMaxDuration
+	if kind == domain.MessageTypeTimeout {
+		defaultDuration, maxDuration = domain.TimeoutDefaultDuration, domain.TimeoutMaxDuration
+	}
+	if duration <= 0 {
+		duration = defaultDuration
+	}
+	if duration > maxDuration {
+		duration = maxDuration
+	}
+
+	key := moderationKey(target)
+	h.clearModerationLocked(key)
+	h.armModerationLocked(key, kind, time.Now().Add(duration))
+	return duration
+}
+
+// armModerationLocked records a mute or timeout and schedules its end
+// NOTE: Caller must hold h.mu.Lock
+func (h *Hub) armModerationLocked(key string, kind domain.MessageType, until time.Time) {
+	state := &moderationState{
+		kind:  kind,
+		until: until,
+	}
+	state.timer = time.AfterFunc(time.Until(until), func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		// Make sure this is still the active state (not replaced or lifted)
+		if h.moderation[key] != state {
+			return
+		}
+		delete(h.moderation, key)
+
+		notice := "🔊 Mute kamu sudah berakhir"
+		if kind == domain.MessageTypeTimeout {
+			notice = "⏳ Timeout kamu sudah berakhir"
+		}
+		for c := range h.allConns() {
+			if moderationKey(c) == key {
+				h.sendSystemNotice(c, notice)
+			}
+		}
+	})
+	h.moderation[key] = state
+}
+
+// clearModerationLocked lifts a mute or timeout, reporting whether one was active
+// NOTE: Caller must hold h.mu.Lock
+func (h *Hub) clearModerationLocked(key string) bool {
+	state, ok := h.moderation[key]
+	if !ok {
+		return false
+	}
+	state.timer.Stop()
+	delete(h.moderation, key)
+	return true
+}
+
+// resetModeration lifts every mute and timeout (room emptied)
+// NOTE: Caller must hold h.mu.Lock
+func (h *Hub) resetModeration() {
+	for key := range h.moderation {
+		h.clearModerationLocked(key)
+	}
+}
+
+// notifyUser sends a system notice to every connection of the target's user
+// NOTE: Caller must hold at least RLock
+func (h *Hub) notifyUser(target *Client, text string) {
+	for _, c := range h.connsOf(target) {
+		h.sendSystemNotice(c, text)
+	}
+}
+
+// formatModerationDuration renders a duration for notices (e.g. "5 menit")
+func formatModerationDuration(d time.Duration) string {
+	switch {
+	case d >= time.Hour && d%time.Hour == 0:
+		return fmt.Sprintf("%d jam", int(d/time.Hour))
+	case d >= time.Minute && d%time.Minute == 0:
+		return fmt.Sprintf("%d menit", int(d/time.Minute))
+	default:
+		return fmt.Sprintf("%d detik", int(d.Round(time.Second)/time.Second))
+	}
+}
@@ -0,0 +1,151 @@
+package ws
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+func moderationMessage(msgType domain.MessageType, targetID string, durationSec int) domain.Message {
+	payload, _ := json.Marshal(domain.ModerationPayload{TargetID: targetID, DurationSec: durationSec})
+	return domain.Message{Type: msgType, Payload: payload}
+}
+
+func TestHub_Mute(t *testing.T) {
+	hub := NewHub()
+	host := withAllFeatures(newMockClient(hub, "TheHost"))
+	victim := withAllFeatures(newMockClient(hub, "TheVictim"))
+	setupRoom(t, hub, host, victim)
+
+	hub.HandleModeration(host, moderationMessage(domain.MessageTypeMute, victim.ID, 60))
+
+	notice, ok := drainForType(victim, domain.MessageTypeSystem)
+	if !ok || !strings.Contains(notice.FromName, "mute") {
+		t.Errorf("Expected mute notice for target, got %q", notice.FromName)
+	}
+
+	for _, msgType := range []domain.MessageType{
+		domain.MessageTypeChat, domain.MessageTypeDice, domain.MessageTypeTod,
+		domain.MessageTypePoll, domain.MessageTypeVote, domain.MessageTypeSuit,
+	} {
+		if !hub.Silenced(victim, msgType) {
+			t.Errorf("Expected muted user to be unable to send %s", msgType)
+		}
+	}
+	if hub.Silenced(victim, domain.MessageTypeMusic) {
+		t.Error("Expected mute to allow room controls")
+	}
+	if hub.Silenced(host, domain.MessageTypeChat) {
+		t.Error("Expected host to be unaffected")
+	}
+
+	hub.HandleModeration(host, moderationMessage(domain.MessageTypeUnmute, victim.ID, 0))
+	if hub.Silenced(victim, domain.MessageTypeChat) {
+		t.Error("Expected unmute to lift the mute")
+	}
+}
+
+func TestHub_Timeout(t *testing.T) {
+	hub := NewHub()
+	host := withAllFeatures(newMockClient(hub, "TheHost"))
+	victim := withAllFeatures(newMockClient(hub, "TheVictim"))
+	setupRoom(t, hub, host, victim)
+
+	hub.HandleModeration(host, moderationMessage(domain.MessageTypeTimeout, victim.ID, 0))
+
+	for _, msgType := range []domain.MessageType{
+		domain.MessageTypeChat, domain.MessageTypeDice,
+		domain.MessageTypeMusic, domain.MessageTypeNobar, domain.MessageTypeKick, domain.MessageTypeMute,
+	} {
+		if !hub.Silenced(victim, msgType) {
+			t.Errorf("Expected timeout to block %s", msgType)
+		}
+	}
+	for _, msgType := range []domain.MessageType{
+		domain.MessageTypeStatusUpdate, domain.MessageTypeLogout,
+		domain.MessageTypePresenceResync, domain.MessageTypeHistoryRequest,
+	} {
+		if hub.Silenced(victim, msgType) {
+			t.Errorf("Expected timeout to allow %s", msgType)
+		}
+	}
+
+	hub.mu.RLock()
+	state := hub.moderation[moderationKey(victim)]
+	hub.mu.RUnlock()
+	if remaining := time.Until(state.until); remaining > domain.TimeoutDefaultDuration || remaining < domain.TimeoutDefaultDuration-time.Second {
+		t.Errorf("Expected default timeout duration, got %v", remaining)
+	}
+}
+
+func TestHub_MuteExpires(t *testing.T) {
+	hub := NewHub()
+	victim := withAllFeatures(newMockClient(hub, "TheVictim"))
+	setupRoom(t, hub, withAllFeatures(newMockClient(hub, "TheHost")), victim)
+
+	hub.mu.Lock()
+	hub.silenceLocked(victim, domain.MessageTypeMute, 30*time.Millisecond)
+	hub.mu.Unlock()
+
+	if !hub.Silenced(victim, domain.MessageTypeChat) {
+		t.Fatal("Expected mute to be active")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	if hub.Silenced(victim, domain.MessageTypeChat) {
+		t.Error("Expected mute to expire")
+	}
+
+	// The expiry timer removes the state, so wait for it rather than a fixed time
+	deadline := time.Now().Add(time.Second)
+	for {
+		hub.mu.RLock()
+		_, exists := hub.moderation[moderationKey(victim)]
+		hub.mu.RUnlock()
+		if !exists {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected expired mute to be removed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHub_ExpiryNoticeNamesTheRestriction(t *testing.T) {
+	hub := NewHub()
+	victim := withAllFeatures(newMockClient(hub, "TheVictim"))
+	setupRoom(t, hub, withAllFeatures(newMockClient(hub, "TheHost")), victim)
+
+	hub.mu.Lock()
+	hub.silenceLocked(victim, domain.MessageTypeTimeout, 30*time.Millisecond)
+	hub.mu.Unlock()
+	drainAll(victim)
+
+	time.Sleep(60 * time.Millisecond)
+
+	notice, ok := drainForType(victim, domain.MessageTypeSystem)
+	if !ok || !strings.Contains(notice.FromName, "Timeout kamu sudah berakhir") {
+		t.Errorf("Expected a timeout expiry notice, got %q", notice.FromName)
+	}
+}
+
+func TestHub_MuteNotHost(t *testing.T) {
+	hub := NewHub()
+	host := withAllFeatures(newMockClient(hub, "TheHost"))
+	victim := withAllFeatures(newMockClient(hub, "TheVictim"))
+	setupRoom(t, hub, host, victim)
+
+	hub.HandleModeration(victim, moderationMessage(domain.MessageTypeMute, host.ID, 60))
+
+	if _, ok := drainForType(victim, domain.MessageTypeError); !ok {
+		t.Error("Expected forbidden error for non-host mute")
+	}
+	if hub.Silenced(host, domain.MessageTypeChat) {
+		t.Error("Expected non-host mute to be ignored")
+	}
+}
@@ -12,13 +12,23 @@ import (
 func (h *Hub) HandleMusic(client *Client, msg *domain.Message) {
 	var payload domain.MusicPayload
 	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		h.sendError(client, domain.ErrCodeInvalidPayload, "Format musik tidak valid")
 		return
 	}
 
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	isHost := client.ID == h.hostID
+	isHost := h.can(client, domain.PermMusic) // Host or moderator
+
+	// Playback controls are host-only
+	switch payload.Action {
+	case "pause", "resume", "stop", "next", "seek":
+		if !isHost {
+			h.sendError(client, domain.ErrCodeForbidden, "Hanya host yang bisa mengatur musik")
+			return
+		}
+	}
 
 	switch payload.Action {
 	case "play":
@@ -28,21 +38,13 @@ func (h *Hub) HandleMusic(client *Client, msg *domain.Message) {
 			h.handleUserRequest(client, &payload)
 		}
 	case "pause", "resume":
-		if isHost {
-			h.handlePauseResume(&payload)
-		}
+		h.handlePauseResume(&payload)
 	case "stop":
-		if isHost {
-			h.handleStop()
-		}
+		h.handleStop()
 	case "next":
-		if isHost {
-			h.handleNext()
-		}
+		h.handleNext()
 	case "seek":
-		if isHost {
-			h.handleMusicSeek(payload.CurrentTime)
-		}
+		h.handleMusicSeek(payload.CurrentTime)
 	case "ended":
 		// Song ended, play next or stop
 		h.handleSongEnded()
@@ -62,6 +64,7 @@ func (h *Hub) HandleMusic(client *Client, msg *domain.Message) {
 func (h *Hub) handleHostPlay(client *Client, payload *domain.MusicPayload) {
 	// Validate YouTube video ID format
 	if !IsValidYouTubeVideoID(payload.VideoID) {
+		h.sendError(client, domain.ErrCodeInvalidPayload, "ID video YouTube tidak valid")
 		return
 	}
 
@@ -92,6 +95,7 @@ func (h *Hub) handleHostPlay(client *Client, payload *domain.MusicPayload) {
 func (h *Hub) handleUserRequest(client *Client, payload *domain.MusicPayload) {
 	// Validate YouTube video ID format
 	if !IsValidYouTubeVideoID(payload.VideoID) {
+		h.sendError(client, domain.ErrCodeInvalidPayload, "ID video YouTube tidak valid")
 		return
 	}
 
@@ -112,12 +116,14 @@ func (h *Hub) HandleMusicApprove(client *Client, msg *domain.Message) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	if client.ID != h.hostID {
+	if !h.can(client, domain.PermApprovals) {
+		h.sendError(client, domain.ErrCodeForbidden, "Kamu tidak bisa menyetujui request")
 		return
 	}
 
 	var payload domain.MusicApprovePayload
 	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		h.sendError(client, domain.ErrCodeInvalidPayload, "Format request tidak valid")
 		return
 	}
 
@@ -143,6 +149,8 @@ func (h *Hub) HandleMusicApprove(client *Client, msg *domain.Message) {
 			return
 		}
 	}
+
+	h.sendError(client, domain.ErrCodeNotFound, "Request lagu tidak ditemukan")
 }
 
 // HandleMusicReject - Host rejects a pending request
@@ -150,12 +158,14 @@ func (h *Hub) HandleMusicReject(client *Client, msg *domain.Message) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	if client.ID != h.hostID {
+	if !h.can(client, domain.PermApprovals) {
+		h.sendError(client, domain.ErrCodeForbidden, "Kamu tidak bisa menolak request")
 		return
 	}
 
 	var payload domain.MusicRejectPayload
 	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		h.sendError(client, domain.ErrCodeInvalidPayload, "Format request tidak valid")
 		return
 	}
 
@@ -167,6 +177,8 @@ func (h *Hub) HandleMusicReject(client *Client, msg *domain.Message) {
 			return
 		}
 	}
+
+	h.sendError(client, domain.ErrCodeNotFound, "Request lagu tidak ditemukan")
 }
 
 // playNow starts playing a song immediately
@@ -246,8 +258,7 @@ func (h *Hub) handleStop() {
 		Payload:   payloadBytes,
 		CreatedAt: time.Now(),
 	}
-	data, _ := json.Marshal(syncMsg)
-	h.Broadcast(data)
+	h.BroadcastMessage(syncMsg)
 
 	// Reset all music state after broadcast
 	h.currentMusic = nil
@@ -279,8 +290,7 @@ func (h *Hub) broadcastMusicSync() {
 			Payload:   payloadBytes,
 			CreatedAt: time.Now(),
 		}
-		data, _ := json.Marshal(syncMsg)
-		h.Broadcast(data)
+		h.BroadcastMessage(syncMsg)
 		return
 	}
 
@@ -291,14 +301,13 @@ func (h *Hub) broadcastMusicSync() {
 		Payload:   payloadBytes,
 		CreatedAt: time.Now(),
 	}
-	data, _ := json.Marshal(syncMsg)
-	h.Broadcast(data)
+	h.BroadcastMessage(syncMsg)
 }
 
 // broadcastQueueSync sends queue state to all clients
 func (h *Hub) broadcastQueueSync() {
 	// Send to each client individually (host sees pending, others don't)
-	for _, c := range h.clients {
+	for c := range h.allConns() {
 		h.sendQueueSyncToClient(c)
 	}
 }
@@ -325,10 +334,5 @@ func (h *Hub) sendQueueSyncToClient(client *Client) {
 		Payload:   payloadBytes,
 		CreatedAt: time.Now(),
 	}
-	data, _ := json.Marshal(syncMsg)
-
-	select {
-	case client.send <- data:
-	default:
-	}
+	client.SendMessage(syncMsg)
 }
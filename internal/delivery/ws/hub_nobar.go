@@ -16,11 +16,18 @@ func (h *Hub) HandleNobar(c *Client, msg domain.Message) {
 	var payload domain.NobarPayload
 	payloadBytes, _ := json.Marshal(msg.Payload)
 	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
+		h.sendError(c, domain.ErrCodeInvalidPayload, "Format nobar tidak valid")
 		return
 	}
 
-	// Determine if this is a request or a command
-	isHost := c.ID == h.hostID
+	// Anything that queues or starts a video needs a real video ID
+	if (payload.Action == "request" || payload.Action == "play") && !IsValidYouTubeVideoID(payload.VideoID) {
+		h.sendError(c, domain.ErrCodeInvalidPayload, "ID video YouTube tidak valid")
+		return
+	}
+
+	// Determine if this is a request or a command (host or moderator)
+	isHost := h.can(c, domain.PermNobar)
 
 	// If payload action is "request", anyone can do it
 	if payload.Action == "request" {
@@ -47,8 +54,23 @@ func (h *Hub) HandleNobar(c *Client, msg domain.Message) {
 		return
 	}
 
-	// All other actions require host privileges
+	// Request approvals have their own permission
+	if payload.Action == "approve" || payload.Action == "reject" {
+		if !h.can(c, domain.PermApprovals) {
+			h.sendError(c, domain.ErrCodeForbidden, "Kamu tidak bisa menyetujui request")
+			return
+		}
+		if payload.Action == "approve" {
+			h.handleNobarApprove(payload)
+		} else {
+			h.handleNobarReject(payload)
+		}
+		return
+	}
+
+	// All other actions require nobar control
 	if !isHost {
+		h.sendError(c, domain.ErrCodeForbidden, "Hanya host yang bisa mengatur nobar")
 		return
 	}
 
@@ -70,10 +92,6 @@ func (h *Hub) HandleNobar(c *Client, msg domain.Message) {
 		h.handleNobarSeek(payload.CurrentTime)
 	case "sync_meta":
 		h.handleNobarSyncMeta(payload)
-	case "approve":
-		h.handleNobarApprove(payload)
-	case "reject":
-		h.handleNobarReject(payload)
 	case "ended":
 		h.handleNobarEnded()
 	case "skip":
@@ -200,16 +218,9 @@ func (h *Hub) broadcastNobarSync() {
 		Payload: payloadBytes,
 	}
 
-	data, err := json.Marshal(syncMsg)
-	if err != nil {
-		return
-	}
-
-	for _, client := range h.clients {
-		select {
-		case client.send <- data:
-		default:
-		}
+	frame := NewFrame(syncMsg)
+	for client := range h.allConns() {
+		client.SendFrame(frame)
 	}
 }
 
@@ -237,15 +248,7 @@ func (h *Hub) sendNobarSyncToClient(c *Client) {
 		Payload: payloadBytes,
 	}
 
-	data, err := json.Marshal(syncMsg)
-	if err != nil {
-		return
-	}
-
-	select {
-	case c.send <- data:
-	default:
-	}
+	c.SendMessage(syncMsg)
 }
 
 // handleNobarRequest adds a video request to the appropriate queue
@@ -259,8 +262,8 @@ func (h *Hub) handleNobarRequest(c *Client, payload domain.NobarPayload) {
 		RequestedByName: c.User.PersonaName,
 	}
 
-	// If host, add to active Playlist Queue
-	if c.ID == h.hostID {
+	// If host or moderator, add to active Playlist Queue
+	if h.can(c, domain.PermNobar) {
 		h.nobarQueue = append(h.nobarQueue, item)
 
 
@@ -292,11 +295,7 @@ func (h *Hub) handleNobarRequest(c *Client, payload domain.NobarPayload) {
 			Payload: successPayload,
 		}
 
-		data, _ := json.Marshal(successMsg)
-		select {
-		case c.send <- data:
-		default:
-		}
+		c.SendMessage(successMsg)
 	}
 
 	// Sync updated queue to host
@@ -381,12 +380,9 @@ func (h *Hub) broadcastNobarQueue() {
 		Payload: payloadBytes,
 	}
 
-	data, err := json.Marshal(syncMsg)
-	if err == nil {
-		select {
-		case h.broadcast <- data:
-		default:
-		}
+	select {
+	case h.broadcast <- NewFrame(syncMsg):
+	default:
 	}
 }
 
@@ -447,11 +443,8 @@ func (h *Hub) broadcastNobarViewers() {
 		Payload: payloadBytes,
 	}
 
-	data, err := json.Marshal(syncMsg)
-	if err == nil {
-		select {
-		case h.broadcast <- data:
-		default:
-		}
+	select {
+	case h.broadcast <- NewFrame(syncMsg):
+	default:
 	}
 }
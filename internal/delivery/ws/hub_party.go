@@ -7,21 +7,23 @@ import (
 	"github.com/mmuslimabdulj/goat-chat/internal/domain"
 )
 
-// HandlePartyChange processes party mode change requests from host
+// HandlePartyChange processes party mode change requests from host or moderators
 func (h *Hub) HandlePartyChange(c *Client, msg domain.Message) {
-	// 1. Verify Host Authorization
+	// 1. Verify Role Authorization
 	h.mu.RLock()
-	isHost := c.ID == h.hostID
+	allowed := h.can(c, domain.PermParty)
 	h.mu.RUnlock()
 
-	if !isHost {
-		return // Ignore non-host requests
+	if !allowed {
+		h.sendError(c, domain.ErrCodeForbidden, "Kamu tidak bisa mengubah party mode")
+		return
 	}
 
 	// 2. Parse payload
 	var payload domain.PartyModePayload
 	payloadBytes, _ := json.Marshal(msg.Payload)
 	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
+		h.sendError(c, domain.ErrCodeInvalidPayload, "Format party mode tidak valid")
 		return
 	}
 
@@ -59,12 +61,9 @@ func (h *Hub) broadcastPartyMode() {
 		Payload: payloadBytes,
 	}
 
-	data, err := json.Marshal(syncMsg)
-	if err == nil {
-		select {
-		case h.broadcast <- data:
-		default:
-		}
+	select {
+	case h.broadcast <- NewFrame(syncMsg):
+	default:
 	}
 }
 
@@ -87,11 +86,8 @@ func (h *Hub) broadcastPartyModeUnlocked() {
 		Payload: payloadBytes,
 	}
 
-	data, err := json.Marshal(syncMsg)
-	if err == nil {
-		select {
-		case h.broadcast <- data:
-		default:
-		}
+	select {
+	case h.broadcast <- NewFrame(syncMsg):
+	default:
 	}
 }
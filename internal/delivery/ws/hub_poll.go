@@ -0,0 +1,272 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+// pollVote records a single user's vote
+type pollVote struct {
+	optionIndex int
+}
+
+// pollState is the server-side state of a poll
+type pollState struct {
+	payload  domain.PollPayload
+	votes    map[string]pollVote // user ID -> vote
+	timer    *time.Timer
+	closesAt time.Time // zero for polls without a duration
+}
+
+// HandlePoll creates a new poll and broadcasts it
+func (h *Hub) HandlePoll(c *Client, msg domain.Message) {
+	var req domain.PollPayload
+	if err := json.Unmarshal(msg.Payload, &req); err != nil {
+		h.sendError(c, domain.ErrCodeInvalidPayload, "Format poll tidak valid")
+		return
+	}
+
+	question := strings.TrimSpace(req.Question)
+	options := make([]string, 0, len(req.Options))
+	seen := make(map[string]bool)
+	for _, opt := range req.Options {
+		opt = strings.TrimSpace(opt)
+		if opt == "" || seen[opt] {
+			continue
+		}
+		seen[opt] = true
+		options = append(options, opt)
+	}
+
+	if question == "" || len(options) < domain.PollMinOptions || len(options) > domain.PollMaxOptions {
+		h.sendError(c, domain.ErrCodeInvalidPayload,
+			fmt.Sprintf("Poll butuh pertanyaan dan %d-%d opsi", domain.PollMinOptions, domain.PollMaxOptions))
+		return
+	}
+
+	h.mu.Lock()
+
+	pollID := req.PollID
+	if _, exists := h.polls[pollID]; exists || pollID == "" {
+		pollID = uuid.New().String()
+	}
+
+	poll := &pollState{
+		payload: domain.PollPayload{
+			Question:    question,
+			Options:     options,
+			PollID:      pollID,
+			CreatorID:   c.ID,
+			AllowChange: req.AllowChange,
+		},
+		votes: make(map[string]pollVote),
+	}
+
+	if req.DurationSec > 0 {
+		duration := time.Duration(req.DurationSec) * time.Second
+		if duration > domain.PollMaxDuration {
+			duration = domain.PollMaxDuration
+		}
+		poll.payload.DurationSec = int(duration / time.Second)
+		h.startPollTimer(poll, duration)
+	}
+
+	h.polls[pollID] = poll
+	h.pollOrder = append(h.pollOrder, pollID)
+	h.evictOldPolls()
+
+	payloadBytes, _ := json.Marshal(h.pollSnapshot(poll))
+	h.mu.Unlock()
+
+	msg.Payload = payloadBytes
+	h.BroadcastMessage(msg)
+}
+
+// HandleVote records a vote (one per user) and broadcasts the new tally
+func (h *Hub) HandleVote(c *Client, msg domain.Message) {
+	var req domain.VotePayload
+	if err := json.Unmarshal(msg.Payload, &req); err != nil {
+		h.sendError(c, domain.ErrCodeInvalidPayload, "Format vote tidak valid")
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	poll, ok := h.polls[req.PollID]
+	if !ok {
+		h.sendError(c, domain.ErrCodePollNotFound, "Poll tidak ditemukan")
+		return
+	}
+	if poll.payload.Closed {
+		h.sendError(c, domain.ErrCodePollClosed, "Poll sudah ditutup")
+		return
+	}
+	if req.OptionIndex < 0 || req.OptionIndex >= len(poll.payload.Options) {
+		h.sendError(c, domain.ErrCodeInvalidPayload, "Opsi tidak valid")
+		return
+	}
+
+	if existing, voted := poll.votes[c.ID]; voted {
+		if !poll.payload.AllowChange {
+			h.sendError(c, domain.ErrCodeAlreadyVoted, "Kamu sudah vote di poll ini")
+			return
+		}
+		if existing.optionIndex == req.OptionIndex {
+			return
+		}
+	}
+
+	poll.votes[c.ID] = pollVote{
+		optionIndex: req.OptionIndex,
+	}
+
+	// Clients without polls get the vote itself and tally it locally, as in v1
+	payloadBytes, _ := json.Marshal(domain.VotePayload{PollID: req.PollID, OptionIndex: req.OptionIndex})
+	msg.Payload = payloadBytes
+	h.sendPollFrame(h.buildPollUpdate(poll).WithFallback(domain.FeaturePolls, msg))
+}
+
+// HandlePollClose closes a poll, only if requester is host or the poll creator
+func (h *Hub) HandlePollClose(c *Client, msg domain.Message) {
+	var req domain.PollClosePayload
+	if err := json.Unmarshal(msg.Payload, &req); err != nil {
+		h.sendError(c, domain.ErrCodeInvalidPayload, "Format tidak valid")
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	poll, ok := h.polls[req.PollID]
+	if !ok {
+		h.sendError(c, domain.ErrCodePollNotFound, "Poll tidak ditemukan")
+		return
+	}
+
+	if c.ID != h.hostID && c.ID != poll.payload.CreatorID {
+		h.sendError(c, domain.ErrCodeForbidden, "Hanya host atau pembuat poll yang bisa menutup poll")
+		return
+	}
+
+	h.closePoll(req.PollID)
+}
+
+// startPollTimer closes the poll once d has passed
+// NOTE: Caller must hold h.mu Lock
+func (h *Hub) startPollTimer(poll *pollState, d time.Duration) {
+	poll.closesAt = time.Now().Add(d)
+
+	pollID := poll.payload.PollID
+	poll.timer = time.AfterFunc(d, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.closePoll(pollID)
+	})
+}
+
+// closePoll marks a poll as closed and broadcasts the final tally
+// NOTE: Caller must hold h.mu Lock
+func (h *Hub) closePoll(pollID string) {
+	poll, ok := h.polls[pollID]
+	if !ok || poll.payload.Closed {
+		return
+	}
+
+	if poll.timer != nil {
+		poll.timer.Stop()
+		poll.timer = nil
+	}
+	poll.payload.Closed = true
+	h.broadcastPollUpdate(poll)
+}
+
+// evictOldPolls drops the oldest polls once the per-room limit is exceeded
+// NOTE: Caller must hold h.mu Lock
+func (h *Hub) evictOldPolls() {
+	for len(h.pollOrder) > domain.MaxPollsPerRoom {
+		oldest := h.pollOrder[0]
+		h.pollOrder = h.pollOrder[1:]
+		if poll, ok := h.polls[oldest]; ok && poll.timer != nil {
+			poll.timer.Stop()
+		}
+		delete(h.polls, oldest)
+	}
+}
+
+// resetPolls stops all poll timers and clears poll state
+// NOTE: Caller must hold h.mu Lock
+func (h *Hub) resetPolls() {
+	for _, poll := range h.polls {
+		if poll.timer != nil {
+			poll.timer.Stop()
+		}
+	}
+	h.polls = make(map[string]*pollState)
+	h.pollOrder = nil
+}
+
+// pollSnapshot builds the authoritative payload with current tallies
+// NOTE: Caller must hold at least RLock
+func (h *Hub) pollSnapshot(poll *pollState) domain.PollPayload {
+	snapshot := poll.payload
+	snapshot.Votes = make(map[string]int, len(poll.payload.Options))
+	snapshot.Voters = make([]string, 0, len(poll.votes))
+
+	for _, opt := range poll.payload.Options {
+		snapshot.Votes[opt] = 0
+	}
+	for userID, vote := range poll.votes {
+		snapshot.Votes[poll.payload.Options[vote.optionIndex]]++
+		snapshot.Voters = append(snapshot.Voters, userID)
+	}
+
+	return snapshot
+}
+
+// buildPollUpdate creates a poll_update frame
+// NOTE: Caller must hold at least RLock
+func (h *Hub) buildPollUpdate(poll *pollState) *Frame {
+	payloadBytes, _ := json.Marshal(h.pollSnapshot(poll))
+
+	msg := domain.Message{
+		ID:        uuid.New().String(),
+		Type:      domain.MessageTypePollUpdate,
+		Payload:   payloadBytes,
+		CreatedAt: time.Now(),
+	}
+
+	return NewFrame(msg)
+}
+
+// broadcastPollUpdate sends the poll tally to all clients
+// NOTE: Caller must hold at least RLock
+func (h *Hub) broadcastPollUpdate(poll *pollState) {
+	h.sendPollFrame(h.buildPollUpdate(poll))
+}
+
+// sendPollFrame sends a poll frame to all clients
+// Sent directly (not via h.Broadcast) so tallies are not stored in history
+// NOTE: Caller must hold at least RLock
+func (h *Hub) sendPollFrame(frame *Frame) {
+	for client := range h.allConns() {
+		h.sendFrame(client, frame)
+	}
+}
+
+// sendPollStateToClient replays the current state of every poll to a client
+// NOTE: Caller must hold at least RLock
+func (h *Hub) sendPollStateToClient(c *Client) {
+	for _, pollID := range h.pollOrder {
+		poll, ok := h.polls[pollID]
+		if !ok {
+			continue
+		}
+		h.sendFrame(c, h.buildPollUpdate(poll))
+	}
+}
@@ -0,0 +1,264 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+// === POLL FEATURE TESTS ===
+
+func createTestPoll(t *testing.T, hub *Hub, creator *Client, req domain.PollPayload) string {
+	t.Helper()
+	payload, _ := json.Marshal(req)
+	hub.HandlePoll(creator, domain.Message{ID: "poll1", Type: domain.MessageTypePoll, Payload: payload})
+
+	msg, ok := drainForType(creator, domain.MessageTypePoll)
+	if !ok {
+		t.Fatal("Expected poll to be broadcast")
+	}
+	var created domain.PollPayload
+	json.Unmarshal(msg.Payload, &created)
+	return created.PollID
+}
+
+func sendVote(hub *Hub, c *Client, pollID string, idx int) {
+	payload, _ := json.Marshal(domain.VotePayload{PollID: pollID, OptionIndex: idx})
+	hub.HandleVote(c, domain.Message{ID: "vote", Type: domain.MessageTypeVote, Payload: payload})
+}
+
+func TestHub_HandlePoll_CreatesWithZeroTally(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	creator := newMockClient(hub, "Creator")
+	hub.Register(creator)
+	time.Sleep(30 * time.Millisecond)
+
+	pollID := createTestPoll(t, hub, creator, domain.PollPayload{
+		PollID:   "client-id",
+		Question: "Lunch?",
+		Options:  []string{"Bakso", "Soto"},
+	})
+
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+
+	poll, ok := hub.polls[pollID]
+	if !ok {
+		t.Fatal("Expected poll to be stored in hub")
+	}
+	snapshot := hub.pollSnapshot(poll)
+	if snapshot.Votes["Bakso"] != 0 || snapshot.Votes["Soto"] != 0 {
+		t.Errorf("Expected zero tally, got %v", snapshot.Votes)
+	}
+}
+
+func TestHub_HandlePoll_RejectsTooFewOptions(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	creator := withAllFeatures(newMockClient(hub, "Creator"))
+	hub.Register(creator)
+	time.Sleep(30 * time.Millisecond)
+
+	payload, _ := json.Marshal(domain.PollPayload{Question: "Q?", Options: []string{"Only", "Only"}})
+	hub.HandlePoll(creator, domain.Message{ID: "p", Type: domain.MessageTypePoll, Payload: payload})
+
+	msg, ok := drainForType(creator, domain.MessageTypeError)
+	if !ok {
+		t.Fatal("Expected error for poll with fewer than 2 distinct options")
+	}
+	var errPayload domain.ErrorPayload
+	json.Unmarshal(msg.Payload, &errPayload)
+	if want := fmt.Sprintf("%d-%d opsi", domain.PollMinOptions, domain.PollMaxOptions); !strings.Contains(errPayload.Message, want) {
+		t.Errorf("Expected the error to state the option limits (%s), got %q", want, errPayload.Message)
+	}
+}
+
+func TestHub_HandleVote_SingleVotePerPersona(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	creator := withAllFeatures(newMockClient(hub, "Creator"))
+	voter := withAllFeatures(newMockClient(hub, "Voter"))
+	hub.Register(creator)
+	hub.Register(voter)
+	time.Sleep(50 * time.Millisecond)
+
+	pollID := createTestPoll(t, hub, creator, domain.PollPayload{
+		Question: "Lunch?",
+		Options:  []string{"Bakso", "Soto"},
+	})
+
+	sendVote(hub, voter, pollID, 0)
+	sendVote(hub, voter, pollID, 0)
+	sendVote(hub, voter, pollID, 1)
+
+	if _, ok := drainForType(voter, domain.MessageTypeError); !ok {
+		t.Error("Expected error frame on repeated vote")
+	}
+
+	hub.mu.RLock()
+	snapshot := hub.pollSnapshot(hub.polls[pollID])
+	hub.mu.RUnlock()
+
+	if snapshot.Votes["Bakso"] != 1 || snapshot.Votes["Soto"] != 0 {
+		t.Errorf("Expected only first vote to count, got %v", snapshot.Votes)
+	}
+	if len(snapshot.Voters) != 1 {
+		t.Errorf("Expected 1 voter, got %d", len(snapshot.Voters))
+	}
+}
+
+func TestHub_HandleVote_AllowChange(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	creator := newMockClient(hub, "Creator")
+	hub.Register(creator)
+	time.Sleep(30 * time.Millisecond)
+
+	pollID := createTestPoll(t, hub, creator, domain.PollPayload{
+		Question:    "Lunch?",
+		Options:     []string{"Bakso", "Soto"},
+		AllowChange: true,
+	})
+
+	sendVote(hub, creator, pollID, 0)
+	sendVote(hub, creator, pollID, 1)
+
+	hub.mu.RLock()
+	snapshot := hub.pollSnapshot(hub.polls[pollID])
+	hub.mu.RUnlock()
+
+	if snapshot.Votes["Bakso"] != 0 || snapshot.Votes["Soto"] != 1 {
+		t.Errorf("Expected vote to move to Soto, got %v", snapshot.Votes)
+	}
+}
+
+func TestHub_HandlePollClose_OnlyHostOrCreator(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	host := withAllFeatures(newMockClient(hub, "Host"))
+	creator := withAllFeatures(newMockClient(hub, "Creator"))
+	other := withAllFeatures(newMockClient(hub, "Other"))
+	hub.Register(host)
+	time.Sleep(30 * time.Millisecond)
+	hub.Register(creator)
+	hub.Register(other)
+	time.Sleep(50 * time.Millisecond)
+
+	pollID := createTestPoll(t, hub, creator, domain.PollPayload{
+		Question: "Lunch?",
+		Options:  []string{"Bakso", "Soto"},
+	})
+
+	closePayload, _ := json.Marshal(domain.PollClosePayload{PollID: pollID})
+	hub.HandlePollClose(other, domain.Message{Type: domain.MessageTypePollClose, Payload: closePayload})
+
+	hub.mu.RLock()
+	closed := hub.polls[pollID].payload.Closed
+	hub.mu.RUnlock()
+	if closed {
+		t.Fatal("Non-host, non-creator should not close poll")
+	}
+
+	hub.HandlePollClose(creator, domain.Message{Type: domain.MessageTypePollClose, Payload: closePayload})
+
+	hub.mu.RLock()
+	closed = hub.polls[pollID].payload.Closed
+	hub.mu.RUnlock()
+	if !closed {
+		t.Fatal("Creator should be able to close poll")
+	}
+
+	sendVote(hub, other, pollID, 0)
+	if _, ok := drainForType(other, domain.MessageTypeError); !ok {
+		t.Error("Expected error when voting on a closed poll")
+	}
+}
+
+func TestHub_HandlePoll_AutoClose(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	creator := newMockClient(hub, "Creator")
+	hub.Register(creator)
+	time.Sleep(30 * time.Millisecond)
+
+	pollID := createTestPoll(t, hub, creator, domain.PollPayload{
+		Question:    "Quick?",
+		Options:     []string{"Yes", "No"},
+		DurationSec: 1,
+	})
+
+	time.Sleep(1100 * time.Millisecond)
+
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	if !hub.polls[pollID].payload.Closed {
+		t.Error("Expected poll to auto-close after its duration")
+	}
+}
+
+func TestHub_PollStateReplayedToLateJoiner(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	creator := withAllFeatures(newMockClient(hub, "Creator"))
+	hub.Register(creator)
+	time.Sleep(30 * time.Millisecond)
+
+	pollID := createTestPoll(t, hub, creator, domain.PollPayload{
+		Question: "Lunch?",
+		Options:  []string{"Bakso", "Soto"},
+	})
+	sendVote(hub, creator, pollID, 1)
+
+	lateJoiner := withAllFeatures(newMockClient(hub, "LateJoiner"))
+	hub.Register(lateJoiner)
+
+	msg, ok := drainForType(lateJoiner, domain.MessageTypePollUpdate)
+	if !ok {
+		t.Fatal("Late joiner should receive poll state")
+	}
+	var state domain.PollPayload
+	json.Unmarshal(msg.Payload, &state)
+	if state.PollID != pollID || state.Votes["Soto"] != 1 {
+		t.Errorf("Expected current tally for %s, got %+v", pollID, state)
+	}
+}
+
+func TestHub_HandleVote_LegacyClientsGetTheVote(t *testing.T) {
+	hub := NewHub()
+	modern := withAllFeatures(newMockClient(hub, "Modern"))
+	legacy := newLegacyClient(hub, "Legacy")
+	setupRoom(t, hub, modern, legacy)
+
+	pollID := createTestPoll(t, hub, modern, domain.PollPayload{
+		Question: "Lunch?",
+		Options:  []string{"Bakso", "Soto"},
+	})
+	drainAll(legacy)
+	sendVote(hub, modern, pollID, 1)
+
+	if _, ok := drainForType(modern, domain.MessageTypePollUpdate); !ok {
+		t.Error("Expected poll_update for a client with polls")
+	}
+
+	msg, ok := drainForType(legacy, domain.MessageTypeVote)
+	if !ok {
+		t.Fatal("Expected the vote itself for a v1 client")
+	}
+	var vote domain.VotePayload
+	json.Unmarshal(msg.Payload, &vote)
+	if vote.PollID != pollID || vote.OptionIndex != 1 {
+		t.Errorf("Unexpected vote: %+v", vote)
+	}
+}
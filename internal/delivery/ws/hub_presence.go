@@ -0,0 +1,104 @@
+package ws
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+// Presence keeps clients' online lists current without resending the whole list.
+// A client gets one snapshot when it joins (or asks for one), then user_delta
+// frames numbered by h.presenceVersion. Deltas are sent directly under h.mu.Lock
+// rather than through the broadcast channel, so every client sees them in version
+// order. A client that sees a gap sends presence_resync and starts over.
+
+// onlineUser returns a client's entry in the online list
+// NOTE: Caller must hold at least RLock when calling this
+func (h *Hub) onlineUser(c *Client) domain.OnlineUser {
+	return domain.OnlineUser{
+		ID:      c.User.ID.String(),
+		Persona: c.User.PersonaName,
+		Color:   c.User.PersonaColor,
+		Battery: c.User.BatteryLevel,
+		Role:    h.roleOf(c),
+	}
+}
+
+// sendPresenceSnapshot sends the full online list at the current version
+// NOTE: Caller must hold at least RLock when calling this
+func (h *Hub) sendPresenceSnapshot(c *Client) {
+	users := make([]domain.OnlineUser, 0, len(h.clients))
+	for _, other := range h.clients {
+		users = append(users, h.onlineUser(other))
+	}
+
+	payload, _ := json.Marshal(domain.PresenceSnapshotPayload{
+		Version:   h.presenceVersion,
+		Users:     users,
+		UserCount: len(h.clients),
+		HostID:    h.hostID,
+	})
+
+	snapshotMsg := domain.Message{
+		ID:        uuid.New().String(),
+		Type:      domain.MessageTypePresenceSnapshot,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}
+
+	h.sendFrame(c, NewFrame(snapshotMsg))
+}
+
+// publishPresence bumps the presence version and sends the delta to presence clients
+// Clients without the feature get legacy instead, when given; except is skipped entirely
+// NOTE: Caller must hold h.mu.Lock
+func (h *Hub) publishPresence(delta domain.UserDeltaPayload, except *Client, legacy *Frame) {
+	h.presenceVersion++
+	delta.Version = h.presenceVersion
+	delta.UserCount = len(h.clients)
+	delta.HostID = h.hostID
+	payload, _ := json.Marshal(delta)
+
+	deltaMsg := domain.Message{
+		ID:        uuid.New().String(),
+		Type:      domain.MessageTypeUserDelta,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}
+	frame := NewFrame(deltaMsg)
+
+	for c := range h.allConns() {
+		if c == except {
+			continue
+		}
+		if c.HasFeature(domain.FeaturePresence) {
+			c.SendFrame(frame)
+		} else if legacy != nil {
+			c.SendFrame(legacy)
+		}
+	}
+}
+
+// broadcastUserUpdate tells everyone that some users' entries changed (role, host)
+// Presence clients get just the changed entries, legacy clients a full user_sync
+// NOTE: Caller must hold h.mu.Lock
+func (h *Hub) broadcastUserUpdate(subject *Client, updated ...*Client) {
+	delta := domain.UserDeltaPayload{}
+	for _, c := range updated {
+		delta.Updated = append(delta.Updated, h.onlineUser(c))
+	}
+	h.publishPresence(delta, nil, h.buildUserEventMessage(subject, domain.MessageTypeUserSync, len(h.clients)))
+}
+
+// HandlePresenceResync answers a client that missed a delta with a fresh snapshot
+func (h *Hub) HandlePresenceResync(c *Client) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if !h.hasConn(c) {
+		return
+	}
+	h.sendPresenceSnapshot(c)
+}
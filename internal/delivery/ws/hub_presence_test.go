@@ -0,0 +1,315 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+func userEventPayload(t *testing.T, msg domain.Message) map[string]json.RawMessage {
+	t.Helper()
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		t.Fatalf("Bad %s payload: %v", msg.Type, err)
+	}
+	return payload
+}
+
+func userDeltaPayload(t *testing.T, c *Client) domain.UserDeltaPayload {
+	t.Helper()
+	msg, ok := drainForType(c, domain.MessageTypeUserDelta)
+	if !ok {
+		t.Fatalf("%s expected user_delta", c.User.PersonaName)
+	}
+	var delta domain.UserDeltaPayload
+	json.Unmarshal(msg.Payload, &delta)
+	return delta
+}
+
+// waitForDelta reads user_delta frames until one matches, failing after a second
+func waitForDelta(t *testing.T, c *Client, match func(domain.UserDeltaPayload) bool) domain.UserDeltaPayload {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		msg, ok := drainForType(c, domain.MessageTypeUserDelta)
+		if !ok {
+			continue
+		}
+		var delta domain.UserDeltaPayload
+		json.Unmarshal(msg.Payload, &delta)
+		if match(delta) {
+			return delta
+		}
+	}
+	t.Fatalf("%s expected a matching user_delta", c.User.PersonaName)
+	return domain.UserDeltaPayload{}
+}
+
+func presenceSnapshot(t *testing.T, c *Client) domain.PresenceSnapshotPayload {
+	t.Helper()
+	msg, ok := drainForType(c, domain.MessageTypePresenceSnapshot)
+	if !ok {
+		t.Fatalf("%s expected presence_snapshot", c.User.PersonaName)
+	}
+	var snapshot domain.PresenceSnapshotPayload
+	json.Unmarshal(msg.Payload, &snapshot)
+	return snapshot
+}
+
+func TestHub_Presence_SnapshotOnJoin(t *testing.T) {
+	hub := NewHub()
+	hub.leaveDelay = 10 * time.Millisecond
+	setupRoom(t, hub, withAllFeatures(newMockClient(hub, "Modern")), newLegacyClient(hub, "Legacy"))
+
+	newcomer := withAllFeatures(newMockClient(hub, "Newcomer"))
+	hub.Register(newcomer)
+	if !waitForClients(hub, 3) {
+		t.Fatal("Newcomer did not register")
+	}
+
+	identity, ok := drainForType(newcomer, domain.MessageTypeIdentity)
+	if !ok {
+		t.Fatal("Expected identity")
+	}
+	if _, hasList := userEventPayload(t, identity)["online_users"]; hasList {
+		t.Error("Presence client's identity should not carry the list")
+	}
+
+	snapshot := presenceSnapshot(t, newcomer)
+	if len(snapshot.Users) != 3 || snapshot.UserCount != 3 {
+		t.Errorf("Expected 3 users in snapshot, got %+v", snapshot)
+	}
+	hub.mu.RLock()
+	version := hub.presenceVersion
+	hub.mu.RUnlock()
+	if snapshot.Version != version {
+		t.Errorf("Expected snapshot at version %d, got %d", version, snapshot.Version)
+	}
+
+	selfJoin, ok := drainForType(newcomer, domain.MessageTypeUserJoin)
+	if !ok {
+		t.Fatal("Expected own user_join")
+	}
+	if _, hasList := userEventPayload(t, selfJoin)["online_users"]; hasList {
+		t.Error("Own user_join should be slim")
+	}
+}
+
+func TestHub_Presence_JoinDeltaForModernFullListForLegacy(t *testing.T) {
+	hub := NewHub()
+	hub.leaveDelay = 10 * time.Millisecond
+	modern := withAllFeatures(newMockClient(hub, "Modern"))
+	legacy := newLegacyClient(hub, "Legacy")
+	setupRoom(t, hub, modern, legacy)
+	time.Sleep(600 * time.Millisecond) // Let the delayed user_sync land
+	drainAll(modern, legacy)
+	hub.mu.RLock()
+	before := hub.presenceVersion
+	hub.mu.RUnlock()
+
+	newcomer := withAllFeatures(newMockClient(hub, "Newcomer"))
+	hub.Register(newcomer)
+	if !waitForClients(hub, 3) {
+		t.Fatal("Newcomer did not register")
+	}
+
+	join, ok := drainForType(modern, domain.MessageTypeUserJoin)
+	if !ok {
+		t.Fatal("Modern client expected user_join")
+	}
+	if _, hasList := userEventPayload(t, join)["online_users"]; hasList {
+		t.Error("Presence client should get a slim user_join")
+	}
+	delta := userDeltaPayload(t, modern)
+	if delta.Version != before+1 {
+		t.Errorf("Expected version %d, got %d", before+1, delta.Version)
+	}
+	if len(delta.Added) != 1 || delta.Added[0].ID != newcomer.ID || delta.UserCount != 3 {
+		t.Errorf("Unexpected delta: %+v", delta)
+	}
+
+	legacyJoin, ok := drainForType(legacy, domain.MessageTypeUserJoin)
+	if !ok {
+		t.Fatal("Legacy client expected user_join")
+	}
+	var users []domain.OnlineUser
+	json.Unmarshal(userEventPayload(t, legacyJoin)["online_users"], &users)
+	if len(users) != 3 {
+		t.Errorf("Legacy client should get the full list, got %d users", len(users))
+	}
+}
+
+func TestHub_Presence_LeaveDeltaForModernFullListForLegacy(t *testing.T) {
+	hub := NewHub()
+	hub.leaveDelay = 10 * time.Millisecond
+	modern := withAllFeatures(newMockClient(hub, "Modern"))
+	legacy := newLegacyClient(hub, "Legacy")
+	setupRoom(t, hub, modern, legacy)
+
+	// Wait for the leaver's join delta so it cannot be mistaken for the leave
+	leaver := withAllFeatures(newMockClient(hub, "Leaver"))
+	hub.Register(leaver)
+	waitForDelta(t, modern, func(d domain.UserDeltaPayload) bool {
+		return len(d.Added) == 1 && d.Added[0].ID == leaver.ID
+	})
+
+	hub.Unregister(leaver)
+
+	delta := waitForDelta(t, modern, func(d domain.UserDeltaPayload) bool { return len(d.Removed) > 0 })
+	if len(delta.Removed) != 1 || delta.Removed[0] != leaver.ID || delta.UserCount != 2 {
+		t.Errorf("Unexpected delta: %+v", delta)
+	}
+	leave, ok := drainForType(modern, domain.MessageTypeUserLeave)
+	if !ok {
+		t.Fatal("Modern client expected user_leave")
+	}
+	if _, hasList := userEventPayload(t, leave)["online_users"]; hasList {
+		t.Error("Presence client should get a slim user_leave")
+	}
+
+	legacyLeave, ok := drainForType(legacy, domain.MessageTypeUserLeave)
+	if !ok {
+		t.Fatal("Legacy client expected user_leave")
+	}
+	if _, hasList := userEventPayload(t, legacyLeave)["online_users"]; !hasList {
+		t.Error("Legacy client should get the full list")
+	}
+
+	// History keeps the slim event
+	history := hub.messageHistory.GetAll()
+	var stored domain.Message
+	json.Unmarshal(history[len(history)-1], &stored)
+	if stored.Type != domain.MessageTypeUserLeave {
+		t.Fatalf("Expected user_leave in history, got %s", stored.Type)
+	}
+	if _, hasList := userEventPayload(t, stored)["online_users"]; hasList {
+		t.Error("History should not store the online list")
+	}
+}
+
+func TestHub_Presence_RoleChangeSendsUpdatedEntryOnly(t *testing.T) {
+	hub := NewHub()
+	hub.leaveDelay = 10 * time.Millisecond
+	modern := withAllFeatures(newMockClient(hub, "Modern"))
+	legacy := newLegacyClient(hub, "Legacy")
+	setupRoom(t, hub, modern, legacy)
+	time.Sleep(600 * time.Millisecond) // Let the delayed user_sync land
+	drainAll(modern, legacy)
+	hub.mu.Lock()
+	hub.hostID = modern.ID
+	hub.mu.Unlock()
+
+	payload, _ := json.Marshal(domain.RolePayload{TargetID: legacy.ID})
+	hub.HandleRoleChange(modern, domain.Message{Type: domain.MessageTypeGrantModerator, Payload: payload})
+
+	delta := userDeltaPayload(t, modern)
+	if len(delta.Updated) != 1 || delta.Updated[0].ID != legacy.ID || delta.Updated[0].Role != domain.RoleModerator {
+		t.Errorf("Unexpected delta: %+v", delta)
+	}
+	if delta.HostID != modern.ID {
+		t.Errorf("Expected host %s in delta, got %s", modern.ID, delta.HostID)
+	}
+	if _, ok := drainForType(modern, domain.MessageTypeUserSync); ok {
+		t.Error("Presence client should not get a full user_sync")
+	}
+
+	if _, ok := drainForType(legacy, domain.MessageTypeUserSync); !ok {
+		t.Error("Legacy client expected a full user_sync")
+	}
+	if _, ok := drainForType(legacy, domain.MessageTypeUserDelta); ok {
+		t.Error("Legacy client should not receive user_delta")
+	}
+}
+
+func TestHub_Presence_VersionsAreConsecutive(t *testing.T) {
+	hub := NewHub()
+	hub.leaveDelay = 10 * time.Millisecond
+	modern := withAllFeatures(newMockClient(hub, "Modern"))
+	setupRoom(t, hub, modern, newLegacyClient(hub, "Legacy"))
+	time.Sleep(600 * time.Millisecond) // Let the delayed user_sync land
+	drainAll(modern)
+
+	for _, name := range []string{"A", "B", "C"} {
+		hub.Register(withAllFeatures(newMockClient(hub, name)))
+	}
+	if !waitForClients(hub, 5) {
+		t.Fatal("Clients did not register")
+	}
+
+	var last uint64
+	for i := 0; i < 3; i++ {
+		delta := userDeltaPayload(t, modern)
+		if last != 0 && delta.Version != last+1 {
+			t.Errorf("Expected version %d, got %d", last+1, delta.Version)
+		}
+		last = delta.Version
+	}
+}
+
+func TestHub_Presence_ResyncSendsSnapshot(t *testing.T) {
+	hub := NewHub()
+	hub.leaveDelay = 10 * time.Millisecond
+	modern := withAllFeatures(newMockClient(hub, "Modern"))
+	setupRoom(t, hub, modern, newLegacyClient(hub, "Legacy"))
+	time.Sleep(600 * time.Millisecond) // Let the delayed user_sync land
+	drainAll(modern)
+
+	dispatchInbound(modern, domain.MessageTypePresenceResync, `{}`, "")
+
+	snapshot := presenceSnapshot(t, modern)
+	if len(snapshot.Users) != 2 || snapshot.HostID == "" {
+		t.Errorf("Unexpected snapshot: %+v", snapshot)
+	}
+	hub.mu.RLock()
+	version := hub.presenceVersion
+	hub.mu.RUnlock()
+	if snapshot.Version != version {
+		t.Errorf("Expected snapshot at version %d, got %d", version, snapshot.Version)
+	}
+}
+
+func TestHub_Presence_HelloSendsSnapshot(t *testing.T) {
+	hub := NewHub()
+	hub.leaveDelay = 10 * time.Millisecond
+	legacy := newLegacyClient(hub, "Legacy")
+	setupRoom(t, hub, withAllFeatures(newMockClient(hub, "Modern")), legacy)
+	time.Sleep(600 * time.Millisecond) // Let the delayed user_sync land
+	drainAll(legacy)
+
+	hub.HandleHello(legacy, helloMessage(domain.ProtocolVersion, domain.FeaturePresence))
+
+	snapshot := presenceSnapshot(t, legacy)
+	if len(snapshot.Users) != 2 {
+		t.Errorf("Expected 2 users in snapshot, got %d", len(snapshot.Users))
+	}
+}
+
+func TestHub_Presence_ReplayedLeaveFullListForLegacy(t *testing.T) {
+	hub := NewHub()
+	hub.leaveDelay = 10 * time.Millisecond
+	host := withAllFeatures(newMockClient(hub, "Host"))
+	leaver := withAllFeatures(newMockClient(hub, "Leaver"))
+	setupRoom(t, hub, host, leaver)
+
+	hub.Unregister(leaver)
+	time.Sleep(50 * time.Millisecond)
+
+	replayedLeave := func(c *Client) map[string]json.RawMessage {
+		t.Helper()
+		hub.Register(c)
+		leave, ok := drainForType(c, domain.MessageTypeUserLeave)
+		if !ok {
+			t.Fatalf("%s expected the user_leave from history", c.User.PersonaName)
+		}
+		return userEventPayload(t, leave)
+	}
+
+	if _, hasList := replayedLeave(newLegacyClient(hub, "Legacy"))["online_users"]; !hasList {
+		t.Error("Legacy client should get the full list when history is replayed")
+	}
+	if _, hasList := replayedLeave(withAllFeatures(newMockClient(hub, "Modern")))["online_users"]; hasList {
+		t.Error("Presence client should get the slim user_leave from history")
+	}
+}
@@ -0,0 +1,130 @@
+package ws
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+// clientProtocol is what a client negotiated in its hello handshake
+type clientProtocol struct {
+	version  int
+	features map[domain.Feature]bool
+}
+
+// Supports reports whether the client understands a frame type
+// Clients that never said hello only get v1 frames
+func (c *Client) Supports(msgType domain.MessageType) bool {
+	feature, gated := domain.FrameFeature(msgType)
+	if !gated {
+		return true
+	}
+	return c.HasFeature(feature)
+}
+
+// HasFeature reports whether the client negotiated a feature
+func (c *Client) HasFeature(feature domain.Feature) bool {
+	proto := c.protocol.Load()
+	return proto != nil && proto.features[feature]
+}
+
+// ProtocolVersion returns the negotiated protocol version (1 until hello)
+func (c *Client) ProtocolVersion() int {
+	if proto := c.protocol.Load(); proto != nil {
+		return proto.version
+	}
+	return 1
+}
+
+// sendFrame queues a frame (or its fallback) for one client, skipping types it does not understand
+func (h *Hub) sendFrame(c *Client, frame *Frame) {
+	out := frame.For(c)
+	if !c.Supports(out.Type) {
+		return
+	}
+	c.SendFrame(out)
+}
+
+// HandleHello negotiates protocol version and features, then replies with the server's side
+// Only the first valid hello on a connection counts; repeats are ignored rather than replaying state
+func (h *Hub) HandleHello(c *Client, msg domain.Message) {
+	var hello domain.HelloPayload
+	if err := json.Unmarshal(msg.Payload, &hello); err != nil || hello.Version < 1 {
+		return // Not negotiated yet, so the client may not understand an error frame
+	}
+	if !c.saidHello.CompareAndSwap(false, true) {
+		return
+	}
+
+	version := hello.Version
+	if version > domain.ProtocolVersion {
+		version = domain.ProtocolVersion
+	}
+
+	// Only features both sides know about are enabled
+	enabled := make(map[domain.Feature]bool)
+	for _, f := range domain.ServerFeatures() {
+		enabled[f] = true
+	}
+	features := make(map[domain.Feature]bool, len(hello.Features))
+	for _, f := range hello.Features {
+		if enabled[f] {
+			features[f] = true
+		}
+	}
+	c.protocol.Store(&clientProtocol{version: version, features: features})
+
+	h.sendHelloReply(c)
+
+	// Frames sent before the handshake were filtered; replay the stateful ones
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	registered := h.hasConn(c)
+	if hello.LastSeq > 0 && !registered {
+		c.SetResumeSeq(hello.LastSeq) // History is sent when the client is admitted
+	}
+
+	if c.waiting.Load() {
+		for i, entry := range h.lobby {
+			if entry.client == c {
+				h.sendKnockStatus(c, "waiting", i+1, "")
+			}
+		}
+		return
+	}
+
+	if registered {
+		h.sendPresenceSnapshot(c)
+	}
+	if len(h.scoreboard) > 0 {
+		h.sendScoreboardToClient(c)
+	}
+	h.sendPollStateToClient(c)
+	if h.can(c, domain.PermApprovals) && len(h.lobby) > 0 {
+		h.sendLobbySync(c)
+	}
+}
+
+// sendHelloReply tells a client the server's version, limits and enabled features
+func (h *Hub) sendHelloReply(c *Client) {
+	payload, _ := json.Marshal(domain.HelloPayload{
+		Version:  domain.ProtocolVersion,
+		Features: domain.ServerFeatures(),
+		Limits: &domain.ProtocolLimits{
+			MaxMessageSize: int(h.maxMessageSize),
+			MaxHistorySize: h.maxHistorySize,
+		},
+	})
+
+	helloMsg := domain.Message{
+		ID:        uuid.New().String(),
+		Type:      domain.MessageTypeHello,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}
+
+	c.SendMessage(helloMsg)
+}
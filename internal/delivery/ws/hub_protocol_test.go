@@ -0,0 +1,157 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+// newLegacyClient is a client that never said hello
+func newLegacyClient(hub *Hub, name string) *Client {
+	return newMockClient(hub, name)
+}
+
+func helloMessage(version int, features ...domain.Feature) domain.Message {
+	payload, _ := json.Marshal(domain.HelloPayload{Version: version, Features: features})
+	return domain.Message{Type: domain.MessageTypeHello, Payload: payload}
+}
+
+func TestHub_Hello_ReplyCarriesVersionLimitsFeatures(t *testing.T) {
+	hub := NewHub()
+	c := newLegacyClient(hub, "Web")
+
+	hub.HandleHello(c, helloMessage(domain.ProtocolVersion+5, domain.FeatureErrors, "hologram"))
+
+	reply, ok := drainForType(c, domain.MessageTypeHello)
+	if !ok {
+		t.Fatal("Expected hello reply")
+	}
+	var payload domain.HelloPayload
+	json.Unmarshal(reply.Payload, &payload)
+	if payload.Version != domain.ProtocolVersion {
+		t.Errorf("Expected server version %d, got %d", domain.ProtocolVersion, payload.Version)
+	}
+	if payload.Limits == nil || payload.Limits.MaxMessageSize != domain.MaxMessageSize || payload.Limits.MaxHistorySize != domain.MaxHistorySize {
+		t.Errorf("Unexpected limits: %+v", payload.Limits)
+	}
+	if len(payload.Features) != len(domain.ServerFeatures()) {
+		t.Errorf("Expected all server features, got %v", payload.Features)
+	}
+
+	if c.ProtocolVersion() != domain.ProtocolVersion {
+		t.Errorf("Expected negotiated version capped at %d, got %d", domain.ProtocolVersion, c.ProtocolVersion())
+	}
+	if !c.Supports(domain.MessageTypeError) {
+		t.Error("Client declared errors, should receive error frames")
+	}
+	if c.Supports(domain.MessageTypePollUpdate) {
+		t.Error("Client did not declare polls, should not receive poll_update")
+	}
+}
+
+func TestHub_Hello_OnlyFirstCounts(t *testing.T) {
+	hub := NewHub()
+	c := newLegacyClient(hub, "Web")
+
+	c.handshake(helloMessage(domain.ProtocolVersion, domain.FeatureErrors))
+	c.handshake(helloMessage(domain.ProtocolVersion, domain.FeatureErrors, domain.FeaturePolls))
+
+	replies := 0
+	for {
+		if _, ok := drainForType(c, domain.MessageTypeHello); !ok {
+			break
+		}
+		replies++
+	}
+	if replies != 1 {
+		t.Errorf("Expected one hello reply, got %d", replies)
+	}
+	if c.Supports(domain.MessageTypePollUpdate) {
+		t.Error("A repeated hello must not renegotiate features")
+	}
+}
+
+func TestHub_Hello_ChargedToFloodBucket(t *testing.T) {
+	hub := NewHub()
+	c := newLegacyClient(hub, "Web")
+
+	c.handshake(helloMessage(domain.ProtocolVersion))
+
+	state := hub.floodStates[moderationKey(c)]
+	if state == nil || state.shared == nil || state.shared.Tokens() >= float64(hub.flood.Burst) {
+		t.Error("Expected hello to draw from the client's flood bucket")
+	}
+}
+
+func TestHub_LegacyClient_OnlyGetsV1Frames(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	modern := withAllFeatures(newMockClient(hub, "Modern"))
+	legacy := newLegacyClient(hub, "Legacy")
+	hub.Register(modern)
+	hub.Register(legacy)
+	if !waitForClients(hub, 2) {
+		t.Fatal("Clients did not register")
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	if legacy.ProtocolVersion() != 1 {
+		t.Errorf("Expected v1 before hello, got %d", legacy.ProtocolVersion())
+	}
+
+	hub.sendError(legacy, domain.ErrCodeForbidden, "nope")
+	if _, ok := drainForType(legacy, domain.MessageTypeError); ok {
+		t.Error("Legacy client should not receive error frames")
+	}
+
+	// Gated frames are skipped in room-wide broadcasts too
+	hub.broadcast <- hub.buildScoreboardMessage()
+	if _, ok := drainForType(legacy, domain.MessageTypeScoreboard); ok {
+		t.Error("Legacy client should not receive scoreboard frames")
+	}
+	if _, ok := drainForType(modern, domain.MessageTypeScoreboard); !ok {
+		t.Error("Modern client should receive scoreboard frames")
+	}
+
+	chat, _ := json.Marshal(domain.Message{ID: "m1", Type: domain.MessageTypeChat, Payload: json.RawMessage(`{"text":"hi"}`)})
+	hub.Broadcast(chat)
+	if _, ok := drainForType(legacy, domain.MessageTypeChat); !ok {
+		t.Error("Legacy client should still receive chat")
+	}
+}
+
+func TestHub_Hello_ReplaysKnockStatusWhileWaiting(t *testing.T) {
+	hub := NewHub()
+	hub.knockToEnter = true
+	go hub.Run()
+
+	host := newMockClient(hub, "Host")
+	hub.Register(host)
+	if !waitForClients(hub, 1) {
+		t.Fatal("Expected host to enter")
+	}
+
+	guest := newLegacyClient(hub, "Guest")
+	guest.waiting.Store(true)
+	hub.Register(guest)
+	time.Sleep(50 * time.Millisecond)
+
+	if _, ok := drainForType(guest, domain.MessageTypeKnock); ok {
+		t.Fatal("Knock status should wait for the handshake")
+	}
+
+	hub.HandleHello(guest, helloMessage(domain.ProtocolVersion, domain.FeatureLobby))
+
+	knock, ok := drainForType(guest, domain.MessageTypeKnock)
+	if !ok {
+		t.Fatal("Expected knock status after hello")
+	}
+	var status domain.KnockPayload
+	json.Unmarshal(knock.Payload, &status)
+	if status.Status != "waiting" || status.Position != 1 {
+		t.Errorf("Expected waiting at position 1, got %+v", status)
+	}
+}
@@ -0,0 +1,100 @@
+package ws
+
+import (
+	"encoding/json"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+// newRolePermissions builds the per-role permission sets from the defaults
+func newRolePermissions() map[domain.Role]map[domain.Permission]bool {
+	perms := make(map[domain.Role]map[domain.Permission]bool, len(domain.DefaultRolePermissions))
+	for role, list := range domain.DefaultRolePermissions {
+		perms[role] = permissionSet(list)
+	}
+	return perms
+}
+
+// permissionSet turns a permission list into a lookup set
+func permissionSet(list []domain.Permission) map[domain.Permission]bool {
+	set := make(map[domain.Permission]bool, len(list))
+	for _, p := range list {
+		set[p] = true
+	}
+	return set
+}
+
+// roleOf returns a client's role in the room
+// NOTE: Caller must hold at least RLock when calling this
+func (h *Hub) roleOf(c *Client) domain.Role {
+	if c.ID == h.hostID {
+		return domain.RoleOwner
+	}
+	if h.moderators[c.ID] {
+		return domain.RoleModerator
+	}
+	return domain.RoleMember
+}
+
+// can reports whether the client's role grants a permission
+// NOTE: Caller must hold at least RLock when calling this
+func (h *Hub) can(c *Client, perm domain.Permission) bool {
+	return h.rolePermissions[h.roleOf(c)][perm]
+}
+
+// canActOn reports whether actor may use a permission against target (actor must outrank target)
+// NOTE: Caller must hold at least RLock when calling this
+func (h *Hub) canActOn(actor, target *Client, perm domain.Permission) bool {
+	if actor.ID == target.ID || !h.can(actor, perm) {
+		return false
+	}
+	return h.roleOf(actor).Rank() > h.roleOf(target).Rank()
+}
+
+// HandleRoleChange - Owner grants or revokes the moderator role
+func (h *Hub) HandleRoleChange(c *Client, msg domain.Message) {
+	var payload domain.RolePayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil || payload.TargetID == "" {
+		h.sendError(c, domain.ErrCodeInvalidPayload, "Format role tidak valid")
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if c.ID != h.hostID {
+		h.sendError(c, domain.ErrCodeForbidden, "Hanya host yang bisa mengatur moderator")
+		return
+	}
+
+	target, exists := h.clients[payload.TargetID]
+	if !exists {
+		h.sendError(c, domain.ErrCodeUserOffline, "User tidak ditemukan")
+		return
+	}
+	if target.ID == h.hostID {
+		return
+	}
+
+	if msg.Type == domain.MessageTypeGrantModerator {
+		if h.moderators[target.ID] {
+			return
+		}
+		h.moderators[target.ID] = true
+		h.notifyUser(target, "🛡️ Kamu sekarang moderator")
+	} else {
+		if !h.moderators[target.ID] {
+			return
+		}
+		delete(h.moderators, target.ID)
+		h.notifyUser(target, "Kamu bukan moderator lagi")
+	}
+
+	// Everyone's user list carries roles
+	h.broadcastUserUpdate(target, target)
+
+	// Moderators see the waiting room if their role grants approvals
+	if len(h.lobby) > 0 {
+		h.sendLobbySyncToApprovers()
+	}
+}
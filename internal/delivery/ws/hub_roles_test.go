@@ -0,0 +1,197 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+func roleMessage(msgType domain.MessageType, targetID string) domain.Message {
+	payload, _ := json.Marshal(domain.RolePayload{TargetID: targetID})
+	return domain.Message{Type: msgType, Payload: payload}
+}
+
+func TestHub_GrantModerator(t *testing.T) {
+	hub := NewHub()
+	owner := newMockClient(hub, "Owner")
+	mod := newMockClient(hub, "Mod")
+	member := newMockClient(hub, "Member")
+	setupRoom(t, hub, owner, mod, member)
+	hub.HandleRoleChange(owner, roleMessage(domain.MessageTypeGrantModerator, mod.ID))
+
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+
+	if hub.roleOf(owner) != domain.RoleOwner {
+		t.Errorf("Expected owner role, got %s", hub.roleOf(owner))
+	}
+	if hub.roleOf(mod) != domain.RoleModerator {
+		t.Errorf("Expected moderator role, got %s", hub.roleOf(mod))
+	}
+	if hub.roleOf(member) != domain.RoleMember {
+		t.Errorf("Expected member role, got %s", hub.roleOf(member))
+	}
+
+	for _, perm := range []domain.Permission{domain.PermMusic, domain.PermNobar, domain.PermKick, domain.PermParty} {
+		if !hub.can(mod, perm) {
+			t.Errorf("Expected moderator to have %s", perm)
+		}
+		if hub.can(member, perm) {
+			t.Errorf("Expected member to lack %s", perm)
+		}
+	}
+	if hub.can(mod, domain.PermApprovals) || !hub.can(owner, domain.PermApprovals) {
+		t.Error("Expected approvals to be owner-only by default")
+	}
+}
+
+func TestHub_WithRolePermissions(t *testing.T) {
+	hub := NewHub(WithRolePermissions(map[domain.Role][]domain.Permission{
+		domain.RoleModerator: {domain.PermApprovals},
+	}))
+	owner := newMockClient(hub, "Owner")
+	mod := newMockClient(hub, "Mod")
+	setupRoom(t, hub, owner, mod)
+	hub.HandleRoleChange(owner, roleMessage(domain.MessageTypeGrantModerator, mod.ID))
+
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+
+	if !hub.can(mod, domain.PermApprovals) {
+		t.Error("Expected moderator to get approvals from the option")
+	}
+	if hub.can(mod, domain.PermKick) {
+		t.Error("Expected the option to replace the moderator's defaults")
+	}
+	if !hub.can(owner, domain.PermKick) {
+		t.Error("Expected roles left out of the option to keep their defaults")
+	}
+}
+
+func TestHub_OnlyOwnerGrantsModerator(t *testing.T) {
+	hub := NewHub()
+	owner := newMockClient(hub, "Owner")
+	mod := newMockClient(hub, "Mod")
+	member := newMockClient(hub, "Member")
+	setupRoom(t, hub, owner, mod, member)
+	hub.HandleRoleChange(owner, roleMessage(domain.MessageTypeGrantModerator, mod.ID))
+
+	hub.HandleRoleChange(mod, roleMessage(domain.MessageTypeGrantModerator, member.ID))
+
+	hub.mu.RLock()
+	role := hub.roleOf(member)
+	hub.mu.RUnlock()
+	if role != domain.RoleMember {
+		t.Errorf("Expected moderator grant by non-owner to be ignored, got %s", role)
+	}
+}
+
+func TestHub_RevokeModerator(t *testing.T) {
+	hub := NewHub()
+	owner := newMockClient(hub, "Owner")
+	mod := newMockClient(hub, "Mod")
+	setupRoom(t, hub, owner, mod, newMockClient(hub, "Member"))
+	hub.HandleRoleChange(owner, roleMessage(domain.MessageTypeGrantModerator, mod.ID))
+
+	hub.HandleRoleChange(owner, roleMessage(domain.MessageTypeRevokeModerator, mod.ID))
+
+	hub.mu.RLock()
+	role := hub.roleOf(mod)
+	hub.mu.RUnlock()
+	if role != domain.RoleMember {
+		t.Errorf("Expected revoked moderator to be member, got %s", role)
+	}
+}
+
+func TestHub_ModeratorKickRules(t *testing.T) {
+	hub := NewHub()
+	owner := newMockClient(hub, "Owner")
+	mod := newMockClient(hub, "Mod")
+	member := newMockClient(hub, "Member")
+	setupRoom(t, hub, owner, mod, member)
+	hub.HandleRoleChange(owner, roleMessage(domain.MessageTypeGrantModerator, mod.ID))
+
+	// Moderator cannot kick the owner
+	hub.KickUser(mod.ID, owner.ID)
+	// Member cannot kick anyone
+	hub.KickUser(member.ID, mod.ID)
+	time.Sleep(600 * time.Millisecond)
+	if hub.ClientCount() != 3 {
+		t.Fatalf("Expected unauthorized kicks to be ignored, got %d clients", hub.ClientCount())
+	}
+
+	// Moderator can kick a member
+	hub.KickUser(mod.ID, member.ID)
+	time.Sleep(600 * time.Millisecond)
+
+	hub.mu.RLock()
+	_, exists := hub.clients[member.ID]
+	hub.mu.RUnlock()
+	if exists {
+		t.Error("Expected moderator to kick member")
+	}
+}
+
+func TestHub_UserSyncIncludesRole(t *testing.T) {
+	hub := NewHub()
+	owner := newMockClient(hub, "Owner")
+	mod := newMockClient(hub, "Mod")
+	setupRoom(t, hub, owner, mod, newMockClient(hub, "Member"))
+	hub.HandleRoleChange(owner, roleMessage(domain.MessageTypeGrantModerator, mod.ID))
+
+	hub.mu.RLock()
+	data := hub.buildUserEventMessage(mod, domain.MessageTypeUserSync, len(hub.clients)).Bytes(CodecJSON)
+	hub.mu.RUnlock()
+
+	var msg domain.Message
+	json.Unmarshal(data, &msg)
+	var payload struct {
+		Role        string `json:"role"`
+		OnlineUsers []struct {
+			ID   string `json:"id"`
+			Role string `json:"role"`
+		} `json:"online_users"`
+	}
+	json.Unmarshal(msg.Payload, &payload)
+
+	if payload.Role != string(domain.RoleModerator) {
+		t.Errorf("Expected role moderator in payload, got %q", payload.Role)
+	}
+	roles := map[string]string{}
+	for _, u := range payload.OnlineUsers {
+		roles[u.ID] = u.Role
+	}
+	if roles[mod.ID] != string(domain.RoleModerator) {
+		t.Errorf("Expected moderator role in online users, got %q", roles[mod.ID])
+	}
+}
+
+func TestHub_ModeratorChangesPartyMode(t *testing.T) {
+	hub := NewHub()
+	owner := newMockClient(hub, "Owner")
+	mod := newMockClient(hub, "Mod")
+	member := newMockClient(hub, "Member")
+	setupRoom(t, hub, owner, mod, member)
+	hub.HandleRoleChange(owner, roleMessage(domain.MessageTypeGrantModerator, mod.ID))
+
+	payload, _ := json.Marshal(domain.PartyModePayload{Mode: "disco"})
+	hub.HandlePartyChange(member, domain.Message{Payload: payload})
+
+	hub.mu.RLock()
+	mode := hub.currentPartyMode
+	hub.mu.RUnlock()
+	if mode != "normal" {
+		t.Fatalf("Expected member party change to be ignored, got %s", mode)
+	}
+
+	hub.HandlePartyChange(mod, domain.Message{Payload: payload})
+
+	hub.mu.RLock()
+	mode = hub.currentPartyMode
+	hub.mu.RUnlock()
+	if mode != "disco" {
+		t.Errorf("Expected moderator to change party mode, got %s", mode)
+	}
+}
@@ -0,0 +1,144 @@
+package ws
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+// scoreEntry returns (creating if needed) the scoreboard entry for a persona
+// NOTE: Caller must hold h.mu Lock
+func (h *Hub) scoreEntry(personaName string) *domain.ScoreboardEntry {
+	entry, ok := h.scoreboard[personaName]
+	if !ok {
+		entry = &domain.ScoreboardEntry{PersonaName: personaName}
+		h.scoreboard[personaName] = entry
+	}
+	return entry
+}
+
+// recordMatchResult records a head-to-head result between two personas
+// NOTE: Caller must hold h.mu Lock
+func (h *Hub) recordMatchResult(winnerPersona, loserPersona string, draw bool) {
+	winner := h.scoreEntry(winnerPersona)
+	loser := h.scoreEntry(loserPersona)
+
+	if draw {
+		winner.Draws++
+		loser.Draws++
+		return
+	}
+
+	winner.Wins++
+	winner.CurrentStreak++
+	if winner.CurrentStreak > winner.BestStreak {
+		winner.BestStreak = winner.CurrentStreak
+	}
+
+	loser.Losses++
+	loser.CurrentStreak = 0
+}
+
+// recordRoll records a server-generated dice roll
+// NOTE: Caller must hold h.mu Lock
+func (h *Hub) recordRoll(personaName string, max, result int) {
+	entry := h.scoreEntry(personaName)
+	entry.Rolls++
+	entry.RollTotal += result
+	if result > entry.BestRoll {
+		entry.BestRoll = result
+	}
+	if result == max {
+		entry.MaxRolls++
+	}
+}
+
+// recordTod records a Truth or Dare draw
+// NOTE: Caller must hold h.mu Lock
+func (h *Hub) recordTod(personaName, todType string) {
+	entry := h.scoreEntry(personaName)
+	if todType == "dare" {
+		entry.Dares++
+	} else {
+		entry.Truths++
+	}
+}
+
+// scoreboardSnapshot returns the leaderboard sorted by wins, then best streak, then rolls
+// NOTE: Caller must hold at least RLock
+func (h *Hub) scoreboardSnapshot() []domain.ScoreboardEntry {
+	entries := make([]domain.ScoreboardEntry, 0, len(h.scoreboard))
+	for _, entry := range h.scoreboard {
+		entries = append(entries, *entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Wins != entries[j].Wins {
+			return entries[i].Wins > entries[j].Wins
+		}
+		if entries[i].BestStreak != entries[j].BestStreak {
+			return entries[i].BestStreak > entries[j].BestStreak
+		}
+		if entries[i].Rolls != entries[j].Rolls {
+			return entries[i].Rolls > entries[j].Rolls
+		}
+		return entries[i].PersonaName < entries[j].PersonaName
+	})
+
+	return entries
+}
+
+// buildScoreboardMessage creates a scoreboard frame
+// NOTE: Caller must hold at least RLock
+func (h *Hub) buildScoreboardMessage() *Frame {
+	return scoreboardFrame(domain.ScoreboardPayload{
+		Entries: h.scoreboardSnapshot(),
+	})
+}
+
+// scoreboardFrame wraps a scoreboard payload in a frame
+func scoreboardFrame(payload domain.ScoreboardPayload) *Frame {
+	payloadBytes, _ := json.Marshal(payload)
+
+	msg := domain.Message{
+		ID:        uuid.New().String(),
+		Type:      domain.MessageTypeScoreboard,
+		Payload:   payloadBytes,
+		CreatedAt: time.Now(),
+	}
+
+	return NewFrame(msg)
+}
+
+// broadcastScoreEntries sends only the given personas' entries to all clients (not stored in history)
+// Keeps the frame for a single roll small however big the room's board has grown
+// NOTE: Caller must hold at least RLock
+func (h *Hub) broadcastScoreEntries(personaNames ...string) {
+	payload := domain.ScoreboardPayload{Partial: true}
+	for _, name := range personaNames {
+		if entry, ok := h.scoreboard[name]; ok {
+			payload.Entries = append(payload.Entries, *entry)
+		}
+	}
+
+	frame := scoreboardFrame(payload)
+	for client := range h.allConns() {
+		h.sendFrame(client, frame)
+	}
+}
+
+// sendScoreboardToClient sends the leaderboard to a specific client
+// NOTE: Caller must hold at least RLock
+func (h *Hub) sendScoreboardToClient(c *Client) {
+	h.sendFrame(c, h.buildScoreboardMessage())
+}
+
+// HandleScoreboard replies to the requester with the room leaderboard
+func (h *Hub) HandleScoreboard(c *Client) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	h.sendScoreboardToClient(c)
+}
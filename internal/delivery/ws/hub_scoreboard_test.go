@@ -0,0 +1,129 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+// === SCOREBOARD FEATURE TESTS ===
+
+func TestHub_RecordMatchResult_Streaks(t *testing.T) {
+	hub := NewHub()
+
+	hub.mu.Lock()
+	hub.recordMatchResult("Alice", "Bob", false)
+	hub.recordMatchResult("Alice", "Bob", false)
+	hub.recordMatchResult("Alice", "Bob", true)
+	hub.recordMatchResult("Bob", "Alice", false)
+	hub.recordMatchResult("Alice", "Bob", false)
+	alice := *hub.scoreboard["Alice"]
+	bob := *hub.scoreboard["Bob"]
+	hub.mu.Unlock()
+
+	if alice.Wins != 3 || alice.Losses != 1 || alice.Draws != 1 {
+		t.Errorf("Unexpected Alice record: %+v", alice)
+	}
+	if alice.BestStreak != 2 {
+		t.Errorf("Expected Alice best streak 2, got %d", alice.BestStreak)
+	}
+	if alice.CurrentStreak != 1 {
+		t.Errorf("Expected Alice current streak 1 after loss then win, got %d", alice.CurrentStreak)
+	}
+	if bob.Wins != 1 || bob.Losses != 3 || bob.CurrentStreak != 0 {
+		t.Errorf("Unexpected Bob record: %+v", bob)
+	}
+}
+
+func TestHub_HandleDice_RecordsRollStats(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	roller := newMockClient(hub, "Roller")
+	hub.Register(roller)
+	time.Sleep(30 * time.Millisecond)
+
+	payload, _ := json.Marshal(domain.DicePayload{Max: 2})
+	for i := 0; i < 10; i++ {
+		hub.HandleDice(roller, domain.Message{Type: domain.MessageTypeDice, Payload: payload})
+	}
+
+	hub.mu.RLock()
+	entry := *hub.scoreboard["Roller"]
+	hub.mu.RUnlock()
+
+	if entry.Rolls != 10 {
+		t.Errorf("Expected 10 rolls, got %d", entry.Rolls)
+	}
+	if entry.RollTotal < 10 || entry.RollTotal > 20 {
+		t.Errorf("Expected roll total in [10,20], got %d", entry.RollTotal)
+	}
+	if entry.BestRoll < 1 || entry.BestRoll > 2 {
+		t.Errorf("Expected best roll in [1,2], got %d", entry.BestRoll)
+	}
+}
+
+func TestHub_Scoreboard_SentToJoiner(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	player := withAllFeatures(newMockClient(hub, "Player"))
+	hub.Register(player)
+	time.Sleep(30 * time.Millisecond)
+
+	payload, _ := json.Marshal(domain.TodPayload{Type: "dare"})
+	hub.HandleTod(player, domain.Message{Type: domain.MessageTypeTod, Payload: payload})
+
+	joiner := withAllFeatures(newMockClient(hub, "Joiner"))
+	hub.Register(joiner)
+
+	msg, ok := drainForType(joiner, domain.MessageTypeScoreboard)
+	if !ok {
+		t.Fatal("Joiner should receive the scoreboard on register")
+	}
+	var board domain.ScoreboardPayload
+	json.Unmarshal(msg.Payload, &board)
+	if len(board.Entries) != 1 || board.Entries[0].Dares != 1 {
+		t.Errorf("Expected Player with 1 dare, got %+v", board.Entries)
+	}
+}
+
+func TestHub_HandleScoreboard_Query(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	client := withAllFeatures(newMockClient(hub, "Curious"))
+	hub.Register(client)
+	time.Sleep(30 * time.Millisecond)
+
+	hub.HandleScoreboard(client)
+
+	if _, ok := drainForType(client, domain.MessageTypeScoreboard); !ok {
+		t.Error("Expected scoreboard reply to query")
+	}
+}
+
+func TestHub_HandleDice_BroadcastsOnlyChangedEntry(t *testing.T) {
+	hub := NewHub()
+	veteran := withAllFeatures(newMockClient(hub, "Veteran"))
+	roller := withAllFeatures(newMockClient(hub, "Roller"))
+	setupRoom(t, hub, veteran, roller)
+
+	payload, _ := json.Marshal(domain.DicePayload{Max: 6})
+	hub.HandleDice(veteran, domain.Message{Type: domain.MessageTypeDice, Payload: payload})
+	drainAll(veteran, roller)
+
+	hub.HandleDice(roller, domain.Message{Type: domain.MessageTypeDice, Payload: payload})
+
+	msg, ok := drainForType(veteran, domain.MessageTypeScoreboard)
+	if !ok {
+		t.Fatal("Expected a scoreboard update after the roll")
+	}
+	var board domain.ScoreboardPayload
+	json.Unmarshal(msg.Payload, &board)
+	if !board.Partial || len(board.Entries) != 1 || board.Entries[0].PersonaName != "Roller" {
+		t.Errorf("Expected a partial update with only Roller, got %+v", board)
+	}
+}
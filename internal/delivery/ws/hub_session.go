@@ -3,8 +3,12 @@ package ws
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"sync"
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
 )
 
 // SessionToken represents a reconnection token with associated persona info
@@ -14,24 +18,58 @@ type SessionToken struct {
 	PersonaColor string
 	RoomCode     string
 	UserID       string
+	Lineage      string // First token of the reconnect chain
 	CreatedAt    time.Time
 	LastUsed     time.Time
 }
 
 // SessionStore manages session tokens for secure reconnection
-type SessionStore struct {
-	tokens  map[string]*SessionToken // token -> session
-	userIDs map[string]string        // userID -> token (for cleanup)
-	mu      sync.RWMutex
-	ttl     time.Duration
+type SessionStore interface {
+	GenerateToken(userID, personaName, personaColor, roomCode string) string
+	GenerateTokenInLineage(userID, personaName, personaColor, roomCode, lineage string) string
+	ValidateToken(token string) (*SessionToken, bool)
+	ConsumeToken(token string) (*SessionToken, bool)
+	Touch(userID string)
+	RemoveToken(token string)
+	RemoveByUserID(userID string)
+	RevokeLineage(lineage string) int
+	GetTokenByUserID(userID string) (string, bool)
+	Count() int
+	Close() error
+}
+
+// MemorySessionStore is the default SessionStore, holding tokens in process memory only
+type MemorySessionStore struct {
+	tokens    map[string]*SessionToken   // token -> session
+	userIDs   map[string]map[string]bool // userID -> tokens, one per connection (for cleanup)
+	mu        sync.RWMutex
+	ttl       time.Duration
+	stop      chan struct{}
+	closeOnce sync.Once
 }
 
-// NewSessionStore creates a new session store
-func NewSessionStore() *SessionStore {
-	store := &SessionStore{
+// SessionStoreOption overrides a MemorySessionStore setting
+type SessionStoreOption func(*MemorySessionStore)
+
+// WithSessionTTL sets how long tokens stay valid
+func WithSessionTTL(ttl time.Duration) SessionStoreOption {
+	return func(s *MemorySessionStore) {
+		if ttl > 0 {
+			s.ttl = ttl
+		}
+	}
+}
+
+// NewSessionStore creates a new in-memory session store
+func NewSessionStore(opts ...SessionStoreOption) *MemorySessionStore {
+	store := &MemorySessionStore{
 		tokens:  make(map[string]*SessionToken),
-		userIDs: make(map[string]string),
-		ttl:     24 * time.Hour, // Tokens valid for 24 hours
+		userIDs: make(map[string]map[string]bool),
+		ttl:     domain.SessionTTL,
+		stop:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(store)
 	}
 
 	// Start cleanup goroutine
@@ -41,19 +79,24 @@ func NewSessionStore() *SessionStore {
 }
 
 // GenerateToken creates a new session token for a user
-func (s *SessionStore) GenerateToken(userID, personaName, personaColor, roomCode string) string {
+func (s *MemorySessionStore) GenerateToken(userID, personaName, personaColor, roomCode string) string {
+	return s.GenerateTokenInLineage(userID, personaName, personaColor, roomCode, "")
+}
+
+// GenerateTokenInLineage creates a session token that continues a reconnect chain
+// An empty lineage starts a new chain rooted at the new token
+// Tokens the user holds for other connections stay valid, so every tab can reconnect on its own
+func (s *MemorySessionStore) GenerateTokenInLineage(userID, personaName, personaColor, roomCode, lineage string) string {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Remove old token if exists
-	if oldToken, exists := s.userIDs[userID]; exists {
-		delete(s.tokens, oldToken)
-	}
-
 	// Generate secure random token
 	tokenBytes := make([]byte, 32) // 256 bits
 	rand.Read(tokenBytes)
 	token := hex.EncodeToString(tokenBytes)
+	if lineage == "" {
+		lineage = token
+	}
 
 	// Store session
 	session := &SessionToken{
@@ -62,18 +105,38 @@ func (s *SessionStore) GenerateToken(userID, personaName, personaColor, roomCode
 		PersonaColor: personaColor,
 		RoomCode:     roomCode,
 		UserID:       userID,
+		Lineage:      lineage,
 		CreatedAt:    time.Now(),
 		LastUsed:     time.Now(),
 	}
 
-	s.tokens[token] = session
-	s.userIDs[userID] = token
+	s.addLocked(session)
 
 	return token
 }
 
+// addLocked indexes a session by token and user
+// NOTE: Caller must hold s.mu.Lock
+func (s *MemorySessionStore) addLocked(session *SessionToken) {
+	s.tokens[session.Token] = session
+	if s.userIDs[session.UserID] == nil {
+		s.userIDs[session.UserID] = make(map[string]bool)
+	}
+	s.userIDs[session.UserID][session.Token] = true
+}
+
+// removeLocked drops a session from both indexes
+// NOTE: Caller must hold s.mu.Lock
+func (s *MemorySessionStore) removeLocked(session *SessionToken) {
+	delete(s.tokens, session.Token)
+	delete(s.userIDs[session.UserID], session.Token)
+	if len(s.userIDs[session.UserID]) == 0 {
+		delete(s.userIDs, session.UserID)
+	}
+}
+
 // ValidateToken checks if a token is valid and returns the session
-func (s *SessionStore) ValidateToken(token string) (*SessionToken, bool) {
+func (s *MemorySessionStore) ValidateToken(token string) (*SessionToken, bool) {
 	s.mu.RLock()
 	session, exists := s.tokens[token]
 	s.mu.RUnlock()
@@ -82,8 +145,8 @@ func (s *SessionStore) ValidateToken(token string) (*SessionToken, bool) {
 		return nil, false
 	}
 
-	// Check if expired
-	if time.Since(session.CreatedAt) > s.ttl {
+	// Check if idle for too long
+	if s.expired(session, time.Now()) {
 		s.RemoveToken(token)
 		return nil, false
 	}
@@ -96,67 +159,206 @@ func (s *SessionStore) ValidateToken(token string) (*SessionToken, bool) {
 	return session, true
 }
 
+// ConsumeToken validates a token and removes it in one step, so it resumes at most once
+// The caller issues a fresh token in the same lineage for the new connection
+func (s *MemorySessionStore) ConsumeToken(token string) (*SessionToken, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.tokens[token]
+	if !exists {
+		return nil, false
+	}
+	s.removeLocked(session)
+
+	if s.expired(session, time.Now()) {
+		return nil, false
+	}
+	return session, true
+}
+
+// Touch slides the expiry of a user's tokens forward (a connection is still alive)
+func (s *MemorySessionStore) Touch(userID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for token := range s.userIDs[userID] {
+		s.tokens[token].LastUsed = now
+	}
+}
+
+// expired reports whether a session has been idle longer than the TTL
+// NOTE: Caller must hold at least s.mu.RLock (or own the session)
+func (s *MemorySessionStore) expired(session *SessionToken, now time.Time) bool {
+	return now.Sub(session.LastUsed) > s.ttl
+}
+
 // RemoveToken removes a token from the store
-func (s *SessionStore) RemoveToken(token string) {
+func (s *MemorySessionStore) RemoveToken(token string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if session, exists := s.tokens[token]; exists {
-		delete(s.userIDs, session.UserID)
-		delete(s.tokens, token)
+		s.removeLocked(session)
 	}
 }
 
-// RemoveByUserID removes a token by user ID
-func (s *SessionStore) RemoveByUserID(userID string) {
+// RemoveByUserID removes every token of a user
+func (s *MemorySessionStore) RemoveByUserID(userID string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if token, exists := s.userIDs[userID]; exists {
+	for token := range s.userIDs[userID] {
 		delete(s.tokens, token)
-		delete(s.userIDs, userID)
 	}
+	delete(s.userIDs, userID)
 }
 
-// GetTokenByUserID returns the token for a user ID
-func (s *SessionStore) GetTokenByUserID(userID string) (string, bool) {
+// RevokeLineage removes every token in a reconnect chain
+func (s *MemorySessionStore) RevokeLineage(lineage string) int {
+	if lineage == "" {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for _, session := range s.tokens {
+		if session.Lineage == lineage {
+			s.removeLocked(session)
+			removed++
+		}
+	}
+	return removed
+}
+
+// GetTokenByUserID returns the most recently issued token for a user ID
+func (s *MemorySessionStore) GetTokenByUserID(userID string) (string, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	token, exists := s.userIDs[userID]
-	return token, exists
+	var newest *SessionToken
+	for token := range s.userIDs[userID] {
+		if session := s.tokens[token]; newest == nil || session.CreatedAt.After(newest.CreatedAt) {
+			newest = session
+		}
+	}
+	if newest == nil {
+		return "", false
+	}
+	return newest.Token, true
 }
 
 // cleanupLoop periodically removes expired tokens
-func (s *SessionStore) cleanupLoop() {
+func (s *MemorySessionStore) cleanupLoop() {
 	ticker := time.NewTicker(1 * time.Hour)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		s.cleanup()
+	for {
+		select {
+		case <-ticker.C:
+			s.cleanup()
+		case <-s.stop:
+			return
+		}
 	}
 }
 
+// Close stops the cleanup goroutine
+func (s *MemorySessionStore) Close() error {
+	s.closeOnce.Do(func() { close(s.stop) })
+	return nil
+}
+
 // cleanup removes expired tokens
-func (s *SessionStore) cleanup() {
+func (s *MemorySessionStore) cleanup() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	now := time.Now()
-	for token, session := range s.tokens {
-		if now.Sub(session.CreatedAt) > s.ttl {
-			delete(s.userIDs, session.UserID)
-			delete(s.tokens, token)
+	for _, session := range s.tokens {
+		if s.expired(session, now) {
+			s.removeLocked(session)
 		}
 	}
 }
 
 // Count returns the number of active sessions
-func (s *SessionStore) Count() int {
+func (s *MemorySessionStore) Count() int {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	return len(s.tokens)
 }
 
-// Global session store
-var GlobalSessionStore = NewSessionStore()
+// sessions returns copies of every unexpired session
+func (s *MemorySessionStore) sessions() []SessionToken {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	out := make([]SessionToken, 0, len(s.tokens))
+	for _, session := range s.tokens {
+		if !s.expired(session, now) {
+			out = append(out, *session)
+		}
+	}
+	return out
+}
+
+// load adds sessions to the store, skipping expired ones and tokens it already holds
+func (s *MemorySessionStore) load(sessions []SessionToken) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	loaded := 0
+	for i := range sessions {
+		session := sessions[i]
+		if session.Token == "" || s.expired(&session, now) {
+			continue
+		}
+		if _, exists := s.tokens[session.Token]; exists {
+			continue
+		}
+		s.addLocked(&session)
+		loaded++
+	}
+	return loaded
+}
+
+// SessionTokenMessage builds the message that hands a connection its reconnect token
+func SessionTokenMessage(token string) domain.Message {
+	payload, _ := json.Marshal(domain.SessionTokenPayload{Token: token})
+	return domain.Message{
+		ID:        uuid.New().String(),
+		Type:      domain.MessageTypeSessionToken,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}
+}
+
+// reissueSpentTokens gives a fresh token to each connection of the user whose own token was redeemed elsewhere
+// A duplicated tab connects with the original tab's token, which would otherwise leave that tab unable to resume
+// NOTE: Caller must hold h.mu.Lock
+func (h *Hub) reissueSpentTokens(userID string) {
+	for _, c := range h.conns[userID] {
+		if c.token == "" {
+			continue
+		}
+		if _, valid := GlobalSessionStore.ValidateToken(c.token); valid {
+			continue
+		}
+		c.token = GlobalSessionStore.GenerateTokenInLineage(userID, c.User.PersonaName, c.User.PersonaColor, h.roomCode, c.lineage)
+		c.SendMessage(SessionTokenMessage(c.token))
+	}
+}
+
+// HandleLogout revokes the user's reconnect tokens, on every tab, so its persona cannot be resumed
+func (h *Hub) HandleLogout(c *Client) {
+	GlobalSessionStore.RemoveByUserID(c.User.ID.String())
+}
+
+// Global session store, replaced at startup with one built from config
+var GlobalSessionStore SessionStore = NewSessionStore()
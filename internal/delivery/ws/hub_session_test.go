@@ -4,6 +4,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
 )
 
 func TestNewSessionStore(t *testing.T) {
@@ -56,30 +58,30 @@ func TestSessionStore_ValidateToken(t *testing.T) {
 	}
 }
 
-func TestSessionStore_TokenReplacesOld(t *testing.T) {
+func TestSessionStore_TokenPerConnection(t *testing.T) {
 	store := NewSessionStore()
 
-	token1 := store.GenerateToken("user1", "OldGoat", "#FF0000", "ROOM1")
-	token2 := store.GenerateToken("user1", "NewGoat", "#00FF00", "ROOM2")
+	// Two tabs of the same user each hold their own token
+	token1 := store.GenerateToken("user1", "CoolGoat", "#FF0000", "ROOM1")
+	time.Sleep(time.Millisecond)
+	token2 := store.GenerateToken("user1", "CoolGoat", "#FF0000", "ROOM1")
 
-	// Old token should be invalid
-	_, valid := store.ValidateToken(token1)
-	if valid {
-		t.Error("Old token should be invalid after generating new one")
+	if _, valid := store.ValidateToken(token1); !valid {
+		t.Error("First tab's token should survive the second tab connecting")
 	}
-
-	// New token should be valid
-	session, valid := store.ValidateToken(token2)
-	if !valid {
-		t.Fatal("New token should be valid")
+	if _, valid := store.ValidateToken(token2); !valid {
+		t.Error("Second tab's token should be valid")
+	}
+	if store.Count() != 2 {
+		t.Errorf("Expected 2 sessions, got %d", store.Count())
 	}
-	if session.PersonaName != "NewGoat" {
-		t.Errorf("Expected persona 'NewGoat', got %s", session.PersonaName)
+	if newest, _ := store.GetTokenByUserID("user1"); newest != token2 {
+		t.Error("Expected the newest token for the user")
 	}
 
-	// Should only have 1 session
-	if store.Count() != 1 {
-		t.Errorf("Expected 1 session, got %d", store.Count())
+	store.RemoveByUserID("user1")
+	if store.Count() != 0 {
+		t.Errorf("Expected every token of the user removed, got %d", store.Count())
 	}
 }
 
@@ -110,6 +112,36 @@ func TestSessionStore_RemoveByUserID(t *testing.T) {
 	}
 }
 
+func TestSessionStore_RevokeLineage(t *testing.T) {
+	store := NewSessionStore()
+
+	first := store.GenerateToken("user1", "CoolGoat", "#FF0000", "ROOM1")
+	session, _ := store.ValidateToken(first)
+	if session.Lineage != first {
+		t.Fatalf("Expected new chain to be rooted at its token, got %s", session.Lineage)
+	}
+
+	// Reconnect under a new user ID continues the chain
+	second := store.GenerateTokenInLineage("user2", "CoolGoat", "#FF0000", "ROOM1", session.Lineage)
+	other := store.GenerateToken("user3", "OtherGoat", "#00FF00", "ROOM1")
+
+	if removed := store.RevokeLineage(session.Lineage); removed != 2 {
+		t.Errorf("Expected 2 tokens revoked, got %d", removed)
+	}
+	if _, valid := store.ValidateToken(first); valid {
+		t.Error("Expected first token in lineage to be revoked")
+	}
+	if _, valid := store.ValidateToken(second); valid {
+		t.Error("Expected reconnect token in lineage to be revoked")
+	}
+	if _, valid := store.ValidateToken(other); !valid {
+		t.Error("Expected unrelated token to survive")
+	}
+	if _, exists := store.GetTokenByUserID("user2"); exists {
+		t.Error("Expected revoked user to have no token left")
+	}
+}
+
 func TestSessionStore_GetTokenByUserID(t *testing.T) {
 	store := NewSessionStore()
 
@@ -131,11 +163,8 @@ func TestSessionStore_GetTokenByUserID(t *testing.T) {
 }
 
 func TestSessionStore_TokenExpiry(t *testing.T) {
-	store := &SessionStore{
-		tokens:  make(map[string]*SessionToken),
-		userIDs: make(map[string]string),
-		ttl:     100 * time.Millisecond, // Very short TTL for testing
-	}
+	store := NewSessionStore(WithSessionTTL(100 * time.Millisecond)) // Very short TTL for testing
+	defer store.Close()
 
 	token := store.GenerateToken("user1", "CoolGoat", "#FF0000", "ROOM1")
 
@@ -202,3 +231,61 @@ func TestGlobalSessionStore(t *testing.T) {
 		t.Fatal("Global session store should be initialized")
 	}
 }
+
+func TestSessionStore_ConsumeTokenSingleUse(t *testing.T) {
+	store := NewSessionStore()
+	defer store.Close()
+
+	token := store.GenerateToken("user1", "CoolGoat", "#FF0000", "ROOM1")
+
+	session, ok := store.ConsumeToken(token)
+	if !ok || session.PersonaName != "CoolGoat" {
+		t.Fatal("Expected first use to succeed")
+	}
+	if _, ok := store.ConsumeToken(token); ok {
+		t.Error("Expected token to be single-use")
+	}
+	if _, exists := store.GetTokenByUserID("user1"); exists {
+		t.Error("Expected consumed token to be removed from the user index")
+	}
+}
+
+func TestSessionStore_SlidingExpiry(t *testing.T) {
+	store := NewSessionStore(WithSessionTTL(100 * time.Millisecond))
+	defer store.Close()
+
+	token := store.GenerateToken("user1", "CoolGoat", "#FF0000", "ROOM1")
+
+	// Kept alive past the TTL by activity
+	time.Sleep(60 * time.Millisecond)
+	store.Touch("user1")
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := store.ConsumeToken(token); !ok {
+		t.Error("Expected a recently used token to stay valid")
+	}
+
+	// Left idle past the TTL
+	token = store.GenerateToken("user2", "CoolGoat", "#FF0000", "ROOM1")
+	time.Sleep(150 * time.Millisecond)
+	if _, ok := store.ConsumeToken(token); ok {
+		t.Error("Expected an idle token to expire")
+	}
+}
+
+func TestHub_LogoutRevokesToken(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	client := newMockClient(hub, "Leaver")
+	hub.Register(client)
+	if !waitForClients(hub, 1) {
+		t.Fatal("Client did not register")
+	}
+
+	token := GlobalSessionStore.GenerateToken(client.User.ID.String(), "Leaver", "#000", "ROOM1")
+	dispatchInbound(client, domain.MessageTypeLogout, `{}`, "")
+
+	if _, valid := GlobalSessionStore.ValidateToken(token); valid {
+		t.Error("Expected logout to revoke the session token")
+	}
+}
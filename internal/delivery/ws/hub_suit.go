@@ -0,0 +1,313 @@
+package ws
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+// suitMatch is the server-side state of a Rock-Paper-Scissors match
+// Moves are kept here and only copied into the public payload on completion
+type suitMatch struct {
+	payload        domain.SuitPayload
+	challengerMove string
+	opponentMove   string
+	timer          *time.Timer
+}
+
+// suitBeats maps a move to the move it defeats
+var suitBeats = map[string]string{
+	"rock":     "scissors",
+	"paper":    "rock",
+	"scissors": "paper",
+}
+
+// HandleSuit runs the suit state machine for challenge/accept/decline/move requests
+func (h *Hub) HandleSuit(c *Client, msg domain.Message) {
+	var req domain.SuitPayload
+	if err := json.Unmarshal(msg.Payload, &req); err != nil {
+		h.sendError(c, domain.ErrCodeInvalidPayload, "Format suit tidak valid")
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if req.Action == "challenge" {
+		h.handleSuitChallenge(c, req)
+		return
+	}
+
+	match, ok := h.suitMatches[req.ID]
+	if !ok {
+		h.sendError(c, domain.ErrCodeSuitNotFound, "Tantangan suit tidak ditemukan")
+		return
+	}
+
+	isChallenger := c.ID == match.payload.ChallengerID
+	isOpponent := c.ID == match.payload.OpponentID
+	if !isChallenger && !isOpponent {
+		h.sendError(c, domain.ErrCodeForbidden, "Kamu bukan peserta suit ini")
+		return
+	}
+
+	switch req.Action {
+	case "accept":
+		if !isOpponent || match.payload.Status != "pending" {
+			return
+		}
+		match.payload.Status = "accepted"
+		h.startSuitTimer(match, h.suitMoveTimeout)
+		h.broadcastSuit(match)
+
+	case "decline":
+		if !isOpponent || match.payload.Status != "pending" {
+			return
+		}
+		match.payload.Status = "declined"
+		h.finishSuit(match)
+
+	case "move":
+		if _, valid := suitBeats[req.Move]; !valid {
+			h.sendError(c, domain.ErrCodeInvalidPayload, "Pilih rock, paper, atau scissors")
+			return
+		}
+		// Challenger may commit early; opponent must accept first
+		if match.payload.Status != "accepted" && !(isChallenger && match.payload.Status == "pending") {
+			return
+		}
+
+		if isChallenger && match.challengerMove == "" {
+			match.challengerMove = req.Move
+			match.payload.ChallengerMoved = true
+		} else if isOpponent && match.opponentMove == "" {
+			match.opponentMove = req.Move
+			match.payload.OpponentMoved = true
+		} else {
+			return // Moves cannot be changed once committed
+		}
+
+		h.sendSuitMoveAck(c, match, req.Move)
+
+		if match.challengerMove != "" && match.opponentMove != "" {
+			h.resolveSuit(match)
+			return
+		}
+		h.broadcastSuit(match)
+	}
+}
+
+// handleSuitChallenge creates a new pending match
+// NOTE: Caller must hold h.mu Lock
+func (h *Hub) handleSuitChallenge(c *Client, req domain.SuitPayload) {
+	opponent, ok := h.clients[req.OpponentID]
+	if !ok {
+		h.sendError(c, domain.ErrCodeUserOffline, "User tidak ditemukan atau sedang offline")
+		return
+	}
+	if opponent.ID == c.ID {
+		h.sendError(c, domain.ErrCodeInvalidPayload, "Tidak bisa menantang diri sendiri")
+		return
+	}
+
+	match := &suitMatch{
+		payload: domain.SuitPayload{
+			ID:             uuid.New().String(),
+			ChallengerID:   c.ID,
+			ChallengerName: c.User.PersonaName,
+			OpponentID:     opponent.ID,
+			OpponentName:   opponent.User.PersonaName,
+			Status:         "pending",
+		},
+	}
+
+	// Challenger may commit their move together with the challenge
+	if _, valid := suitBeats[req.Move]; valid {
+		match.challengerMove = req.Move
+		match.payload.ChallengerMoved = true
+	}
+
+	h.suitMatches[match.payload.ID] = match
+	h.startSuitTimer(match, h.suitAcceptTimeout)
+	h.broadcastSuit(match)
+
+	if match.challengerMove != "" {
+		h.sendSuitMoveAck(c, match, match.challengerMove)
+	}
+}
+
+// startSuitTimer (re)arms the timeout for the match's current state
+// NOTE: Caller must hold h.mu Lock
+func (h *Hub) startSuitTimer(match *suitMatch, d time.Duration) {
+	if match.timer != nil {
+		match.timer.Stop()
+	}
+	match.payload.ExpiresAt = time.Now().Add(d)
+
+	matchID := match.payload.ID
+	match.timer = time.AfterFunc(d, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		m, ok := h.suitMatches[matchID]
+		if !ok || m != match {
+			return
+		}
+		h.handleSuitTimeout(m)
+	})
+}
+
+// handleSuitTimeout expires a pending match or forfeits an accepted one
+// NOTE: Caller must hold h.mu Lock
+func (h *Hub) handleSuitTimeout(match *suitMatch) {
+	if match.payload.Status == "accepted" {
+		// Whoever committed a move wins by forfeit
+		switch {
+		case match.challengerMove != "" && match.opponentMove == "":
+			match.payload.ChallengerMove = match.challengerMove
+			h.completeSuit(match, match.payload.ChallengerID)
+			return
+		case match.opponentMove != "" && match.challengerMove == "":
+			match.payload.OpponentMove = match.opponentMove
+			h.completeSuit(match, match.payload.OpponentID)
+			return
+		}
+	}
+
+	match.payload.Status = "expired"
+	h.finishSuit(match)
+}
+
+// resolveSuit reveals both moves and decides the winner
+// NOTE: Caller must hold h.mu Lock
+func (h *Hub) resolveSuit(match *suitMatch) {
+	match.payload.ChallengerMove = match.challengerMove
+	match.payload.OpponentMove = match.opponentMove
+
+	winner := "draw"
+	if suitBeats[match.challengerMove] == match.opponentMove {
+		winner = match.payload.ChallengerID
+	} else if suitBeats[match.opponentMove] == match.challengerMove {
+		winner = match.payload.OpponentID
+	}
+	h.completeSuit(match, winner)
+}
+
+// completeSuit records the result on the scoreboard and finishes the match
+// NOTE: Caller must hold h.mu Lock
+func (h *Hub) completeSuit(match *suitMatch, winner string) {
+	match.payload.Status = "completed"
+	match.payload.Winner = winner
+
+	switch winner {
+	case match.payload.ChallengerID:
+		h.recordMatchResult(match.payload.ChallengerName, match.payload.OpponentName, false)
+	case match.payload.OpponentID:
+		h.recordMatchResult(match.payload.OpponentName, match.payload.ChallengerName, false)
+	default:
+		h.recordMatchResult(match.payload.ChallengerName, match.payload.OpponentName, true)
+	}
+
+	h.finishSuit(match)
+	h.broadcastScoreEntries(match.payload.ChallengerName, match.payload.OpponentName)
+}
+
+// finishSuit publishes the final state and forgets the match
+// Only this state goes into history, so late joiners never replay a decided challenge
+// NOTE: Caller must hold h.mu Lock
+func (h *Hub) finishSuit(match *suitMatch) {
+	if match.timer != nil {
+		match.timer.Stop()
+		match.timer = nil
+	}
+	match.payload.ExpiresAt = time.Time{}
+	delete(h.suitMatches, match.payload.ID)
+	h.publishLocked(suitFrame(match))
+}
+
+// resetSuitMatches stops all pending suit timers and drops active matches
+// NOTE: Caller must hold h.mu Lock
+func (h *Hub) resetSuitMatches() {
+	for _, match := range h.suitMatches {
+		if match.timer != nil {
+			match.timer.Stop()
+		}
+	}
+	h.suitMatches = make(map[string]*suitMatch)
+}
+
+// broadcastSuit sends an intermediate match state to all clients (not stored in history)
+// Delivered in place rather than queued, so a busy room never loses a match update
+// NOTE: Caller must hold h.mu Lock
+func (h *Hub) broadcastSuit(match *suitMatch) {
+	h.deliverLocked(suitFrame(match))
+}
+
+// suitFrame builds the public match state (moves stay hidden until completion)
+func suitFrame(match *suitMatch) *Frame {
+	payloadBytes, _ := json.Marshal(match.payload)
+
+	msg := domain.Message{
+		ID:        uuid.New().String(),
+		Type:      domain.MessageTypeSuit,
+		FromID:    match.payload.ChallengerID,
+		FromName:  match.payload.ChallengerName,
+		Payload:   payloadBytes,
+		CreatedAt: time.Now(),
+	}
+
+	return NewFrame(msg)
+}
+
+// sendSuitMoveAck privately confirms a committed move to the player who made it
+// NOTE: Caller must hold at least RLock
+func (h *Hub) sendSuitMoveAck(c *Client, match *suitMatch, move string) {
+	private := match.payload
+	if c.ID == match.payload.ChallengerID {
+		private.ChallengerMove = move
+	} else {
+		private.OpponentMove = move
+	}
+	payloadBytes, _ := json.Marshal(private)
+
+	msg := domain.Message{
+		ID:        uuid.New().String(),
+		Type:      domain.MessageTypeSuit,
+		FromID:    match.payload.ChallengerID,
+		FromName:  match.payload.ChallengerName,
+		Payload:   payloadBytes,
+		CreatedAt: time.Now(),
+	}
+
+	h.sendToUser(c, NewFrame(msg))
+}
+
+// HandleSuitScoreboard replies to the requester with the room's suit scoreboard
+func (h *Hub) HandleSuitScoreboard(c *Client) {
+	h.mu.RLock()
+	scores := make([]domain.SuitScore, 0, len(h.scoreboard))
+	for _, entry := range h.scoreboardSnapshot() {
+		if entry.Wins+entry.Losses+entry.Draws == 0 {
+			continue
+		}
+		scores = append(scores, domain.SuitScore{
+			PersonaName: entry.PersonaName,
+			Wins:        entry.Wins,
+			Losses:      entry.Losses,
+			Draws:       entry.Draws,
+		})
+	}
+	h.mu.RUnlock()
+
+	payloadBytes, _ := json.Marshal(domain.SuitScoreboardPayload{Scores: scores})
+	msg := domain.Message{
+		ID:        uuid.New().String(),
+		Type:      domain.MessageTypeSuitScoreboard,
+		Payload:   payloadBytes,
+		CreatedAt: time.Now(),
+	}
+
+	h.sendFrame(c, NewFrame(msg))
+}
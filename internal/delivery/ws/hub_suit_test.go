@@ -0,0 +1,237 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+// === SUIT (ROCK-PAPER-SCISSORS) FEATURE TESTS ===
+
+func sendSuit(hub *Hub, c *Client, req domain.SuitPayload) {
+	payload, _ := json.Marshal(req)
+	hub.HandleSuit(c, domain.Message{Type: domain.MessageTypeSuit, Payload: payload})
+}
+
+// lastSuitState drains a client's queue and returns the latest suit payload seen
+func lastSuitState(c *Client) (domain.SuitPayload, bool) {
+	var last domain.SuitPayload
+	found := false
+	for {
+		msg, ok := drainForType(c, domain.MessageTypeSuit)
+		if !ok {
+			return last, found
+		}
+		json.Unmarshal(msg.Payload, &last)
+		found = true
+	}
+}
+
+// challengeSuit has challenger challenge opponent and returns the new match id
+func challengeSuit(t *testing.T, hub *Hub, challenger, opponent *Client) string {
+	t.Helper()
+	sendSuit(hub, challenger, domain.SuitPayload{Action: "challenge", OpponentID: opponent.ID})
+
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	for id := range hub.suitMatches {
+		return id
+	}
+	t.Fatal("Expected suit match to be created")
+	return ""
+}
+
+func TestHub_HandleSuit_DeliveredWhenBroadcastQueueIsFull(t *testing.T) {
+	hub := NewHub() // Not running, so nothing drains the broadcast queue
+
+	challenger := withAllFeatures(newMockClient(hub, "Challenger"))
+	opponent := withAllFeatures(newMockClient(hub, "Opponent"))
+	hub.mu.Lock()
+	for _, c := range []*Client{challenger, opponent} {
+		hub.clients[c.ID] = c
+		hub.conns[c.ID] = []*Client{c}
+	}
+	hub.mu.Unlock()
+	for len(hub.broadcast) < cap(hub.broadcast) {
+		hub.broadcast <- NewFrame(domain.Message{Type: domain.MessageTypeChat})
+	}
+
+	sendSuit(hub, challenger, domain.SuitPayload{Action: "challenge", OpponentID: opponent.ID})
+
+	state, ok := lastSuitState(opponent)
+	if !ok || state.Status != "pending" {
+		t.Fatalf("Expected the challenge to reach the opponent, got %+v", state)
+	}
+}
+
+func TestHub_HandleSuit_MovesHiddenUntilCompleted(t *testing.T) {
+	hub := NewHub()
+	challenger := withAllFeatures(newMockClient(hub, "Challenger"))
+	opponent := withAllFeatures(newMockClient(hub, "Opponent"))
+	watcher := withAllFeatures(newMockClient(hub, "Watcher"))
+	setupRoom(t, hub, challenger, opponent, watcher)
+	id := challengeSuit(t, hub, challenger, opponent)
+
+	sendSuit(hub, opponent, domain.SuitPayload{ID: id, Action: "accept"})
+	sendSuit(hub, challenger, domain.SuitPayload{ID: id, Action: "move", Move: "rock"})
+	time.Sleep(20 * time.Millisecond)
+
+	state, ok := lastSuitState(watcher)
+	if !ok {
+		t.Fatal("Watcher should receive public suit state")
+	}
+	if state.ChallengerMove != "" {
+		t.Error("Challenger move must stay hidden before opponent moves")
+	}
+	if !state.ChallengerMoved {
+		t.Error("Expected public state to show challenger has moved")
+	}
+
+	sendSuit(hub, opponent, domain.SuitPayload{ID: id, Action: "move", Move: "scissors"})
+	time.Sleep(20 * time.Millisecond)
+
+	state, _ = lastSuitState(watcher)
+	if state.Status != "completed" {
+		t.Fatalf("Expected completed status, got %s", state.Status)
+	}
+	if state.Winner != challenger.ID {
+		t.Errorf("Expected rock to beat scissors (winner %s), got %s", challenger.ID, state.Winner)
+	}
+	if state.ChallengerMove != "rock" || state.OpponentMove != "scissors" {
+		t.Errorf("Expected both moves revealed, got %s vs %s", state.ChallengerMove, state.OpponentMove)
+	}
+}
+
+func TestHub_HandleSuit_HistoryKeepsOnlyFinalState(t *testing.T) {
+	hub := NewHub()
+	challenger := withAllFeatures(newMockClient(hub, "Challenger"))
+	opponent := withAllFeatures(newMockClient(hub, "Opponent"))
+	setupRoom(t, hub, challenger, opponent)
+	id := challengeSuit(t, hub, challenger, opponent)
+
+	sendSuit(hub, opponent, domain.SuitPayload{ID: id, Action: "accept"})
+	sendSuit(hub, challenger, domain.SuitPayload{ID: id, Action: "move", Move: "paper"})
+	sendSuit(hub, opponent, domain.SuitPayload{ID: id, Action: "move", Move: "rock"})
+
+	hub.mu.RLock()
+	history := hub.messageHistory.GetAll()
+	hub.mu.RUnlock()
+
+	var states []string
+	for _, data := range history {
+		var msg domain.Message
+		if json.Unmarshal(data, &msg) != nil || msg.Type != domain.MessageTypeSuit {
+			continue
+		}
+		var state domain.SuitPayload
+		json.Unmarshal(msg.Payload, &state)
+		states = append(states, state.Status)
+	}
+	if len(states) != 1 || states[0] != "completed" {
+		t.Errorf("Expected only the completed state in history, got %v", states)
+	}
+}
+
+func TestHub_HandleSuit_OpponentCannotMoveBeforeAccept(t *testing.T) {
+	hub := NewHub()
+	challenger := withAllFeatures(newMockClient(hub, "Challenger"))
+	opponent := withAllFeatures(newMockClient(hub, "Opponent"))
+	setupRoom(t, hub, challenger, opponent, withAllFeatures(newMockClient(hub, "Watcher")))
+	id := challengeSuit(t, hub, challenger, opponent)
+
+	sendSuit(hub, opponent, domain.SuitPayload{ID: id, Action: "move", Move: "paper"})
+
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	if hub.suitMatches[id].opponentMove != "" {
+		t.Error("Opponent move should be ignored before accepting")
+	}
+}
+
+func TestHub_HandleSuit_OutsiderRejected(t *testing.T) {
+	hub := NewHub()
+	challenger := withAllFeatures(newMockClient(hub, "Challenger"))
+	opponent := withAllFeatures(newMockClient(hub, "Opponent"))
+	watcher := withAllFeatures(newMockClient(hub, "Watcher"))
+	setupRoom(t, hub, challenger, opponent, watcher)
+	id := challengeSuit(t, hub, challenger, opponent)
+
+	sendSuit(hub, watcher, domain.SuitPayload{ID: id, Action: "accept"})
+
+	if _, ok := drainForType(watcher, domain.MessageTypeError); !ok {
+		t.Error("Expected error frame for non-participant")
+	}
+}
+
+func TestHub_HandleSuit_AcceptTimeout(t *testing.T) {
+	hub := NewHub()
+	hub.suitAcceptTimeout = 20 * time.Millisecond
+	challenger := withAllFeatures(newMockClient(hub, "Challenger"))
+	opponent := withAllFeatures(newMockClient(hub, "Opponent"))
+	watcher := withAllFeatures(newMockClient(hub, "Watcher"))
+	setupRoom(t, hub, challenger, opponent, watcher)
+	id := challengeSuit(t, hub, challenger, opponent)
+	time.Sleep(60 * time.Millisecond)
+
+	hub.mu.RLock()
+	_, stillActive := hub.suitMatches[id]
+	hub.mu.RUnlock()
+	if stillActive {
+		t.Fatal("Expected match to be removed after accept timeout")
+	}
+
+	state, _ := lastSuitState(watcher)
+	if state.Status != "expired" {
+		t.Errorf("Expected expired status, got %s", state.Status)
+	}
+}
+
+func TestHub_HandleSuit_MoveTimeoutForfeit(t *testing.T) {
+	hub := NewHub()
+	hub.suitMoveTimeout = 20 * time.Millisecond
+	challenger := withAllFeatures(newMockClient(hub, "Challenger"))
+	opponent := withAllFeatures(newMockClient(hub, "Opponent"))
+	watcher := withAllFeatures(newMockClient(hub, "Watcher"))
+	setupRoom(t, hub, challenger, opponent, watcher)
+	id := challengeSuit(t, hub, challenger, opponent)
+	sendSuit(hub, opponent, domain.SuitPayload{ID: id, Action: "accept"})
+	sendSuit(hub, opponent, domain.SuitPayload{ID: id, Action: "move", Move: "paper"})
+	time.Sleep(60 * time.Millisecond)
+
+	state, _ := lastSuitState(watcher)
+	if state.Status != "completed" || state.Winner != opponent.ID {
+		t.Errorf("Expected opponent to win by forfeit, got status %s winner %s", state.Status, state.Winner)
+	}
+}
+
+func TestHub_HandleSuitScoreboard(t *testing.T) {
+	hub := NewHub()
+	challenger := withAllFeatures(newMockClient(hub, "Challenger"))
+	opponent := withAllFeatures(newMockClient(hub, "Opponent"))
+	setupRoom(t, hub, challenger, opponent, withAllFeatures(newMockClient(hub, "Watcher")))
+	id := challengeSuit(t, hub, challenger, opponent)
+	sendSuit(hub, opponent, domain.SuitPayload{ID: id, Action: "accept"})
+	sendSuit(hub, challenger, domain.SuitPayload{ID: id, Action: "move", Move: "paper"})
+	sendSuit(hub, opponent, domain.SuitPayload{ID: id, Action: "move", Move: "scissors"})
+
+	hub.HandleSuitScoreboard(challenger)
+
+	msg, ok := drainForType(challenger, domain.MessageTypeSuitScoreboard)
+	if !ok {
+		t.Fatal("Expected scoreboard reply")
+	}
+	var board domain.SuitScoreboardPayload
+	json.Unmarshal(msg.Payload, &board)
+
+	if len(board.Scores) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(board.Scores))
+	}
+	if board.Scores[0].PersonaName != "Opponent" || board.Scores[0].Wins != 1 {
+		t.Errorf("Expected Opponent on top with 1 win, got %+v", board.Scores[0])
+	}
+	if board.Scores[1].Losses != 1 {
+		t.Errorf("Expected Challenger to have 1 loss, got %+v", board.Scores[1])
+	}
+}
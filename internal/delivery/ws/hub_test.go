@@ -17,13 +17,89 @@ func newMockClient(hub *Hub, name string) *Client {
 	user := domain.NewUser(name, "#000000")
 	user.ID = uuid.New()
 	
-	return &Client{
+	c := &Client{
 		ID:   user.ID.String(),
 		User: user,
 		hub:  hub,
 		conn: nil,
 		send: make(chan []byte, 256),
 	}
+	return c
+}
+
+// withAllFeatures makes a mock client speak the current protocol with every feature
+func withAllFeatures(c *Client) *Client {
+	c.protocol.Store(&clientProtocol{version: domain.ProtocolVersion, features: allFeatures()})
+	return c
+}
+
+// reconnectMockClient creates another connection of previous's user (same user ID and persona),
+// as a resumed session or a second tab would
+func reconnectMockClient(hub *Hub, previous *Client) *Client {
+	c := newMockClient(hub, previous.User.PersonaName)
+	c.User.ID = previous.User.ID
+	c.ID = previous.ID
+	c.protocol.Store(previous.protocol.Load())
+	return c
+}
+
+// setupRoom runs hub and registers clients in order, so the first one hosts
+func setupRoom(t *testing.T, hub *Hub, clients ...*Client) {
+	t.Helper()
+	go hub.Run()
+	for i, c := range clients {
+		hub.Register(c)
+		if !waitForClients(hub, i+1) {
+			t.Fatalf("Setup failed: expected %d clients, got %d", i+1, hub.ClientCount())
+		}
+	}
+}
+
+// waitForClients polls until the hub has n users
+func waitForClients(hub *Hub, n int) bool {
+	for i := 0; i < 50; i++ {
+		if hub.ClientCount() == n {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return false
+}
+
+// drainForType reads queued messages and returns the first one of the given type
+func drainForType(c *Client, msgType domain.MessageType) (domain.Message, bool) {
+	timeout := time.After(100 * time.Millisecond)
+	for {
+		select {
+		case data, ok := <-c.send:
+			if !ok {
+				return domain.Message{}, false
+			}
+			var m domain.Message
+			if json.Unmarshal(data, &m) == nil && m.Type == msgType {
+				return m, true
+			}
+		case <-timeout:
+			return domain.Message{}, false
+		}
+	}
+}
+
+// drainAll discards everything queued for the clients
+func drainAll(clients ...*Client) {
+	for _, c := range clients {
+		for len(c.send) > 0 {
+			<-c.send
+		}
+	}
+}
+
+func allFeatures() map[domain.Feature]bool {
+	features := make(map[domain.Feature]bool)
+	for _, f := range domain.ServerFeatures() {
+		features[f] = true
+	}
+	return features
 }
 
 func TestNewHub(t *testing.T) {
@@ -385,7 +461,7 @@ func TestHub_HostReclaimOnReconnect(t *testing.T) {
 
 	// Verify initial host
 	hub.mu.RLock()
-	if hub.hostPersona != "MyPersona" || hub.hostID != originalHost.ID {
+	if hub.hostID != originalHost.ID {
 		t.Fatalf("Setup failed: expected MyPersona to be host")
 	}
 	hub.mu.RUnlock()
@@ -402,31 +478,199 @@ func TestHub_HostReclaimOnReconnect(t *testing.T) {
 	// Verify ID and Perks remain with the Disconnected Host (Sticky Host)
 	hub.mu.RLock()
 	// NOTE: Sticky Host logic means hostID does NOT change immediately
-	if hub.hostID == tempHost.ID {
+	if hub.hostID != originalHost.ID {
 		t.Errorf("Expected hostID to REMAIN with originalHost (sticky), but it transferred too early")
 	}
-	if hub.hostPersona != "MyPersona" {
-		t.Errorf("Expected hostPersona to remain 'MyPersona', got %s", hub.hostPersona)
-	}
 	hub.mu.RUnlock()
 
-	// 4. Original host reconnects (new ID, same Persona)
-	reconnectingHost := newMockClient(hub, "MyPersona") 
+	// 4. Original host reconnects (new socket, same user ID restored from the session)
+	reconnectingHost := reconnectMockClient(hub, originalHost)
 	hub.Register(reconnectingHost)
-	time.Sleep(50 * time.Millisecond)
+	time.Sleep(250 * time.Millisecond) // Past hostTransferDelay: the role must not move
 
-	// Verify reclaim
 	hub.mu.RLock()
 	finalHostID := hub.hostID
-	finalHostPersona := hub.hostPersona
 	hub.mu.RUnlock()
 
-	if finalHostID != reconnectingHost.ID {
-		t.Errorf("Expected returning host to reclaim hostID. Got %s, want %s", finalHostID, reconnectingHost.ID)
+	if finalHostID != originalHost.ID {
+		t.Errorf("Expected returning host to keep hostID. Got %s, want %s", finalHostID, originalHost.ID)
 	}
-	if finalHostPersona != "MyPersona" {
-		t.Errorf("Expected hostPersona to remain MyPersona")
+}
+
+func TestHub_SamePersonaDifferentUserDoesNotReclaimHost(t *testing.T) {
+	hub := NewHub()
+	hub.leaveDelay = 10 * time.Millisecond
+	hub.hostTransferDelay = 50 * time.Millisecond
+	go hub.Run()
+
+	host := newMockClient(hub, "MyPersona")
+	hub.Register(host)
+	other := newMockClient(hub, "OtherUser")
+	hub.Register(other)
+	waitForClients(hub, 2)
+
+	// The transfer prefers a moderator, so the only way the imposter gets host is by persona
+	hub.mu.Lock()
+	hub.moderators[other.ID] = true
+	hub.mu.Unlock()
+
+	hub.Unregister(host)
+	time.Sleep(20 * time.Millisecond)
+
+	// A colliding persona name is not the same user
+	imposter := newMockClient(hub, "MyPersona")
+	hub.Register(imposter)
+	time.Sleep(100 * time.Millisecond)
+
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	if hub.hostID == imposter.ID {
+		t.Error("Persona collision must not inherit the host role")
+	}
+	if hub.hostID == host.ID {
+		t.Error("Expected host to transfer after the original host stayed away")
 	}
 }
 
+func TestHub_MultiConn_SecondTabJoinsSilently(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	firstTab := withAllFeatures(newMockClient(hub, "TwoTabs"))
+	observer := withAllFeatures(newMockClient(hub, "Observer"))
+	hub.Register(firstTab)
+	hub.Register(observer)
+	waitForClients(hub, 2)
+	time.Sleep(30 * time.Millisecond)
+	drainAll(observer)
+
+	secondTab := reconnectMockClient(hub, firstTab)
+	hub.Register(secondTab)
 
+	// The second tab gets its own identity and snapshot, listing users not sockets
+	snapshot, ok := drainForType(secondTab, domain.MessageTypePresenceSnapshot)
+	if !ok {
+		t.Fatal("Expected presence snapshot on the second tab")
+	}
+	var payload domain.PresenceSnapshotPayload
+	json.Unmarshal(snapshot.Payload, &payload)
+	if payload.UserCount != 2 || len(payload.Users) != 2 {
+		t.Errorf("Expected 2 users in the snapshot, got %d (%d entries)", payload.UserCount, len(payload.Users))
+	}
+
+	// Nobody else hears about it: the user was already here
+	for _, msgType := range []domain.MessageType{domain.MessageTypeUserJoin, domain.MessageTypeUserDelta} {
+		if _, ok := drainForType(observer, msgType); ok {
+			t.Errorf("Observer should not receive %s for a second tab", msgType)
+		}
+	}
+	if hub.ClientCount() != 2 {
+		t.Errorf("Expected 2 users, got %d", hub.ClientCount())
+	}
+}
+
+func TestHub_MultiConn_ClosingOneTabKeepsUser(t *testing.T) {
+	hub := NewHub()
+	hub.leaveDelay = 10 * time.Millisecond
+	go hub.Run()
+
+	firstTab := newMockClient(hub, "TwoTabs")
+	observer := newMockClient(hub, "Observer")
+	hub.Register(firstTab)
+	hub.Register(observer)
+	waitForClients(hub, 2)
+	secondTab := reconnectMockClient(hub, firstTab)
+	hub.Register(secondTab)
+	time.Sleep(30 * time.Millisecond)
+	drainAll(observer)
+	drainAll(secondTab)
+
+	hub.Unregister(firstTab)
+	time.Sleep(50 * time.Millisecond) // Past the leave delay
+
+	if _, ok := drainForType(observer, domain.MessageTypeUserLeave); ok {
+		t.Error("Closing one tab must not announce a leave while another is open")
+	}
+	hub.mu.RLock()
+	_, leaving := hub.delayedLeavers[firstTab.ID]
+	representative := hub.clients[firstTab.ID]
+	hub.mu.RUnlock()
+	if leaving || representative != secondTab {
+		t.Error("Expected the remaining tab to represent the user")
+	}
+
+	// Broadcasts still reach the open tab
+	broadcastChat(hub, "masih di sini")
+	if _, ok := drainForType(secondTab, domain.MessageTypeChat); !ok {
+		t.Error("Expected the remaining tab to receive broadcasts")
+	}
+
+	// Closing the last tab is a real leave
+	hub.Unregister(secondTab)
+	if _, ok := drainForType(observer, domain.MessageTypeUserLeave); !ok {
+		t.Error("Expected user_leave once the last tab closed")
+	}
+}
+
+func TestHub_MultiConn_TargetedMessagesFanOut(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	sender := newMockClient(hub, "Sender")
+	firstTab := newMockClient(hub, "TwoTabs")
+	hub.Register(sender)
+	hub.Register(firstTab)
+	waitForClients(hub, 2)
+	secondTab := reconnectMockClient(hub, firstTab)
+	hub.Register(secondTab)
+	time.Sleep(30 * time.Millisecond)
+	drainAll(firstTab)
+	drainAll(secondTab)
+
+	hub.HandleWhisper(sender, whisperMessage(sender, firstTab.ID, "psst"))
+
+	for i, tab := range []*Client{firstTab, secondTab} {
+		if _, ok := drainForType(tab, domain.MessageTypeWhisper); !ok {
+			t.Errorf("Expected whisper on tab %d", i+1)
+		}
+	}
+}
+
+func TestHub_MultiConn_SlowConnectionIsDropped(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	fast := newMockClient(hub, "TwoTabs")
+	hub.Register(fast)
+	slow := reconnectMockClient(hub, fast)
+	slow.send = make(chan []byte, 1)
+	hub.Register(slow)
+	waitForClients(hub, 1)
+	time.Sleep(20 * time.Millisecond)
+
+	// Overflow the slow tab's buffer several times over
+	for i := 0; i < 5; i++ {
+		broadcastChat(hub, "spam")
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	hub.mu.RLock()
+	stillThere := hub.hasConn(slow)
+	conns := len(hub.conns[fast.ID])
+	representative := hub.clients[fast.ID]
+	hub.mu.RUnlock()
+
+	if stillThere {
+		t.Error("Expected the slow connection to be dropped")
+	}
+	if conns != 1 || representative != fast {
+		t.Errorf("Expected the fast tab to remain as the user's only connection, got %d", conns)
+	}
+
+	// ReadPump unregisters the dropped connection once its socket closes
+	hub.Unregister(slow)
+	broadcastChat(hub, "after")
+	if _, ok := drainForType(fast, domain.MessageTypeChat); !ok {
+		t.Error("Expected the fast tab to keep receiving")
+	}
+}
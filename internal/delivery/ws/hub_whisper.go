@@ -0,0 +1,69 @@
+package ws
+
+import (
+	"encoding/json"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+// HandleWhisper delivers a private message only to the sender and the target
+func (h *Hub) HandleWhisper(c *Client, msg domain.Message) {
+	var payload domain.WhisperPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		h.sendError(c, domain.ErrCodeInvalidPayload, "Format whisper tidak valid")
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	target, ok := h.clients[payload.ToID]
+	if !ok {
+		h.sendError(c, domain.ErrCodeUserOffline, "User tidak ditemukan atau sedang offline")
+		return
+	}
+
+	// Normalize target info from server state (client-supplied name may be stale)
+	payload.ToName = target.User.PersonaName
+	payloadBytes, _ := json.Marshal(payload)
+	msg.Payload = payloadBytes
+	msg.ToID = target.ID
+
+	frame := NewFrame(msg)
+	data := frame.Bytes(CodecJSON)
+
+	// Keep per-user history so whispers survive reconnects
+	h.addWhisperHistory(c.ID, data)
+	if target.ID != c.ID {
+		h.addWhisperHistory(target.ID, data)
+	}
+
+	// Every tab or device of both users sees the whisper
+	h.sendToUser(c, frame)
+	if target.ID != c.ID {
+		h.sendToUser(target, frame)
+	}
+}
+
+// addWhisperHistory stores a whisper in the given user's private history
+// NOTE: Caller must hold h.mu Lock
+func (h *Hub) addWhisperHistory(userID string, data []byte) {
+	rb, ok := h.whisperHistory[userID]
+	if !ok {
+		rb = NewRingBuffer(domain.MaxWhisperHistorySize)
+		h.whisperHistory[userID] = rb
+	}
+	rb.Add(data)
+}
+
+// sendWhisperHistoryToClient replays a client's private whispers
+// NOTE: Caller must hold at least RLock
+func (h *Hub) sendWhisperHistoryToClient(c *Client) {
+	rb, ok := h.whisperHistory[c.ID]
+	if !ok {
+		return
+	}
+	for _, data := range rb.GetAll() {
+		c.Send(data)
+	}
+}
@@ -0,0 +1,131 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+// === WHISPER FEATURE TESTS ===
+
+func whisperMessage(from *Client, toID, text string) domain.Message {
+	payload, _ := json.Marshal(domain.WhisperPayload{ToID: toID, Text: text})
+	return domain.Message{
+		ID:       "whisper-" + text,
+		Type:     domain.MessageTypeWhisper,
+		FromID:   from.ID,
+		FromName: from.User.PersonaName,
+		Payload:  payload,
+	}
+}
+
+func TestHub_HandleWhisper_OnlySenderAndTarget(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	sender := newMockClient(hub, "Sender")
+	target := newMockClient(hub, "Target")
+	bystander := newMockClient(hub, "Bystander")
+
+	hub.Register(sender)
+	hub.Register(target)
+	hub.Register(bystander)
+	time.Sleep(50 * time.Millisecond)
+
+	hub.HandleWhisper(sender, whisperMessage(sender, target.ID, "secret"))
+
+	if _, ok := drainForType(sender, domain.MessageTypeWhisper); !ok {
+		t.Error("Sender should receive their own whisper")
+	}
+
+	msg, ok := drainForType(target, domain.MessageTypeWhisper)
+	if !ok {
+		t.Fatal("Target should receive the whisper")
+	}
+	var payload domain.WhisperPayload
+	json.Unmarshal(msg.Payload, &payload)
+	if payload.ToName != "Target" {
+		t.Errorf("Expected to_name to be filled by server, got %q", payload.ToName)
+	}
+
+	if _, ok := drainForType(bystander, domain.MessageTypeWhisper); ok {
+		t.Error("Bystander must not receive the whisper")
+	}
+}
+
+func TestHub_HandleWhisper_NotInSharedHistory(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	sender := newMockClient(hub, "Sender")
+	target := newMockClient(hub, "Target")
+	hub.Register(sender)
+	hub.Register(target)
+	time.Sleep(50 * time.Millisecond)
+
+	hub.HandleWhisper(sender, whisperMessage(sender, target.ID, "secret"))
+	time.Sleep(20 * time.Millisecond)
+
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	for _, data := range hub.messageHistory.GetAll() {
+		var m domain.Message
+		if json.Unmarshal(data, &m) == nil && m.Type == domain.MessageTypeWhisper {
+			t.Fatal("Whisper must not be stored in the shared room history")
+		}
+	}
+}
+
+func TestHub_HandleWhisper_TargetOffline(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	sender := withAllFeatures(newMockClient(hub, "Sender"))
+	hub.Register(sender)
+	time.Sleep(30 * time.Millisecond)
+
+	hub.HandleWhisper(sender, whisperMessage(sender, "missing-id", "hello?"))
+
+	msg, ok := drainForType(sender, domain.MessageTypeError)
+	if !ok {
+		t.Fatal("Expected error frame when target is offline")
+	}
+	var payload domain.ErrorPayload
+	json.Unmarshal(msg.Payload, &payload)
+	if payload.Code != domain.ErrCodeUserOffline {
+		t.Errorf("Expected code %q, got %q", domain.ErrCodeUserOffline, payload.Code)
+	}
+}
+
+func TestHub_HandleWhisper_ReplayedOnReconnect(t *testing.T) {
+	hub := NewHub()
+	hub.leaveDelay = 200 * time.Millisecond
+	go hub.Run()
+
+	sender := newMockClient(hub, "Sender")
+	target := newMockClient(hub, "Target")
+	hub.Register(sender)
+	hub.Register(target)
+	time.Sleep(50 * time.Millisecond)
+
+	hub.HandleWhisper(sender, whisperMessage(sender, target.ID, "remember me"))
+
+	// Target refreshes the page (same user ID, new connection)
+	hub.Unregister(target)
+	time.Sleep(20 * time.Millisecond)
+	reconnected := reconnectMockClient(hub, target)
+	hub.Register(reconnected)
+
+	if _, ok := drainForType(reconnected, domain.MessageTypeWhisper); !ok {
+		t.Error("Reconnected target should receive whisper history")
+	}
+
+	// A different user who happens to get the same persona name sees nothing
+	lookalike := newMockClient(hub, "Target")
+	hub.Register(lookalike)
+	if _, ok := drainForType(lookalike, domain.MessageTypeWhisper); ok {
+		t.Error("Whisper history must not leak to a colliding persona")
+	}
+}
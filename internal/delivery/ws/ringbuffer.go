@@ -2,11 +2,13 @@ package ws
 
 // RingBuffer is a fixed-size circular buffer for storing message history
 // It provides O(1) append and efficient memory usage
+// Entries are numbered 1, 2, 3... in insertion order, so readers can resume by sequence
 type RingBuffer struct {
-	data  [][]byte
-	head  int  // next write position
-	size  int  // current number of elements
-	cap   int  // maximum capacity
+	data    [][]byte
+	head    int    // next write position
+	size    int    // current number of elements
+	cap     int    // maximum capacity
+	lastSeq uint64 // sequence of the newest element (0 = nothing added yet)
 }
 
 // NewRingBuffer creates a new ring buffer with the given capacity
@@ -20,6 +22,7 @@ func NewRingBuffer(capacity int) *RingBuffer {
 }
 
 // Add appends a message to the buffer, overwriting oldest if full
+// The message gets sequence NextSeq()
 func (rb *RingBuffer) Add(msg []byte) {
 	// Copy message to avoid external modification
 	copied := make([]byte, len(msg))
@@ -31,6 +34,30 @@ func (rb *RingBuffer) Add(msg []byte) {
 	if rb.size < rb.cap {
 		rb.size++
 	}
+	rb.lastSeq++
+}
+
+// NextSeq returns the sequence the next added message will get
+func (rb *RingBuffer) NextSeq() uint64 {
+	return rb.lastSeq + 1
+}
+
+// LastSeq returns the sequence of the newest message (0 if none was ever added)
+func (rb *RingBuffer) LastSeq() uint64 {
+	return rb.lastSeq
+}
+
+// Since returns the messages added after seq, oldest first
+// complete is false when some of them were already evicted (or seq is from the future,
+// e.g. a previous room with the same code); all retained messages are returned then
+func (rb *RingBuffer) Since(seq uint64) (msgs [][]byte, complete bool) {
+	oldest := rb.OldestSeq()
+	if seq > rb.lastSeq || seq+1 < oldest {
+		return rb.GetAll(), false
+	}
+
+	all := rb.GetAll()
+	return all[uint64(len(all))-(rb.lastSeq-seq):], true
 }
 
 // GetAll returns all messages in chronological order (oldest first)
@@ -59,7 +86,36 @@ func (rb *RingBuffer) Len() int {
 	return rb.size
 }
 
+// Before returns up to limit messages older than seq (0 = newest), oldest first
+// first is the sequence of msgs[0]; more reports whether even older messages are retained
+func (rb *RingBuffer) Before(seq uint64, limit int) (msgs [][]byte, first uint64, more bool) {
+	oldest := rb.OldestSeq()
+	if rb.size == 0 || limit <= 0 {
+		return nil, 0, false
+	}
+	if seq == 0 || seq > rb.lastSeq+1 {
+		seq = rb.lastSeq + 1
+	}
+	if seq <= oldest {
+		return nil, 0, false
+	}
+
+	all := rb.GetAll()
+	end := int(seq - oldest) // messages before seq are all[:end]
+	start := end - limit
+	if start < 0 {
+		start = 0
+	}
+	return all[start:end], oldest + uint64(start), start > 0
+}
+
+// OldestSeq returns the sequence of the oldest retained message (LastSeq()+1 when empty)
+func (rb *RingBuffer) OldestSeq() uint64 {
+	return rb.lastSeq - uint64(rb.size) + 1
+}
+
 // Clear removes all elements from the buffer
+// Sequences keep counting, so readers behind the clear see a gap
 func (rb *RingBuffer) Clear() {
 	rb.head = 0
 	rb.size = 0
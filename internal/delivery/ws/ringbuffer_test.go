@@ -94,3 +94,111 @@ func TestRingBuffer_Empty(t *testing.T) {
 		t.Errorf("Expected nil from empty buffer, got %v", all)
 	}
 }
+
+func TestRingBuffer_Seq(t *testing.T) {
+	rb := NewRingBuffer(3)
+
+	if rb.NextSeq() != 1 || rb.LastSeq() != 0 {
+		t.Fatalf("Expected fresh buffer at seq 0, got next=%d last=%d", rb.NextSeq(), rb.LastSeq())
+	}
+
+	rb.Add([]byte("msg1"))
+	rb.Add([]byte("msg2"))
+
+	if rb.LastSeq() != 2 || rb.NextSeq() != 3 {
+		t.Errorf("Expected last=2 next=3, got last=%d next=%d", rb.LastSeq(), rb.NextSeq())
+	}
+}
+
+func TestRingBuffer_Since(t *testing.T) {
+	rb := NewRingBuffer(3)
+	for _, m := range []string{"msg1", "msg2", "msg3", "msg4", "msg5"} {
+		rb.Add([]byte(m))
+	}
+	// Retained: msg3 (seq 3), msg4, msg5
+
+	tests := []struct {
+		name     string
+		seq      uint64
+		expected []string
+		complete bool
+	}{
+		{"up to date", 5, nil, true},
+		{"missed one", 4, []string{"msg5"}, true},
+		{"missed all retained", 2, []string{"msg3", "msg4", "msg5"}, true},
+		{"gap evicted", 1, []string{"msg3", "msg4", "msg5"}, false},
+		{"seq from the future", 9, []string{"msg3", "msg4", "msg5"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgs, complete := rb.Since(tt.seq)
+			if complete != tt.complete {
+				t.Errorf("Expected complete=%v, got %v", tt.complete, complete)
+			}
+			if len(msgs) != len(tt.expected) {
+				t.Fatalf("Expected %d messages, got %d", len(tt.expected), len(msgs))
+			}
+			for i, exp := range tt.expected {
+				if !bytes.Equal(msgs[i], []byte(exp)) {
+					t.Errorf("Position %d: expected %s, got %s", i, exp, msgs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRingBuffer_SinceAfterClear(t *testing.T) {
+	rb := NewRingBuffer(5)
+	rb.Add([]byte("msg1"))
+	rb.Add([]byte("msg2"))
+	rb.Clear()
+
+	if _, complete := rb.Since(2); !complete {
+		t.Error("Client that saw everything should be complete after clear")
+	}
+	if _, complete := rb.Since(1); complete {
+		t.Error("Cleared messages should count as a gap")
+	}
+}
+
+func TestRingBuffer_Before(t *testing.T) {
+	rb := NewRingBuffer(4)
+	for _, m := range []string{"msg1", "msg2", "msg3", "msg4", "msg5", "msg6"} {
+		rb.Add([]byte(m))
+	}
+	// Retained: msg3 (seq 3) .. msg6 (seq 6)
+
+	tests := []struct {
+		name     string
+		seq      uint64
+		limit    int
+		expected []string
+		first    uint64
+		more     bool
+	}{
+		{"newest page", 0, 2, []string{"msg5", "msg6"}, 5, true},
+		{"older page", 5, 2, []string{"msg3", "msg4"}, 3, false},
+		{"limit past oldest", 5, 10, []string{"msg3", "msg4"}, 3, false},
+		{"cursor at oldest", 3, 2, nil, 0, false},
+		{"cursor evicted", 2, 2, nil, 0, false},
+		{"cursor from the future", 99, 1, []string{"msg6"}, 6, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgs, first, more := rb.Before(tt.seq, tt.limit)
+			if first != tt.first || more != tt.more {
+				t.Errorf("Expected first=%d more=%v, got first=%d more=%v", tt.first, tt.more, first, more)
+			}
+			if len(msgs) != len(tt.expected) {
+				t.Fatalf("Expected %d messages, got %d", len(tt.expected), len(msgs))
+			}
+			for i, exp := range tt.expected {
+				if !bytes.Equal(msgs[i], []byte(exp)) {
+					t.Errorf("Position %d: expected %s, got %s", i, exp, msgs[i])
+				}
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"sync"
+	"time"
 )
 
 // Room represents a chat room with its own hub
@@ -11,6 +12,20 @@ type Room struct {
 	Code string // 12-character unique code
 	Name string // User-defined room name
 	Hub  *Hub   // Each room has its own hub
+
+	accessMu sync.Mutex
+	passSalt []byte
+	passHash []byte               // nil = open room
+	invites  map[string]*Invite   // token -> invite
+	tickets  map[string]time.Time // join ticket -> expiry
+}
+
+// RoomOptions configures a room at creation
+type RoomOptions struct {
+	Passphrase   string      // Optional; when set, joining requires the passphrase or an invite
+	MaxOccupancy int         // Optional; 0 = unlimited
+	KnockToEnter bool        // New clients wait for host approval
+	Hub          []HubOption // Optional; applied after the manager's defaults
 }
 
 // RoomManager manages all active rooms
@@ -18,12 +33,15 @@ type RoomManager struct {
 	mu       sync.RWMutex
 	rooms    map[string]*Room // map[code]*Room
 	releaser PersonaReleaser
+	hubOpts  []HubOption // Limits and timings for every room's hub
 }
 
 // NewRoomManager creates a new room manager
-func NewRoomManager() *RoomManager {
+// hubOpts apply to every room; a room's own RoomOptions.Hub can override them
+func NewRoomManager(hubOpts ...HubOption) *RoomManager {
 	return &RoomManager{
-		rooms: make(map[string]*Room),
+		rooms:   make(map[string]*Room),
+		hubOpts: hubOpts,
 	}
 }
 
@@ -39,8 +57,21 @@ func GenerateRoomCode() string {
 	return hex.EncodeToString(bytes)
 }
 
-// CreateRoom creates a new room with the given name
+// CreateRoom creates a new open room with the given name
 func (rm *RoomManager) CreateRoom(name string) *Room {
+	return rm.CreateRoomWithOptions(name, RoomOptions{})
+}
+
+// CreateRoomWithOptions creates a new room with the given name and options
+func (rm *RoomManager) CreateRoomWithOptions(name string, opts RoomOptions) *Room {
+	room := &Room{
+		Name:    name,
+		invites: make(map[string]*Invite),
+		tickets: make(map[string]time.Time),
+	}
+	// Hash outside the manager lock (PBKDF2 is deliberately slow)
+	room.setPassphrase(opts.Passphrase)
+
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 
@@ -50,23 +81,27 @@ func (rm *RoomManager) CreateRoom(name string) *Room {
 		code = GenerateRoomCode()
 	}
 
-	hub := NewHub()
-	hub.SetPersonaReleaser(rm.releaser)
-	hub.roomManager = rm
-	hub.roomCode = code
-
-	room := &Room{
-		Code: code,
-		Name: name,
-		Hub:  hub,
-	}
+	room.Code = code
+	room.Hub = rm.newHub(code, opts)
 
 	rm.rooms[code] = room
-	go hub.Run()
+	go room.Hub.Run()
 
 	return room
 }
 
+// newHub builds a room's hub from the manager's defaults and the room's options
+func (rm *RoomManager) newHub(code string, opts RoomOptions) *Hub {
+	hubOpts := append(append([]HubOption{}, rm.hubOpts...), opts.Hub...)
+	hub := NewHub(hubOpts...)
+	hub.SetPersonaReleaser(rm.releaser)
+	hub.roomManager = rm
+	hub.roomCode = code
+	hub.maxOccupancy = opts.MaxOccupancy
+	hub.knockToEnter = opts.KnockToEnter
+	return hub
+}
+
 // GetRoom returns a room by its code
 func (rm *RoomManager) GetRoom(code string) *Room {
 	rm.mu.RLock()
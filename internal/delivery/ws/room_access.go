@@ -0,0 +1,162 @@
+package ws
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"time"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+const (
+	// passphraseIterations is the PBKDF2 work factor for room passphrases
+	passphraseIterations = 100000
+
+	// passphraseKeyLength is the derived key length in bytes
+	passphraseKeyLength = 32
+)
+
+// Invite is a host-minted room invite
+type Invite struct {
+	Token     string
+	SingleUse bool
+	ExpiresAt time.Time
+}
+
+// randomToken returns a hex-encoded random token of n bytes
+func randomToken(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// hashPassphrase derives a salted hash of the passphrase
+func hashPassphrase(passphrase string, salt []byte) []byte {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, passphraseIterations, passphraseKeyLength)
+	if err != nil {
+		return nil
+	}
+	return key
+}
+
+// setPassphrase stores a salted hash of the passphrase (empty clears protection)
+func (r *Room) setPassphrase(passphrase string) {
+	r.accessMu.Lock()
+	defer r.accessMu.Unlock()
+
+	if passphrase == "" {
+		r.passSalt = nil
+		r.passHash = nil
+		return
+	}
+
+	salt := make([]byte, 16)
+	rand.Read(salt)
+	r.passSalt = salt
+	r.passHash = hashPassphrase(passphrase, salt)
+}
+
+// IsProtected reports whether joining requires a passphrase or invite
+func (r *Room) IsProtected() bool {
+	r.accessMu.Lock()
+	defer r.accessMu.Unlock()
+	return r.passHash != nil
+}
+
+// CheckPassphrase verifies a passphrase against the stored hash
+func (r *Room) CheckPassphrase(passphrase string) bool {
+	r.accessMu.Lock()
+	salt, expected := r.passSalt, r.passHash
+	r.accessMu.Unlock()
+
+	if expected == nil || passphrase == "" || len(passphrase) > domain.MaxPassphraseLength {
+		return false
+	}
+	return subtle.ConstantTimeCompare(hashPassphrase(passphrase, salt), expected) == 1
+}
+
+// CreateInvite mints an invite token; ttl <= 0 uses the default lifetime
+func (r *Room) CreateInvite(singleUse bool, ttl time.Duration) *Invite {
+	if ttl <= 0 {
+		ttl = domain.InviteDefaultTTL
+	}
+	if ttl > domain.InviteMaxTTL {
+		ttl = domain.InviteMaxTTL
+	}
+
+	invite := &Invite{
+		Token:     randomToken(16),
+		SingleUse: singleUse,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	r.accessMu.Lock()
+	defer r.accessMu.Unlock()
+
+	r.pruneAccessLocked()
+	r.invites[invite.Token] = invite
+	return invite
+}
+
+// ConsumeInvite validates an invite, burning it if single-use
+func (r *Room) ConsumeInvite(token string) bool {
+	r.accessMu.Lock()
+	defer r.accessMu.Unlock()
+
+	invite, ok := r.invites[token]
+	if !ok {
+		return false
+	}
+	if time.Now().After(invite.ExpiresAt) {
+		delete(r.invites, token)
+		return false
+	}
+	if invite.SingleUse {
+		delete(r.invites, token)
+	}
+	return true
+}
+
+// IssueTicket returns a short-lived single-use proof that the holder passed the access check
+func (r *Room) IssueTicket() string {
+	ticket := randomToken(16)
+
+	r.accessMu.Lock()
+	defer r.accessMu.Unlock()
+
+	r.pruneAccessLocked()
+	r.tickets[ticket] = time.Now().Add(domain.JoinTicketTTL)
+	return ticket
+}
+
+// ConsumeTicket validates and burns a join ticket
+func (r *Room) ConsumeTicket(ticket string) bool {
+	r.accessMu.Lock()
+	defer r.accessMu.Unlock()
+
+	expiresAt, ok := r.tickets[ticket]
+	if !ok {
+		return false
+	}
+	delete(r.tickets, ticket)
+	return time.Now().Before(expiresAt)
+}
+
+// pruneAccessLocked drops expired invites and tickets
+// NOTE: Caller must hold r.accessMu
+func (r *Room) pruneAccessLocked() {
+	now := time.Now()
+	for token, invite := range r.invites {
+		if now.After(invite.ExpiresAt) {
+			delete(r.invites, token)
+		}
+	}
+	for ticket, expiresAt := range r.tickets {
+		if now.After(expiresAt) {
+			delete(r.tickets, ticket)
+		}
+	}
+}
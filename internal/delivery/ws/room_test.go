@@ -2,6 +2,9 @@ package ws
 
 import (
 	"testing"
+	"time"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
 )
 
 func TestRoomManager_CreateRoom(t *testing.T) {
@@ -80,3 +83,99 @@ func TestRoomManager_Concurrency(t *testing.T) {
 		<-done
 	}
 }
+
+func TestRoom_Passphrase(t *testing.T) {
+	rm := NewRoomManager()
+
+	open := rm.CreateRoom("Open")
+	if open.IsProtected() {
+		t.Error("Expected room without passphrase to be open")
+	}
+
+	room := rm.CreateRoomWithOptions("Secret", RoomOptions{Passphrase: "opensesame"})
+	if !room.IsProtected() {
+		t.Fatal("Expected room with passphrase to be protected")
+	}
+	if !room.CheckPassphrase("opensesame") {
+		t.Error("Expected correct passphrase to be accepted")
+	}
+	if room.CheckPassphrase("wrong") || room.CheckPassphrase("") {
+		t.Error("Expected wrong passphrase to be rejected")
+	}
+}
+
+func TestRoom_Invites(t *testing.T) {
+	rm := NewRoomManager()
+	room := rm.CreateRoomWithOptions("Secret", RoomOptions{Passphrase: "opensesame"})
+
+	single := room.CreateInvite(true, 0)
+	if !room.ConsumeInvite(single.Token) {
+		t.Error("Expected single-use invite to be accepted once")
+	}
+	if room.ConsumeInvite(single.Token) {
+		t.Error("Expected single-use invite to be burned")
+	}
+
+	multi := room.CreateInvite(false, time.Hour)
+	if !room.ConsumeInvite(multi.Token) || !room.ConsumeInvite(multi.Token) {
+		t.Error("Expected multi-use invite to be reusable")
+	}
+
+	expired := room.CreateInvite(false, time.Hour)
+	room.accessMu.Lock()
+	room.invites[expired.Token].ExpiresAt = time.Now().Add(-time.Second)
+	room.accessMu.Unlock()
+	if room.ConsumeInvite(expired.Token) {
+		t.Error("Expected expired invite to be rejected")
+	}
+
+	if room.ConsumeInvite("bogus") {
+		t.Error("Expected unknown invite to be rejected")
+	}
+}
+
+func TestRoom_Tickets(t *testing.T) {
+	rm := NewRoomManager()
+	room := rm.CreateRoomWithOptions("Secret", RoomOptions{Passphrase: "opensesame"})
+
+	ticket := room.IssueTicket()
+	if !room.ConsumeTicket(ticket) {
+		t.Error("Expected fresh ticket to be accepted")
+	}
+	if room.ConsumeTicket(ticket) {
+		t.Error("Expected ticket to be single-use")
+	}
+}
+
+func TestRoomManager_HubOptions(t *testing.T) {
+	rm := NewRoomManager(WithMaxHistorySize(5), WithMaxMessageSize(1024), WithShutdownGracePeriod(time.Second))
+
+	room := rm.CreateRoom("Defaults")
+	if room.Hub.messageHistory.cap != 5 {
+		t.Errorf("Expected history capacity 5, got %d", room.Hub.messageHistory.cap)
+	}
+	if room.Hub.maxMessageSize != 1024 {
+		t.Errorf("Expected max message size 1024, got %d", room.Hub.maxMessageSize)
+	}
+	if room.Hub.shutdownGrace != time.Second {
+		t.Errorf("Expected shutdown grace 1s, got %v", room.Hub.shutdownGrace)
+	}
+
+	// Per-room options win over the manager's defaults
+	custom := rm.CreateRoomWithOptions("Custom", RoomOptions{Hub: []HubOption{WithMaxHistorySize(20)}})
+	if custom.Hub.messageHistory.cap != 20 {
+		t.Errorf("Expected history capacity 20, got %d", custom.Hub.messageHistory.cap)
+	}
+	if custom.Hub.maxMessageSize != 1024 {
+		t.Errorf("Expected inherited max message size 1024, got %d", custom.Hub.maxMessageSize)
+	}
+
+	// Invalid values keep the domain defaults
+	hub := NewHub(WithMaxHistorySize(0), WithMaxMessageSize(-1))
+	if hub.messageHistory.cap != domain.MaxHistorySize {
+		t.Errorf("Expected default history capacity, got %d", hub.messageHistory.cap)
+	}
+	if hub.maxMessageSize != domain.MaxMessageSize {
+		t.Errorf("Expected default max message size, got %d", hub.maxMessageSize)
+	}
+}
@@ -0,0 +1,80 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+// Prefixes marking credentials offered in Sec-WebSocket-Protocol
+// They are never selected, so nothing secret is echoed back in the handshake response
+const (
+	SubprotocolTokenPrefix  = "goat.token."  // Session token, to resume a persona
+	SubprotocolTicketPrefix = "goat.ticket." // Join ticket for a protected room
+	SubprotocolInvitePrefix = "goat.invite." // Invite token for a protected room
+)
+
+var errAuthFrameRequired = errors.New("first frame must be auth")
+
+// Credentials are what a connecting client presents to resume or to enter a protected room
+// None of them is accepted in the URL, where proxies and access logs would keep them
+type Credentials struct {
+	Token  string
+	Ticket string
+	Invite string
+}
+
+// CredentialsFromSubprotocols returns the credentials offered alongside the codec subprotocols
+func CredentialsFromSubprotocols(protocols []string) Credentials {
+	var creds Credentials
+	for _, p := range protocols {
+		switch {
+		case strings.HasPrefix(p, SubprotocolTokenPrefix):
+			creds.Token = strings.TrimPrefix(p, SubprotocolTokenPrefix)
+		case strings.HasPrefix(p, SubprotocolTicketPrefix):
+			creds.Ticket = strings.TrimPrefix(p, SubprotocolTicketPrefix)
+		case strings.HasPrefix(p, SubprotocolInvitePrefix):
+			creds.Invite = strings.TrimPrefix(p, SubprotocolInvitePrefix)
+		}
+	}
+	return creds
+}
+
+// ReadAuthFrame reads a connection's first frame, which must be an auth message
+// Returns the credentials it carries (no token asks for a fresh persona)
+// maxSize is the room's inbound frame limit
+func ReadAuthFrame(conn *websocket.Conn, maxSize int64, timeout time.Duration) (Credentials, error) {
+	conn.SetReadLimit(maxSize)
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	messageType, message, err := conn.ReadMessage()
+	if err != nil {
+		return Credentials{}, err
+	}
+	if messageType == websocket.BinaryMessage {
+		if codecForSubprotocol(conn.Subprotocol()) != CodecMsgpack {
+			return Credentials{}, errAuthFrameRequired
+		}
+		if message, err = msgpackToJSON(message); err != nil {
+			return Credentials{}, errAuthFrameRequired
+		}
+	}
+
+	var incoming struct {
+		Type    domain.MessageType `json:"type"`
+		Payload domain.AuthPayload `json:"payload"`
+	}
+	if err := json.Unmarshal(message, &incoming); err != nil || incoming.Type != domain.MessageTypeAuth {
+		return Credentials{}, errAuthFrameRequired
+	}
+	return Credentials{
+		Token:  incoming.Payload.Token,
+		Ticket: incoming.Payload.Ticket,
+		Invite: incoming.Payload.Invite,
+	}, nil
+}
@@ -0,0 +1,160 @@
+package ws
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
+
+// snapshotAAD binds sealed snapshots to this format, so other AES-GCM blobs under the same key don't open
+var snapshotAAD = []byte("goat-chat/session-snapshot/v1")
+
+// ErrSnapshotStale is returned when a snapshot is older than SessionSnapshotMaxAge
+var ErrSnapshotStale = errors.New("session snapshot is stale")
+
+// sessionSnapshot is the plaintext inside a sealed snapshot file
+type sessionSnapshot struct {
+	SealedAt time.Time      `json:"sealed_at"`
+	Sessions []SessionToken `json:"sessions"`
+}
+
+// SealedSessionStore is an in-memory SessionStore that survives restarts
+// Close seals every live session with AES-256-GCM and writes it to disk;
+// Restore reads it back on startup and deletes the file. The key never touches disk.
+type SealedSessionStore struct {
+	*MemorySessionStore
+	path   string
+	aead   cipher.AEAD
+	maxAge time.Duration
+}
+
+// NewSealedSessionStore creates a sealed store writing to path
+// key is 32 bytes encoded as hex or base64 (e.g. `openssl rand -base64 32`)
+func NewSealedSessionStore(path, key string, opts ...SessionStoreOption) (*SealedSessionStore, error) {
+	raw, err := decodeSnapshotKey(key)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SealedSessionStore{
+		MemorySessionStore: NewSessionStore(opts...),
+		path:               path,
+		aead:               aead,
+		maxAge:             domain.SessionSnapshotMaxAge,
+	}, nil
+}
+
+// decodeSnapshotKey accepts a 32-byte key as hex or base64
+func decodeSnapshotKey(key string) ([]byte, error) {
+	if raw, err := hex.DecodeString(key); err == nil && len(raw) == 32 {
+		return raw, nil
+	}
+	if raw, err := base64.StdEncoding.DecodeString(key); err == nil && len(raw) == 32 {
+		return raw, nil
+	}
+	if raw, err := base64.RawURLEncoding.DecodeString(key); err == nil && len(raw) == 32 {
+		return raw, nil
+	}
+	return nil, errors.New("session snapshot key must be 32 bytes, hex or base64 encoded")
+}
+
+// Restore loads sessions from the snapshot file and deletes it
+// The file is removed even when it cannot be opened, so a bad snapshot is never retried.
+// A missing file is not an error.
+func (s *SealedSessionStore) Restore() (int, error) {
+	sealed, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := os.Remove(s.path); err != nil {
+		return 0, err
+	}
+
+	snapshot, err := s.open(sealed)
+	if err != nil {
+		return 0, err
+	}
+	if time.Since(snapshot.SealedAt) > s.maxAge {
+		return 0, ErrSnapshotStale
+	}
+	return s.load(snapshot.Sessions), nil
+}
+
+// Seal writes every live session to the snapshot file
+// The file is written next to its destination and renamed, so a crash never leaves half a snapshot.
+func (s *SealedSessionStore) Seal() error {
+	plaintext, err := json.Marshal(sessionSnapshot{
+		SealedAt: time.Now(),
+		Sessions: s.sessions(),
+	})
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := s.aead.Seal(nonce, nonce, plaintext, snapshotAAD)
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".sessions-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(sealed); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// Close stops the cleanup goroutine and seals the sessions to disk
+func (s *SealedSessionStore) Close() error {
+	s.MemorySessionStore.Close()
+	return s.Seal()
+}
+
+// open decrypts and decodes a sealed snapshot
+func (s *SealedSessionStore) open(sealed []byte) (*sessionSnapshot, error) {
+	nonceSize := s.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, errors.New("session snapshot is truncated")
+	}
+	plaintext, err := s.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], snapshotAAD)
+	if err != nil {
+		return nil, fmt.Errorf("session snapshot cannot be opened: %w", err)
+	}
+
+	var snapshot sessionSnapshot
+	if err := json.Unmarshal(plaintext, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
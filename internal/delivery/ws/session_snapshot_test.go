@@ -0,0 +1,124 @@
+package ws
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSnapshotKey = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+func newTestSealedStore(t *testing.T, path, key string) *SealedSessionStore {
+	t.Helper()
+	store, err := NewSealedSessionStore(path, key)
+	if err != nil {
+		t.Fatalf("NewSealedSessionStore: %v", err)
+	}
+	return store
+}
+
+func TestSealedSessionStore_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.sealed")
+
+	before := newTestSealedStore(t, path, testSnapshotKey)
+	token := before.GenerateTokenInLineage("user1", "CoolGoat", "#FF0000", "ROOM1", "lineage-1")
+	if err := before.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	sealed, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected snapshot file: %v", err)
+	}
+	if strings.Contains(string(sealed), "CoolGoat") || strings.Contains(string(sealed), token) {
+		t.Fatal("Snapshot must not contain plaintext session data")
+	}
+
+	after := newTestSealedStore(t, path, testSnapshotKey)
+	defer after.MemorySessionStore.Close()
+
+	restored, err := after.Restore()
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if restored != 1 {
+		t.Errorf("Expected 1 restored session, got %d", restored)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected snapshot file to be deleted after restore")
+	}
+
+	session, ok := after.ConsumeToken(token)
+	if !ok {
+		t.Fatal("Expected restored token to resume")
+	}
+	if session.PersonaName != "CoolGoat" || session.Lineage != "lineage-1" || session.UserID != "user1" {
+		t.Errorf("Restored session mismatch: %+v", session)
+	}
+}
+
+func TestSealedSessionStore_WrongKeyDiscardsSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.sealed")
+
+	before := newTestSealedStore(t, path, testSnapshotKey)
+	before.GenerateToken("user1", "CoolGoat", "#FF0000", "ROOM1")
+	if err := before.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	otherKey := strings.Repeat("ab", 32)
+	after := newTestSealedStore(t, path, otherKey)
+	defer after.MemorySessionStore.Close()
+
+	if _, err := after.Restore(); err == nil {
+		t.Fatal("Expected restore with the wrong key to fail")
+	}
+	if after.Count() != 0 {
+		t.Errorf("Expected no sessions, got %d", after.Count())
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected unreadable snapshot to be deleted")
+	}
+}
+
+func TestSealedSessionStore_StaleSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.sealed")
+
+	before := newTestSealedStore(t, path, testSnapshotKey)
+	before.GenerateToken("user1", "CoolGoat", "#FF0000", "ROOM1")
+	if err := before.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	after := newTestSealedStore(t, path, testSnapshotKey)
+	defer after.MemorySessionStore.Close()
+	after.maxAge = -time.Second
+
+	if _, err := after.Restore(); !errors.Is(err, ErrSnapshotStale) {
+		t.Fatalf("Expected ErrSnapshotStale, got %v", err)
+	}
+	if after.Count() != 0 {
+		t.Errorf("Expected no sessions, got %d", after.Count())
+	}
+}
+
+func TestSealedSessionStore_MissingSnapshot(t *testing.T) {
+	store := newTestSealedStore(t, filepath.Join(t.TempDir(), "sessions.sealed"), testSnapshotKey)
+	defer store.MemorySessionStore.Close()
+
+	restored, err := store.Restore()
+	if err != nil || restored != 0 {
+		t.Errorf("Expected nothing restored without error, got %d, %v", restored, err)
+	}
+}
+
+func TestNewSealedSessionStore_RejectsBadKey(t *testing.T) {
+	for _, key := range []string{"", "short", strings.Repeat("ab", 16)} {
+		if _, err := NewSealedSessionStore("unused", key); err == nil {
+			t.Errorf("Expected key %q to be rejected", key)
+		}
+	}
+}
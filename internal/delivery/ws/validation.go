@@ -1,10 +1,24 @@
 package ws
 
-import "regexp"
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
 
 // youtubeVideoIDRegex matches valid YouTube video IDs (11 characters, alphanumeric + - and _)
 var youtubeVideoIDRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{11}$`)
 
+// hexColorRegex matches #RGB and #RRGGBB colors
+var hexColorRegex = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
 // IsValidYouTubeVideoID validates a YouTube video ID format
 // YouTube video IDs are exactly 11 characters containing alphanumeric, - and _
 func IsValidYouTubeVideoID(videoID string) bool {
@@ -13,3 +27,539 @@ func IsValidYouTubeVideoID(videoID string) bool {
 	}
 	return youtubeVideoIDRegex.MatchString(videoID)
 }
+
+// errUnknownMessageType marks inbound types the server does not accept from clients
+var errUnknownMessageType = errors.New("unknown message type")
+
+// payloadValidator checks an inbound payload and returns a normalized copy
+type payloadValidator func(raw json.RawMessage) (json.RawMessage, error)
+
+// payloadValidators holds one validator per client-sendable MessageType
+// Types missing here (server-only frames like identity, user_join, host_change) are dropped
+var payloadValidators = map[domain.MessageType]payloadValidator{
+	// Relayed as-is after validation
+	domain.MessageTypeChat:         validateChat,
+	domain.MessageTypeVibrate:      validateVibrate,
+	domain.MessageTypeChaos:        validateChaos,
+	domain.MessageTypeReaction:     validateReaction,
+	domain.MessageTypeStatusUpdate: validateStatusUpdate,
+	domain.MessageTypeSpin:         validateSpin,
+	domain.MessageTypeGif:          validateGif,
+	domain.MessageTypeTyping:       validateTyping,
+	domain.MessageTypeConfetti:     validateConfetti,
+	domain.MessageTypeYoutube:      validateYoutube,
+	domain.MessageTypeTts:          validateTts,
+
+	// Handled server-side; handlers do their own semantic checks
+	domain.MessageTypeWhisper:         validateWhisper,
+	domain.MessageTypeFlip:            validateFlip,
+	domain.MessageTypeMusic:           validateMusic,
+	domain.MessageTypeNobar:           validateNobar,
+	domain.MessageTypeDice:            validateDice,
+	domain.MessageTypeTod:             validateTod,
+	domain.MessageTypePoll:            validatePoll,
+	domain.MessageTypeVote:            validateVote,
+	domain.MessageTypePollClose:       validatePollClose,
+	domain.MessageTypeSuit:            validateSuit,
+	domain.MessageTypeSuitScoreboard:  objectPayload,
+	domain.MessageTypeScoreboard:      objectPayload,
+	domain.MessageTypeKick:            idPayload,
+	domain.MessageTypeTransfer:        idPayload,
+	domain.MessageTypeMusicApprove:    idPayload,
+	domain.MessageTypeMusicReject:     idPayload,
+	domain.MessageTypePartyChange:     idPayload,
+	domain.MessageTypeInvite:          objectPayload,
+	domain.MessageTypeAdmitApprove:    validateAdmit,
+	domain.MessageTypeAdmitDeny:       validateAdmit,
+	domain.MessageTypeBan:             validateBan,
+	domain.MessageTypeUnban:           validateBan,
+	domain.MessageTypeBanList:         objectPayload,
+	domain.MessageTypeMute:            validateModeration(domain.MuteMaxDuration),
+	domain.MessageTypeTimeout:         validateModeration(domain.TimeoutMaxDuration),
+	domain.MessageTypeUnmute:          validateModeration(0),
+	domain.MessageTypeGrantModerator:  idPayload,
+	domain.MessageTypeRevokeModerator: idPayload,
+	domain.MessageTypePresenceResync:  objectPayload,
+	domain.MessageTypeHistoryRequest:  validateHistoryRequest,
+	domain.MessageTypeLogout:          objectPayload,
+}
+
+// gifHosts are the hosts GIF URLs may point at (subdomains included)
+var gifHosts = []string{"giphy.com"}
+
+// youtubeHosts are the hosts YouTube links may point at (subdomains included)
+var youtubeHosts = []string{"youtube.com", "youtu.be"}
+
+// validatePayload runs the validator for a message type
+// Returns errUnknownMessageType for types clients may not send
+func validatePayload(msgType domain.MessageType, raw json.RawMessage) (json.RawMessage, error) {
+	validate, ok := payloadValidators[msgType]
+	if !ok {
+		return nil, errUnknownMessageType
+	}
+	return validate(raw)
+}
+
+// decodePayload unmarshals a payload, treating a missing payload as empty
+func decodePayload(raw json.RawMessage, v interface{}) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return errors.New("format payload tidak valid")
+	}
+	return nil
+}
+
+// encodePayload marshals a normalized payload (drops unknown fields)
+func encodePayload(v interface{}) (json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.New("format payload tidak valid")
+	}
+	return data, nil
+}
+
+// objectPayload accepts an empty payload or any JSON object
+func objectPayload(raw json.RawMessage) (json.RawMessage, error) {
+	var obj map[string]json.RawMessage
+	if err := decodePayload(raw, &obj); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// errInvalidID rejects ids too long to belong to anything the server handed out
+var errInvalidID = errors.New("id tidak valid")
+
+// checkIDs rejects any id longer than domain.MaxIDLength
+func checkIDs(ids ...string) error {
+	for _, id := range ids {
+		if len(id) > domain.MaxIDLength {
+			return errInvalidID
+		}
+	}
+	return nil
+}
+
+// idPayload accepts an empty payload or an object of short string fields (target_id, request_id, mode)
+func idPayload(raw json.RawMessage) (json.RawMessage, error) {
+	var obj map[string]string
+	if err := decodePayload(raw, &obj); err != nil {
+		return nil, err
+	}
+	for _, v := range obj {
+		if err := checkIDs(v); err != nil {
+			return nil, err
+		}
+	}
+	return raw, nil
+}
+
+// sanitizeText strips control characters (keeping newlines and tabs when multiline)
+// and truncates to maxLen characters
+func sanitizeText(s string, maxLen int, multiline bool) string {
+	s = strings.Map(func(r rune) rune {
+		if multiline && (r == '\n' || r == '\t') {
+			return r
+		}
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+
+	if utf8.RuneCountInString(s) > maxLen {
+		s = string([]rune(s)[:maxLen])
+	}
+	return s
+}
+
+// clampInt bounds v to [lo, hi]
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// clampFloat bounds v to [lo, hi]
+func clampFloat(v, lo, hi float64) float64 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// isAllowedURL checks for an https URL on one of the hosts (or their subdomains)
+func isAllowedURL(raw string, hosts []string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.User != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range hosts {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func validateChat(raw json.RawMessage) (json.RawMessage, error) {
+	var p domain.ChatPayload
+	if err := decodePayload(raw, &p); err != nil {
+		return nil, err
+	}
+	if utf8.RuneCountInString(p.Text) > domain.MaxChatLength {
+		return nil, errors.New("pesan terlalu panjang")
+	}
+	p.Text = sanitizeText(p.Text, domain.MaxChatLength, true)
+	if strings.TrimSpace(p.Text) == "" {
+		return nil, errors.New("pesan kosong")
+	}
+	return encodePayload(p)
+}
+
+func validateWhisper(raw json.RawMessage) (json.RawMessage, error) {
+	var p domain.WhisperPayload
+	if err := decodePayload(raw, &p); err != nil {
+		return nil, err
+	}
+	if utf8.RuneCountInString(p.Text) > domain.MaxChatLength {
+		return nil, errors.New("bisikan terlalu panjang")
+	}
+	if err := checkIDs(p.ToID); err != nil {
+		return nil, err
+	}
+	p.Text = sanitizeText(p.Text, domain.MaxChatLength, true)
+	if strings.TrimSpace(p.Text) == "" {
+		return nil, errors.New("bisikan kosong")
+	}
+	p.ToName = sanitizeText(p.ToName, domain.MaxTitleLength, false)
+	return encodePayload(p)
+}
+
+func validateVibrate(raw json.RawMessage) (json.RawMessage, error) {
+	var p domain.VibratePayload
+	if err := decodePayload(raw, &p); err != nil {
+		return nil, err
+	}
+	if len(p.Pattern) > domain.VibrateMaxPulses {
+		p.Pattern = p.Pattern[:domain.VibrateMaxPulses]
+	}
+	for i, ms := range p.Pattern {
+		p.Pattern[i] = clampInt(ms, 0, domain.VibrateMaxPulseMs)
+	}
+	if len(p.Pattern) == 0 {
+		p.Pattern = []int{200}
+	}
+	return encodePayload(p)
+}
+
+func validateChaos(raw json.RawMessage) (json.RawMessage, error) {
+	var p domain.ChaosPayload
+	if err := decodePayload(raw, &p); err != nil {
+		return nil, err
+	}
+	if p.DurationMs <= 0 {
+		p.DurationMs = domain.ChaosDefaultDurationMs
+	}
+	p.DurationMs = clampInt(p.DurationMs, 0, domain.ChaosMaxDurationMs)
+	return encodePayload(p)
+}
+
+func validateReaction(raw json.RawMessage) (json.RawMessage, error) {
+	var p domain.ReactionPayload
+	if err := decodePayload(raw, &p); err != nil {
+		return nil, err
+	}
+	if len(p.Emoji) > domain.MaxReactionLength {
+		return nil, errors.New("reaksi tidak valid")
+	}
+	p.Emoji = sanitizeText(p.Emoji, domain.MaxReactionLength, false)
+	if p.Emoji == "" {
+		return nil, errors.New("reaksi tidak valid")
+	}
+	p.X = clampFloat(p.X, 0, 1)
+	p.Y = clampFloat(p.Y, 0, 1)
+	return encodePayload(p)
+}
+
+func validateStatusUpdate(raw json.RawMessage) (json.RawMessage, error) {
+	var p domain.StatusUpdatePayload
+	if err := decodePayload(raw, &p); err != nil {
+		return nil, err
+	}
+	p.Battery = clampInt(p.Battery, 0, 100)
+	p.DeviceModel = sanitizeText(p.DeviceModel, domain.MaxDeviceModelLength, false)
+	return encodePayload(p)
+}
+
+func validateSpin(raw json.RawMessage) (json.RawMessage, error) {
+	var p domain.SpinPayload
+	if err := decodePayload(raw, &p); err != nil {
+		return nil, err
+	}
+	p.Text = sanitizeText(p.Text, domain.MaxEffectTextLength, false)
+	if strings.TrimSpace(p.Text) == "" {
+		return nil, errors.New("teks kosong")
+	}
+	return encodePayload(p)
+}
+
+func validateFlip(raw json.RawMessage) (json.RawMessage, error) {
+	var p domain.FlipPayload
+	if err := decodePayload(raw, &p); err != nil {
+		return nil, err
+	}
+	p.Original = sanitizeText(p.Original, domain.MaxEffectTextLength, false)
+	p.Flipped = "" // Computed server-side
+	return encodePayload(p)
+}
+
+func validateGif(raw json.RawMessage) (json.RawMessage, error) {
+	var p domain.GifPayload
+	if err := decodePayload(raw, &p); err != nil {
+		return nil, err
+	}
+	if !isAllowedURL(p.URL, gifHosts) {
+		return nil, errors.New("GIF hanya boleh dari GIPHY")
+	}
+	if p.Preview != "" && !isAllowedURL(p.Preview, gifHosts) {
+		p.Preview = ""
+	}
+	p.Width = clampInt(p.Width, 0, domain.MaxGifDimension)
+	p.Height = clampInt(p.Height, 0, domain.MaxGifDimension)
+	return encodePayload(p)
+}
+
+func validateTyping(raw json.RawMessage) (json.RawMessage, error) {
+	var p domain.TypingPayload
+	if err := decodePayload(raw, &p); err != nil {
+		return nil, err
+	}
+	return encodePayload(p)
+}
+
+func validateConfetti(raw json.RawMessage) (json.RawMessage, error) {
+	var p domain.ConfettiPayload
+	if err := decodePayload(raw, &p); err != nil {
+		return nil, err
+	}
+	p.Duration = clampInt(p.Duration, 0, domain.ConfettiMaxDurationMs)
+	if p.Color != "" && !hexColorRegex.MatchString(p.Color) {
+		p.Color = ""
+	}
+	return encodePayload(p)
+}
+
+func validateYoutube(raw json.RawMessage) (json.RawMessage, error) {
+	var p domain.YoutubePayload
+	if err := decodePayload(raw, &p); err != nil {
+		return nil, err
+	}
+	if !IsValidYouTubeVideoID(p.VideoID) {
+		return nil, errors.New("video YouTube tidak valid")
+	}
+	if p.URL != "" && !isAllowedURL(p.URL, youtubeHosts) {
+		p.URL = "https://www.youtube.com/watch?v=" + p.VideoID
+	}
+	return encodePayload(p)
+}
+
+func validateTts(raw json.RawMessage) (json.RawMessage, error) {
+	var p domain.TtsPayload
+	if err := decodePayload(raw, &p); err != nil {
+		return nil, err
+	}
+	if utf8.RuneCountInString(p.Text) > domain.MaxTtsLength {
+		return nil, errors.New("teks TTS terlalu panjang")
+	}
+	p.Text = sanitizeText(p.Text, domain.MaxTtsLength, false)
+	if strings.TrimSpace(p.Text) == "" {
+		return nil, errors.New("teks kosong")
+	}
+	return encodePayload(p)
+}
+
+func validateMusic(raw json.RawMessage) (json.RawMessage, error) {
+	var p domain.MusicPayload
+	if err := decodePayload(raw, &p); err != nil {
+		return nil, err
+	}
+	p.Title = sanitizeText(p.Title, domain.MaxTitleLength, false)
+	if p.CurrentTime < 0 {
+		p.CurrentTime = 0
+	}
+	return encodePayload(p)
+}
+
+func validateNobar(raw json.RawMessage) (json.RawMessage, error) {
+	var p domain.NobarPayload
+	if err := decodePayload(raw, &p); err != nil {
+		return nil, err
+	}
+	p.Title = sanitizeText(p.Title, domain.MaxTitleLength, false)
+	if p.CurrentTime < 0 {
+		p.CurrentTime = 0
+	}
+	return encodePayload(p)
+}
+
+func validateHistoryRequest(raw json.RawMessage) (json.RawMessage, error) {
+	var p domain.HistoryRequestPayload
+	if err := decodePayload(raw, &p); err != nil {
+		return nil, err
+	}
+	if p.Limit <= 0 {
+		p.Limit = domain.HistoryPageDefaultLimit
+	}
+	p.Limit = clampInt(p.Limit, 1, domain.HistoryPageMaxLimit)
+	return encodePayload(p)
+}
+
+func validateDice(raw json.RawMessage) (json.RawMessage, error) {
+	var p domain.DicePayload
+	if err := decodePayload(raw, &p); err != nil {
+		return nil, err
+	}
+	p.Result = 0 // Rolled server-side
+	return encodePayload(p)
+}
+
+func validateTod(raw json.RawMessage) (json.RawMessage, error) {
+	var p domain.TodPayload
+	if err := decodePayload(raw, &p); err != nil {
+		return nil, err
+	}
+	if p.Type != "truth" && p.Type != "dare" {
+		p.Type = "" // Server picks one
+	}
+	p.Question = "" // Chosen server-side
+	return encodePayload(p)
+}
+
+func validatePoll(raw json.RawMessage) (json.RawMessage, error) {
+	var p domain.PollPayload
+	if err := decodePayload(raw, &p); err != nil {
+		return nil, err
+	}
+	if utf8.RuneCountInString(p.Question) > domain.PollMaxQuestionLength {
+		return nil, errors.New("pertanyaan poll terlalu panjang")
+	}
+	if len(p.Options) > domain.PollMaxOptions {
+		return nil, errors.New("opsi poll terlalu banyak")
+	}
+	for i, opt := range p.Options {
+		if utf8.RuneCountInString(opt) > domain.PollMaxOptionLength {
+			return nil, errors.New("opsi poll terlalu panjang")
+		}
+		p.Options[i] = sanitizeText(opt, domain.PollMaxOptionLength, false)
+	}
+	if checkIDs(p.PollID) != nil {
+		p.PollID = "" // Server assigns one
+	}
+	return encodePayload(domain.PollPayload{
+		Question:    sanitizeText(p.Question, domain.PollMaxQuestionLength, false),
+		Options:     p.Options,
+		PollID:      p.PollID,
+		AllowChange: p.AllowChange,
+		DurationSec: clampInt(p.DurationSec, 0, int(domain.PollMaxDuration.Seconds())),
+	})
+}
+
+func validateVote(raw json.RawMessage) (json.RawMessage, error) {
+	var p domain.VotePayload
+	if err := decodePayload(raw, &p); err != nil {
+		return nil, err
+	}
+	if err := checkIDs(p.PollID); err != nil {
+		return nil, err
+	}
+	if p.OptionIndex < 0 || p.OptionIndex >= domain.PollMaxOptions {
+		return nil, errors.New("opsi poll tidak valid")
+	}
+	return encodePayload(p)
+}
+
+func validatePollClose(raw json.RawMessage) (json.RawMessage, error) {
+	var p domain.PollClosePayload
+	if err := decodePayload(raw, &p); err != nil {
+		return nil, err
+	}
+	if err := checkIDs(p.PollID); err != nil {
+		return nil, err
+	}
+	return encodePayload(p)
+}
+
+func validateSuit(raw json.RawMessage) (json.RawMessage, error) {
+	var p domain.SuitPayload
+	if err := decodePayload(raw, &p); err != nil {
+		return nil, err
+	}
+	switch p.Action {
+	case "challenge", "accept", "decline", "move":
+	default:
+		return nil, errors.New("aksi suit tidak valid")
+	}
+	if _, ok := suitBeats[p.Move]; p.Move != "" && !ok {
+		return nil, errors.New("langkah suit tidak valid")
+	}
+	if err := checkIDs(p.ID, p.OpponentID); err != nil {
+		return nil, err
+	}
+	// Only the request fields; names, moves made and the outcome are the server's to fill in
+	return encodePayload(domain.SuitPayload{
+		ID:         p.ID,
+		Action:     p.Action,
+		Move:       p.Move,
+		OpponentID: p.OpponentID,
+	})
+}
+
+func validateAdmit(raw json.RawMessage) (json.RawMessage, error) {
+	var p domain.AdmitPayload
+	if err := decodePayload(raw, &p); err != nil {
+		return nil, err
+	}
+	if err := checkIDs(p.ClientID); err != nil {
+		return nil, err
+	}
+	p.Reason = sanitizeText(p.Reason, domain.MaxReasonLength, false)
+	return encodePayload(p)
+}
+
+func validateBan(raw json.RawMessage) (json.RawMessage, error) {
+	var p domain.BanPayload
+	if err := decodePayload(raw, &p); err != nil {
+		return nil, err
+	}
+	if err := checkIDs(p.TargetID, p.BanID); err != nil {
+		return nil, err
+	}
+	p.DurationSec = clampInt(p.DurationSec, 0, int(domain.BanMaxDuration.Seconds()))
+	return encodePayload(p)
+}
+
+// validateModeration builds the validator for one moderation action, capping its duration
+func validateModeration(maxDuration time.Duration) payloadValidator {
+	return func(raw json.RawMessage) (json.RawMessage, error) {
+		var p domain.ModerationPayload
+		if err := decodePayload(raw, &p); err != nil {
+			return nil, err
+		}
+		if err := checkIDs(p.TargetID); err != nil {
+			return nil, err
+		}
+		p.DurationSec = clampInt(p.DurationSec, 0, int(maxDuration.Seconds()))
+		return encodePayload(p)
+	}
+}
@@ -1,6 +1,12 @@
 package ws
 
-import "testing"
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mmuslimabdulj/goat-chat/internal/domain"
+)
 
 func TestIsValidYouTubeVideoID(t *testing.T) {
 	tests := []struct {
@@ -28,3 +34,251 @@ func TestIsValidYouTubeVideoID(t *testing.T) {
 		})
 	}
 }
+
+func TestValidatePayload_UnknownTypesDropped(t *testing.T) {
+	types := []domain.MessageType{
+		"does_not_exist",
+		domain.MessageTypeIdentity,
+		domain.MessageTypeUserJoin,
+		domain.MessageTypeSystem,
+		domain.MessageTypeHostChange,
+		domain.MessageTypeError,
+	}
+
+	for _, msgType := range types {
+		if _, err := validatePayload(msgType, json.RawMessage(`{}`)); err != errUnknownMessageType {
+			t.Errorf("validatePayload(%q) error = %v, expected errUnknownMessageType", msgType, err)
+		}
+	}
+}
+
+func TestValidatePayload_Rejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		msgType domain.MessageType
+		payload string
+	}{
+		{"Chat empty", domain.MessageTypeChat, `{"text":"   "}`},
+		{"Chat missing payload", domain.MessageTypeChat, ``},
+		{"Chat too long", domain.MessageTypeChat, `{"text":"` + strings.Repeat("a", domain.MaxChatLength+1) + `"}`},
+		{"Chat wrong type", domain.MessageTypeChat, `{"text":42}`},
+		{"TTS too long", domain.MessageTypeTts, `{"text":"` + strings.Repeat("a", domain.MaxTtsLength+1) + `"}`},
+		{"Reaction empty", domain.MessageTypeReaction, `{"emoji":""}`},
+		{"Reaction too long", domain.MessageTypeReaction, `{"emoji":"` + strings.Repeat("x", domain.MaxReactionLength+1) + `"}`},
+		{"GIF off-host", domain.MessageTypeGif, `{"url":"https://evil.example.com/a.gif"}`},
+		{"GIF lookalike host", domain.MessageTypeGif, `{"url":"https://notgiphy.com/a.gif"}`},
+		{"GIF plain http", domain.MessageTypeGif, `{"url":"http://media.giphy.com/a.gif"}`},
+		{"GIF javascript", domain.MessageTypeGif, `{"url":"javascript:alert(1)"}`},
+		{"YouTube bad ID", domain.MessageTypeYoutube, `{"video_id":"<script>"}`},
+		{"Kick not an object", domain.MessageTypeKick, `"abc"`},
+		{"Kick id too long", domain.MessageTypeKick, `{"target_id":"` + strings.Repeat("a", domain.MaxIDLength+1) + `"}`},
+		{"Poll question too long", domain.MessageTypePoll, `{"question":"` + strings.Repeat("a", domain.PollMaxQuestionLength+1) + `","options":["a","b"]}`},
+		{"Poll option too long", domain.MessageTypePoll, `{"question":"q","options":["a","` + strings.Repeat("b", domain.PollMaxOptionLength+1) + `"]}`},
+		{"Poll too many options", domain.MessageTypePoll, `{"question":"q","options":["1","2","3","4","5","6","7","8","9","10","11"]}`},
+		{"Vote poll id too long", domain.MessageTypeVote, `{"poll_id":"` + strings.Repeat("p", domain.MaxIDLength+1) + `","option_index":0}`},
+		{"Vote option out of range", domain.MessageTypeVote, `{"poll_id":"p","option_index":-1}`},
+		{"Poll close id too long", domain.MessageTypePollClose, `{"poll_id":"` + strings.Repeat("p", domain.MaxIDLength+1) + `"}`},
+		{"Suit unknown action", domain.MessageTypeSuit, `{"action":"cheat"}`},
+		{"Suit unknown move", domain.MessageTypeSuit, `{"action":"move","id":"m","move":"lizard"}`},
+		{"Suit opponent id too long", domain.MessageTypeSuit, `{"action":"challenge","opponent_id":"` + strings.Repeat("o", domain.MaxIDLength+1) + `"}`},
+		{"Ban id too long", domain.MessageTypeBan, `{"target_id":"` + strings.Repeat("t", domain.MaxIDLength+1) + `"}`},
+		{"Whisper empty", domain.MessageTypeWhisper, `{"to_id":"u","text":"   "}`},
+		{"Whisper target id too long", domain.MessageTypeWhisper, `{"to_id":"` + strings.Repeat("u", domain.MaxIDLength+1) + `","text":"psst"}`},
+		{"Mute id too long", domain.MessageTypeMute, `{"target_id":"` + strings.Repeat("t", domain.MaxIDLength+1) + `"}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := validatePayload(tc.msgType, json.RawMessage(tc.payload)); err == nil {
+				t.Errorf("expected %s payload %s to be rejected", tc.msgType, tc.payload)
+			}
+		})
+	}
+}
+
+func TestValidatePayload_Normalizes(t *testing.T) {
+	t.Run("Chat strips control chars and unknown fields", func(t *testing.T) {
+		out, err := validatePayload(domain.MessageTypeChat, json.RawMessage(`{"text":"hi\u0007\nthere","html":"<b>"}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(out) != `{"text":"hi\nthere"}` {
+			t.Errorf("got %s", out)
+		}
+	})
+
+	t.Run("Vibrate clamps pattern", func(t *testing.T) {
+		out, err := validatePayload(domain.MessageTypeVibrate, json.RawMessage(`{"pattern":[99999,-5,1,2,3,4,5,6,7,8,9,10]}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var p domain.VibratePayload
+		json.Unmarshal(out, &p)
+		if len(p.Pattern) != domain.VibrateMaxPulses {
+			t.Errorf("expected %d pulses, got %d", domain.VibrateMaxPulses, len(p.Pattern))
+		}
+		if p.Pattern[0] != domain.VibrateMaxPulseMs || p.Pattern[1] != 0 {
+			t.Errorf("expected pulses clamped, got %v", p.Pattern)
+		}
+	})
+
+	t.Run("Chaos defaults and clamps duration", func(t *testing.T) {
+		var p domain.ChaosPayload
+		out, _ := validatePayload(domain.MessageTypeChaos, nil)
+		json.Unmarshal(out, &p)
+		if p.DurationMs != domain.ChaosDefaultDurationMs {
+			t.Errorf("expected default %d, got %d", domain.ChaosDefaultDurationMs, p.DurationMs)
+		}
+
+		out, _ = validatePayload(domain.MessageTypeChaos, json.RawMessage(`{"duration_ms":600000}`))
+		json.Unmarshal(out, &p)
+		if p.DurationMs != domain.ChaosMaxDurationMs {
+			t.Errorf("expected clamp to %d, got %d", domain.ChaosMaxDurationMs, p.DurationMs)
+		}
+	})
+
+	t.Run("Reaction clamps position", func(t *testing.T) {
+		var p domain.ReactionPayload
+		out, _ := validatePayload(domain.MessageTypeReaction, json.RawMessage(`{"emoji":"🔥","x":5,"y":-1}`))
+		json.Unmarshal(out, &p)
+		if p.X != 1 || p.Y != 0 {
+			t.Errorf("expected (1,0), got (%v,%v)", p.X, p.Y)
+		}
+	})
+
+	t.Run("History request defaults and clamps limit", func(t *testing.T) {
+		var p domain.HistoryRequestPayload
+		out, _ := validatePayload(domain.MessageTypeHistoryRequest, json.RawMessage(`{"before":42}`))
+		json.Unmarshal(out, &p)
+		if p.Limit != domain.HistoryPageDefaultLimit || p.Before != 42 {
+			t.Errorf("expected default limit before 42, got %+v", p)
+		}
+
+		out, _ = validatePayload(domain.MessageTypeHistoryRequest, json.RawMessage(`{"limit":100000}`))
+		json.Unmarshal(out, &p)
+		if p.Limit != domain.HistoryPageMaxLimit {
+			t.Errorf("expected clamp to %d, got %d", domain.HistoryPageMaxLimit, p.Limit)
+		}
+	})
+
+	t.Run("Status update clamps battery", func(t *testing.T) {
+		var p domain.StatusUpdatePayload
+		out, _ := validatePayload(domain.MessageTypeStatusUpdate, json.RawMessage(`{"battery":900}`))
+		json.Unmarshal(out, &p)
+		if p.Battery != 100 {
+			t.Errorf("expected 100, got %d", p.Battery)
+		}
+	})
+
+	t.Run("Status update keeps device model", func(t *testing.T) {
+		var p domain.StatusUpdatePayload
+		out, _ := validatePayload(domain.MessageTypeStatusUpdate, json.RawMessage(`{"device_model":"Pixel 8"}`))
+		json.Unmarshal(out, &p)
+		if p.DeviceModel != "Pixel 8" {
+			t.Errorf("expected device model to survive, got %s", out)
+		}
+	})
+
+	t.Run("Poll clamps duration and drops server fields", func(t *testing.T) {
+		var p domain.PollPayload
+		out, err := validatePayload(domain.MessageTypePoll, json.RawMessage(`{"question":"q\u0007?","options":["a","b"],"duration_sec":99999999,"closed":true,"voters":["x"],"creator_id":"me"}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		json.Unmarshal(out, &p)
+		if p.Question != "q?" || p.Closed || p.Voters != nil || p.CreatorID != "" {
+			t.Errorf("unexpected normalized poll: %+v", p)
+		}
+		if p.DurationSec != int(domain.PollMaxDuration.Seconds()) {
+			t.Errorf("expected duration clamped, got %d", p.DurationSec)
+		}
+	})
+
+	t.Run("Suit keeps only request fields", func(t *testing.T) {
+		var p domain.SuitPayload
+		out, err := validatePayload(domain.MessageTypeSuit, json.RawMessage(`{"action":"move","id":"m1","move":"rock","winner":"me","status":"completed","opponent_move":"scissors"}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		json.Unmarshal(out, &p)
+		if p.Action != "move" || p.ID != "m1" || p.Move != "rock" {
+			t.Errorf("request fields lost: %+v", p)
+		}
+		if p.Winner != "" || p.Status != "" || p.OpponentMove != "" {
+			t.Errorf("server fields kept: %+v", p)
+		}
+	})
+
+	t.Run("Dice and truth or dare drop server-chosen results", func(t *testing.T) {
+		var dice domain.DicePayload
+		out, _ := validatePayload(domain.MessageTypeDice, json.RawMessage(`{"max":20,"result":20}`))
+		json.Unmarshal(out, &dice)
+		if dice.Max != 20 || dice.Result != 0 {
+			t.Errorf("unexpected dice: %+v", dice)
+		}
+
+		var tod domain.TodPayload
+		out, _ = validatePayload(domain.MessageTypeTod, json.RawMessage(`{"type":"anything","question":"mine"}`))
+		json.Unmarshal(out, &tod)
+		if tod.Type != "" || tod.Question != "" {
+			t.Errorf("unexpected tod: %+v", tod)
+		}
+	})
+
+	t.Run("Moderation clamps duration per action", func(t *testing.T) {
+		var p domain.ModerationPayload
+		out, _ := validatePayload(domain.MessageTypeMute, json.RawMessage(`{"target_id":"t","duration_sec":99999999}`))
+		json.Unmarshal(out, &p)
+		if p.DurationSec != int(domain.MuteMaxDuration.Seconds()) {
+			t.Errorf("expected mute clamped to %v, got %ds", domain.MuteMaxDuration, p.DurationSec)
+		}
+
+		out, _ = validatePayload(domain.MessageTypeTimeout, json.RawMessage(`{"target_id":"t","duration_sec":99999999}`))
+		json.Unmarshal(out, &p)
+		if p.DurationSec != int(domain.TimeoutMaxDuration.Seconds()) {
+			t.Errorf("expected timeout clamped to %v, got %ds", domain.TimeoutMaxDuration, p.DurationSec)
+		}
+	})
+
+	t.Run("Admit deny truncates reason", func(t *testing.T) {
+		var p domain.AdmitPayload
+		out, _ := validatePayload(domain.MessageTypeAdmitDeny, json.RawMessage(`{"client_id":"c","reason":"`+strings.Repeat("r", domain.MaxReasonLength+50)+`"}`))
+		json.Unmarshal(out, &p)
+		if n := len([]rune(p.Reason)); n != domain.MaxReasonLength {
+			t.Errorf("expected %d runes, got %d", domain.MaxReasonLength, n)
+		}
+	})
+
+	t.Run("GIF keeps allowed host and drops bad preview", func(t *testing.T) {
+		var p domain.GifPayload
+		out, err := validatePayload(domain.MessageTypeGif, json.RawMessage(`{"url":"https://media2.giphy.com/a.gif","preview":"https://evil.example.com/p.gif","width":99999}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		json.Unmarshal(out, &p)
+		if p.Preview != "" || p.Width != domain.MaxGifDimension {
+			t.Errorf("unexpected normalized gif: %+v", p)
+		}
+	})
+
+	t.Run("YouTube rewrites off-host URL", func(t *testing.T) {
+		var p domain.YoutubePayload
+		out, err := validatePayload(domain.MessageTypeYoutube, json.RawMessage(`{"video_id":"dQw4w9WgXcQ","url":"https://evil.example.com/"}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		json.Unmarshal(out, &p)
+		if p.URL != "https://www.youtube.com/watch?v=dQw4w9WgXcQ" {
+			t.Errorf("got URL %q", p.URL)
+		}
+	})
+
+	t.Run("Spin truncates text", func(t *testing.T) {
+		var p domain.SpinPayload
+		out, _ := validatePayload(domain.MessageTypeSpin, json.RawMessage(`{"text":"`+strings.Repeat("é", domain.MaxEffectTextLength+50)+`"}`))
+		json.Unmarshal(out, &p)
+		if n := len([]rune(p.Text)); n != domain.MaxEffectTextLength {
+			t.Errorf("expected %d runes, got %d", domain.MaxEffectTextLength, n)
+		}
+	})
+}
@@ -10,10 +10,151 @@ const MaxMessageSize = 4096
 // MaxHistorySize is the maximum number of messages to store for new clients
 const MaxHistorySize = 200
 
+// MaxWhisperHistorySize is the maximum number of whispers kept per persona
+const MaxWhisperHistorySize = 50
+
+// HistoryJoinBurst is how many recent messages a new client gets on join
+// Older ones are fetched on demand with history_request
+const HistoryJoinBurst = 50
+
+// History paging over history_request
+const (
+	HistoryPageDefaultLimit = 50 // Messages per page when the request gives no limit
+	HistoryPageMaxLimit     = 100
+	HistoryChunkSize        = 20 // Messages per history_page frame
+)
+
+// ==== Payload Limits ====
+
+const (
+	// MaxChatLength is the maximum chat/whisper text length in characters
+	MaxChatLength = 1000
+
+	// MaxTtsLength is the maximum text-to-speech length in characters
+	MaxTtsLength = 200
+
+	// MaxEffectTextLength is the maximum spin/flip text length in characters
+	MaxEffectTextLength = 100
+
+	// MaxReactionLength is the maximum reaction emoji length in bytes
+	MaxReactionLength = 32
+
+	// MaxTitleLength is the maximum music/nobar title length in characters
+	MaxTitleLength = 200
+
+	// VibrateMaxPulses is the maximum number of entries in a vibrate pattern
+	VibrateMaxPulses = 10
+
+	// VibrateMaxPulseMs caps a single vibrate/pause entry
+	VibrateMaxPulseMs = 1000
+
+	// ChaosDefaultDurationMs is used when a chaos payload has no duration
+	ChaosDefaultDurationMs = 5000
+
+	// ChaosMaxDurationMs caps a chaos effect
+	ChaosMaxDurationMs = 10000
+
+	// ConfettiMaxDurationMs caps a confetti effect
+	ConfettiMaxDurationMs = 5000
+
+	// MaxGifDimension caps the advertised GIF width/height in pixels
+	MaxGifDimension = 2000
+
+	// MaxClientMsgIDLength caps the client-supplied message id echoed in ack/error frames
+	MaxClientMsgIDLength = 64
+
+	// MaxIDLength caps ids clients refer to (users, polls, suit matches, bans, requests)
+	MaxIDLength = 64
+
+	// MaxDeviceModelLength is the maximum device model length in characters
+	MaxDeviceModelLength = 64
+
+	// MaxReasonLength is the maximum length of a host-supplied reason in characters
+	MaxReasonLength = 200
+)
+
 // ==== Session Constants ====
 
-// SessionTTL is the default session token time-to-live
-const SessionTTL = 24 * time.Hour
+const (
+	// SessionTTL is how long an unused session token stays valid (sliding, from LastUsed)
+	SessionTTL = 24 * time.Hour
+
+	// SessionAuthTimeout is how long the server waits for the auth frame
+	SessionAuthTimeout = 5 * time.Second
+
+	// SessionSnapshotMaxAge is how old a sealed session snapshot may be when restored
+	// Long enough for a rolling restart, short enough that a stray file is useless
+	SessionSnapshotMaxAge = 5 * time.Minute
+)
+
+// ==== Room Access Constants ====
+
+const (
+	// MaxPassphraseLength is the maximum room passphrase length in bytes
+	MaxPassphraseLength = 128
+
+	// InviteDefaultTTL is the lifetime of an invite when the host does not set one
+	InviteDefaultTTL = 24 * time.Hour
+
+	// InviteMaxTTL caps the lifetime of an invite
+	InviteMaxTTL = 7 * 24 * time.Hour
+
+	// JoinTicketTTL is how long a join ticket (proof of passphrase/invite) stays valid
+	JoinTicketTTL = 2 * time.Minute
+
+	// MaxRoomOccupancy caps the occupancy limit a room can be created with
+	MaxRoomOccupancy = 100
+
+	// MaxLobbySize is the number of clients that can wait for approval at once
+	MaxLobbySize = 20
+
+	// KickBanDuration is how long a kicked user is kept out of the room
+	KickBanDuration = 10 * time.Minute
+
+	// BanMaxDuration caps a timed ban
+	BanMaxDuration = 7 * 24 * time.Hour
+)
+
+// ==== Moderation Constants ====
+
+const (
+	// MuteDefaultDuration is used when the host does not give a mute duration
+	MuteDefaultDuration = 10 * time.Minute
+
+	// MuteMaxDuration caps a mute
+	MuteMaxDuration = 24 * time.Hour
+
+	// TimeoutDefaultDuration is used when the host does not give a timeout duration
+	TimeoutDefaultDuration = 5 * time.Minute
+
+	// TimeoutMaxDuration caps a timeout
+	TimeoutMaxDuration = time.Hour
+)
+
+// ==== Flood Protection Constants ====
+
+const (
+	// FloodRate is the inbound token refill rate of each per-user bucket (tokens/sec)
+	FloodRate = 5
+
+	// FloodBurst is the size of each per-user inbound token bucket
+	FloodBurst = 20
+
+	// FloodWarnStrikes is the number of dropped messages before a warning
+	FloodWarnStrikes = 3
+
+	// FloodTimeoutStrikes is the number of dropped messages before an auto-timeout
+	FloodTimeoutStrikes = 10
+
+	// FloodStrikeWindow is the quiet period after which strikes are forgotten
+	FloodStrikeWindow = 30 * time.Second
+
+	// FloodTimeoutDuration is how long an auto-timeout lasts
+	FloodTimeoutDuration = time.Minute
+
+	// ChaosCooldown is the room-wide minimum gap between chaos messages
+	ChaosCooldown = 10 * time.Second
+)
 
 // ==== Rate Limit Constants ====
 
@@ -28,6 +169,34 @@ const (
 	DefaultRateLimitStrict = 2
 )
 
+// ==== Game Constants ====
+
+const (
+	// DiceDefaultSides is used when the requested dice size is out of range
+	DiceDefaultSides = 6
+
+	// DiceMaxSides is the largest dice the server will roll
+	DiceMaxSides = 1000
+
+	// PollMinOptions is the minimum number of distinct options in a poll
+	PollMinOptions = 2
+
+	// PollMaxOptions is the maximum number of options in a poll
+	PollMaxOptions = 10
+
+	// PollMaxQuestionLength is the maximum poll question length in characters
+	PollMaxQuestionLength = 200
+
+	// PollMaxOptionLength is the maximum poll option length in characters
+	PollMaxOptionLength = 100
+
+	// MaxPollsPerRoom is the number of polls kept per room (oldest are evicted)
+	MaxPollsPerRoom = 20
+
+	// PollMaxDuration caps the auto-close timer of a poll
+	PollMaxDuration = 24 * time.Hour
+)
+
 // ==== Timing Constants ====
 
 const (
@@ -40,6 +209,15 @@ const (
 	// ShutdownGracePeriod is the time to wait before destroying empty room
 	ShutdownGracePeriod = 60 * time.Second
 
+	// HandoffRejoinWindow is how long users carried over by a process handoff have to reconnect
+	HandoffRejoinWindow = 30 * time.Second
+
 	// SongEndedDebounce prevents rapid song-ended events
 	SongEndedDebounce = 5 * time.Second
+
+	// SuitAcceptTimeout is how long an opponent has to accept a suit challenge
+	SuitAcceptTimeout = 30 * time.Second
+
+	// SuitMoveTimeout is how long both players have to move once accepted
+	SuitMoveTimeout = 30 * time.Second
 )
@@ -48,8 +48,183 @@ const (
 	MessageTypeNobarViewers  MessageType = "nobar_viewers_sync" // Sync active viewers
 	MessageTypePartyChange   MessageType = "party_change"       // Party mode change
 	MessageTypeTts           MessageType = "tts"                // Text to speech
+	MessageTypeError         MessageType = "error"              // Error frame sent to a single client
+	MessageTypePollUpdate    MessageType = "poll_update"        // Authoritative poll tally
+	MessageTypePollClose     MessageType = "poll_close"         // Close a poll (host or creator)
+	MessageTypeSuitScoreboard MessageType = "suit_scoreboard"   // Query/return suit win-loss table
+	MessageTypeScoreboard    MessageType = "scoreboard"         // Query/return room mini-game leaderboard
+	MessageTypeInvite        MessageType = "invite"             // Host mints a room invite token
+	MessageTypeKnock         MessageType = "knock"              // Lobby status sent to a waiting client
+	MessageTypeLobbySync     MessageType = "lobby_sync"         // Sync waiting room (for host)
+	MessageTypeAdmitApprove  MessageType = "admit_approve"      // Host lets a waiting client in
+	MessageTypeAdmitDeny     MessageType = "admit_deny"         // Host turns a waiting client away
+	MessageTypeBan           MessageType = "ban"                // Host bans a user from the room
+	MessageTypeUnban         MessageType = "unban"              // Host lifts a ban
+	MessageTypeBanList       MessageType = "ban_list"           // Query/return active bans (for host)
+	MessageTypeMute          MessageType = "mute"               // Host silences a user's chat-like messages
+	MessageTypeTimeout       MessageType = "timeout"            // Host silences all of a user's messages
+	MessageTypeUnmute        MessageType = "unmute"             // Host lifts a mute or timeout
+	MessageTypeGrantModerator  MessageType = "grant_moderator"  // Owner makes a user moderator
+	MessageTypeRevokeModerator MessageType = "revoke_moderator" // Owner demotes a moderator
+	MessageTypeAck             MessageType = "ack"              // Server accepted a message sent with client_msg_id
+	MessageTypeHello           MessageType = "hello"            // Protocol version and feature handshake
+	MessageTypeSessionToken    MessageType = "session_token"    // Reconnect token issued on connect
+	MessageTypeAuth            MessageType = "auth"             // First frame carrying the reconnect token
+	MessageTypeLogout          MessageType = "logout"           // Client revokes its reconnect token
+	MessageTypeUserDelta       MessageType = "user_delta"       // Online list changes since the last version
+	MessageTypePresenceSnapshot MessageType = "presence_snapshot" // Full online list at a version
+	MessageTypePresenceResync   MessageType = "presence_resync"   // Client missed a version, wants a snapshot
+	MessageTypeHistoryTruncated MessageType = "history_truncated" // Part of the requested history was evicted
+	MessageTypeHistoryRequest   MessageType = "history_request"   // Client pages back through room history
+	MessageTypeHistoryPage      MessageType = "history_page"      // One chunk of a history page
 )
 
+// Error codes carried in ErrorPayload.Code
+const (
+	ErrCodeUserOffline    = "user_offline"    // Target user is not connected
+	ErrCodeInvalidPayload = "invalid_payload" // Payload could not be parsed
+	ErrCodeForbidden      = "forbidden"       // Requester lacks permission
+	ErrCodePollNotFound   = "poll_not_found"  // Poll does not exist
+	ErrCodePollClosed     = "poll_closed"     // Poll no longer accepts votes
+	ErrCodeAlreadyVoted   = "already_voted"   // Vote changes are disabled for this poll
+	ErrCodeSuitNotFound   = "suit_not_found"  // Suit match does not exist or already finished
+	ErrCodeRoomFull       = "room_full"       // Room or its waiting room is at capacity
+	ErrCodeBanNotFound    = "ban_not_found"   // Ban does not exist or already expired
+	ErrCodeMuted          = "muted"           // Sender is muted or timed out
+	ErrCodeRateLimited    = "rate_limited"    // Sender is over its message budget or a cooldown
+	ErrCodeNotFound       = "not_found"       // Referenced request or item does not exist
+)
+
+// ErrorPayload is the payload for error frames
+// ClientMsgID echoes the id of the message that failed, when the client sent one
+type ErrorPayload struct {
+	Code        string `json:"code"`
+	Message     string `json:"message"`
+	ClientMsgID string `json:"client_msg_id,omitempty"`
+}
+
+// OnlineUser is one entry in the online user list
+type OnlineUser struct {
+	ID      string `json:"id"`
+	Persona string `json:"persona"`
+	Color   string `json:"color"`
+	Battery int    `json:"battery"`
+	Role    Role   `json:"role"`
+}
+
+// UserDeltaPayload describes online list changes instead of resending the whole list
+// Version goes up by one per delta, so clients can spot a missed one
+type UserDeltaPayload struct {
+	Version   uint64       `json:"version"`
+	Added     []OnlineUser `json:"added,omitempty"`
+	Removed   []string     `json:"removed,omitempty"` // User IDs
+	Updated   []OnlineUser `json:"updated,omitempty"`
+	UserCount int          `json:"user_count"`
+	HostID    string       `json:"host_id"`
+}
+
+// HistoryTruncatedPayload tells a resuming client that messages after LastSeq were lost
+// The history that follows starts at OldestSeq (0 when none is left)
+type HistoryTruncatedPayload struct {
+	LastSeq   uint64 `json:"last_seq"`
+	OldestSeq uint64 `json:"oldest_seq"`
+}
+
+// HistoryRequestPayload asks for up to Limit messages older than Before (0 = newest)
+type HistoryRequestPayload struct {
+	Before uint64 `json:"before,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// HistoryPagePayload carries one chunk of a history page, oldest message first
+// Clients collect chunks until Final, then continue from Next while HasMore
+type HistoryPagePayload struct {
+	Before   uint64            `json:"before"` // Cursor from the request
+	Messages []json.RawMessage `json:"messages"`
+	Final    bool              `json:"final"`
+	HasMore  bool              `json:"has_more"`
+	Next     uint64            `json:"next,omitempty"` // Cursor for the next older page
+}
+
+// PresenceSnapshotPayload is the whole online list; deltas continue from Version
+type PresenceSnapshotPayload struct {
+	Version   uint64       `json:"version"`
+	Users     []OnlineUser `json:"users"`
+	UserCount int          `json:"user_count"`
+	HostID    string       `json:"host_id"`
+}
+
+// AckPayload confirms the server accepted a message sent with client_msg_id
+type AckPayload struct {
+	ClientMsgID string `json:"client_msg_id"`
+	MessageID   string `json:"message_id"` // Server-assigned id (matches the relayed copy for chat-like messages)
+}
+
+// InvitePayload is used to request (host) and return a room invite token
+type InvitePayload struct {
+	Token     string    `json:"token,omitempty"`
+	SingleUse bool      `json:"single_use"`
+	TTLSec    int       `json:"ttl_sec,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// KnockPayload tells a waiting client where it stands in the lobby
+type KnockPayload struct {
+	Status   string `json:"status"` // waiting, approved, denied
+	Position int    `json:"position,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// LobbyEntry is a client waiting for host approval
+type LobbyEntry struct {
+	ID           string    `json:"id"`
+	PersonaName  string    `json:"persona"`
+	PersonaColor string    `json:"color"`
+	KnockedAt    time.Time `json:"knocked_at"`
+}
+
+// LobbySyncPayload is the waiting room state sent to the host
+type LobbySyncPayload struct {
+	Waiting []LobbyEntry `json:"waiting"`
+}
+
+// AdmitPayload is used by the host to approve or deny a waiting client
+type AdmitPayload struct {
+	ClientID string `json:"client_id"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// BanPayload is used by the host to ban (target_id) or unban (ban_id) a user
+type BanPayload struct {
+	TargetID    string `json:"target_id,omitempty"`
+	BanID       string `json:"ban_id,omitempty"`
+	DurationSec int    `json:"duration_sec,omitempty"` // 0 = until the room closes
+}
+
+// BanEntry describes an active ban (IP and token lineage are never sent to clients)
+type BanEntry struct {
+	ID          string     `json:"id"`
+	PersonaName string     `json:"persona"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// BanListPayload is the active ban list sent to the host
+type BanListPayload struct {
+	Bans []BanEntry `json:"bans"`
+}
+
+// ModerationPayload is used by the host to mute, time out, or unmute a user
+type ModerationPayload struct {
+	TargetID    string `json:"target_id"`
+	DurationSec int    `json:"duration_sec,omitempty"`
+}
+
+// RolePayload is used by the owner to grant or revoke the moderator role
+type RolePayload struct {
+	TargetID string `json:"target_id"`
+}
+
 // NobarViewer represents an active viewer
 type NobarViewer struct {
 	ID          string `json:"id"`
@@ -127,15 +302,23 @@ type NobarQueueSyncPayload struct {
 }
 
 // Message represents a chat message or command
+// Over MessagePack the payload is carried as binary holding its JSON encoding
 type Message struct {
-	ID        string          `json:"id"`
-	Type      MessageType     `json:"type"`
-	FromID    string          `json:"from_id"`
-	FromName  string          `json:"from_name"`
-	FromColor string          `json:"from_color,omitempty"`
-	ToID      string          `json:"to_id,omitempty"` // For whisper
-	Payload   json.RawMessage `json:"payload"`
-	CreatedAt time.Time       `json:"created_at"`
+	ID        string          `json:"id" msgpack:"id"`
+	Type      MessageType     `json:"type" msgpack:"type"`
+	FromID    string          `json:"from_id" msgpack:"from_id"`
+	FromName  string          `json:"from_name" msgpack:"from_name"`
+	FromColor string          `json:"from_color,omitempty" msgpack:"from_color,omitempty"`
+	ToID      string          `json:"to_id,omitempty" msgpack:"to_id,omitempty"` // For whisper
+	Payload   json.RawMessage `json:"payload" msgpack:"payload"`
+	CreatedAt time.Time       `json:"created_at" msgpack:"created_at"`
+
+	// ClientMsgID is the sender's own id for the message, used to de-duplicate optimistic UI
+	ClientMsgID string `json:"client_msg_id,omitempty" msgpack:"client_msg_id,omitempty"`
+
+	// Seq is the room history sequence, set on messages kept in history
+	// Clients pass the last one they saw as last_seq to resume after a reconnect
+	Seq uint64 `json:"seq,omitempty" msgpack:"seq,omitempty"`
 }
 
 // ChatPayload is the payload for chat messages
@@ -168,7 +351,8 @@ type ReactionPayload struct {
 
 // StatusUpdatePayload is the payload for battery/location updates
 type StatusUpdatePayload struct {
-	Battery   int     `json:"battery,omitempty"` // 0-100
+	Battery     int    `json:"battery,omitempty"`      // 0-100
+	DeviceModel string `json:"device_model,omitempty"` // sent once by the native app
 }
 
 // DicePayload is the payload for dice roll
@@ -204,15 +388,60 @@ type GifPayload struct {
 }
 
 // SuitPayload is the payload for Rock Paper Scissors
+// Clients send requests with Action (challenge, accept, decline, move);
+// the server replies with the authoritative state in Status.
+// Moves are only revealed once Status is "completed".
 type SuitPayload struct {
-	ChallengerID   string `json:"challenger_id"`
-	ChallengerName string `json:"challenger_name"`
-	OpponentID     string `json:"opponent_id,omitempty"`
-	OpponentName   string `json:"opponent_name,omitempty"`
-	ChallengerMove string `json:"challenger_move,omitempty"` // rock, paper, scissors
-	OpponentMove   string `json:"opponent_move,omitempty"`
-	Winner         string `json:"winner,omitempty"` // challenger_id, opponent_id, or "draw"
-	Status         string `json:"status"`           // pending, accepted, completed
+	ID              string    `json:"id,omitempty"`
+	Action          string    `json:"action,omitempty"` // challenge, accept, decline, move
+	Move            string    `json:"move,omitempty"`   // move being committed (requests only)
+	ChallengerID    string    `json:"challenger_id"`
+	ChallengerName  string    `json:"challenger_name"`
+	OpponentID      string    `json:"opponent_id,omitempty"`
+	OpponentName    string    `json:"opponent_name,omitempty"`
+	ChallengerMove  string    `json:"challenger_move,omitempty"` // rock, paper, scissors
+	OpponentMove    string    `json:"opponent_move,omitempty"`
+	ChallengerMoved bool      `json:"challenger_moved,omitempty"`
+	OpponentMoved   bool      `json:"opponent_moved,omitempty"`
+	Winner          string    `json:"winner,omitempty"` // challenger_id, opponent_id, or "draw"
+	Status          string    `json:"status"`           // pending, accepted, completed, declined, expired
+	ExpiresAt       time.Time `json:"expires_at,omitempty"`
+}
+
+// SuitScore is a single row of the suit scoreboard
+type SuitScore struct {
+	PersonaName string `json:"persona_name"`
+	Wins        int    `json:"wins"`
+	Losses      int    `json:"losses"`
+	Draws       int    `json:"draws"`
+}
+
+// SuitScoreboardPayload is the per-room suit win/loss table
+type SuitScoreboardPayload struct {
+	Scores []SuitScore `json:"scores"`
+}
+
+// ScoreboardEntry is a persona's mini-game record within a room
+type ScoreboardEntry struct {
+	PersonaName   string `json:"persona_name"`
+	Wins          int    `json:"wins"`
+	Losses        int    `json:"losses"`
+	Draws         int    `json:"draws"`
+	CurrentStreak int    `json:"current_streak"` // consecutive wins
+	BestStreak    int    `json:"best_streak"`
+	Rolls         int    `json:"rolls"`
+	RollTotal     int    `json:"roll_total"`
+	BestRoll      int    `json:"best_roll"`
+	MaxRolls      int    `json:"max_rolls"` // rolls that hit the dice's max (e.g. natural 20)
+	Truths        int    `json:"truths"`
+	Dares         int    `json:"dares"`
+}
+
+// ScoreboardPayload is the room leaderboard, sorted by wins
+// A partial payload carries only the entries that changed, to be merged by persona_name
+type ScoreboardPayload struct {
+	Entries []ScoreboardEntry `json:"entries"`
+	Partial bool              `json:"partial,omitempty"`
 }
 
 // TodPayload is the payload for Truth or Dare
@@ -223,11 +452,20 @@ type TodPayload struct {
 
 // PollPayload is the payload for polls
 type PollPayload struct {
-	Question string         `json:"question"`
-	Options  []string       `json:"options"`
-	Votes    map[string]int `json:"votes,omitempty"`  // option -> count
-	Voters   []string       `json:"voters,omitempty"` // who voted
-	PollID   string         `json:"poll_id"`
+	Question    string         `json:"question"`
+	Options     []string       `json:"options"`
+	Votes       map[string]int `json:"votes,omitempty"`  // option -> count
+	Voters      []string       `json:"voters,omitempty"` // who voted
+	PollID      string         `json:"poll_id"`
+	CreatorID   string         `json:"creator_id,omitempty"`
+	AllowChange bool           `json:"allow_change,omitempty"` // voters may change their vote
+	DurationSec int            `json:"duration_sec,omitempty"` // auto-close after N seconds (0 = manual)
+	Closed      bool           `json:"closed,omitempty"`
+}
+
+// PollClosePayload is the payload for closing a poll
+type PollClosePayload struct {
+	PollID string `json:"poll_id"`
 }
 
 // VotePayload is the payload for poll votes
@@ -0,0 +1,94 @@
+package domain
+
+// ProtocolVersion is the wire protocol version spoken by this server
+// Version 1 is the original envelope; clients that never send hello are treated as v1
+const ProtocolVersion = 2
+
+// Feature is an optional protocol capability negotiated in the hello handshake
+type Feature string
+
+const (
+	FeatureErrors      Feature = "errors"      // error and ack frames
+	FeaturePolls       Feature = "polls"       // Authoritative poll tallies
+	FeatureScoreboards Feature = "scoreboards" // Suit and mini-game leaderboards
+	FeatureInvites     Feature = "invites"     // Room invite tokens
+	FeatureLobby       Feature = "lobby"       // Knock-to-enter waiting room
+	FeatureBans        Feature = "bans"        // Ban list for hosts
+	FeaturePresence    Feature = "presence"    // Versioned online list snapshot and deltas
+	FeatureHistory     Feature = "history"     // Paged history_request
+)
+
+// FeatureFrames lists the server-sent frame types each feature introduces
+// Frame types not listed here are part of v1 and go to every client
+var FeatureFrames = map[Feature][]MessageType{
+	FeatureErrors:      {MessageTypeError, MessageTypeAck},
+	FeaturePolls:       {MessageTypePollUpdate},
+	FeatureScoreboards: {MessageTypeSuitScoreboard, MessageTypeScoreboard},
+	FeatureInvites:     {MessageTypeInvite},
+	FeatureLobby:       {MessageTypeKnock, MessageTypeLobbySync},
+	FeatureBans:        {MessageTypeBanList},
+	FeaturePresence:    {MessageTypePresenceSnapshot, MessageTypeUserDelta},
+	FeatureHistory:     {MessageTypeHistoryPage},
+}
+
+// frameFeatures is the reverse index of FeatureFrames
+var frameFeatures = func() map[MessageType]Feature {
+	index := make(map[MessageType]Feature)
+	for feature, types := range FeatureFrames {
+		for _, t := range types {
+			index[t] = feature
+		}
+	}
+	return index
+}()
+
+// FrameFeature returns the feature a frame type belongs to, or false for v1 frames
+func FrameFeature(t MessageType) (Feature, bool) {
+	feature, ok := frameFeatures[t]
+	return feature, ok
+}
+
+// ServerFeatures returns every feature this server has enabled
+func ServerFeatures() []Feature {
+	return []Feature{
+		FeatureErrors,
+		FeaturePolls,
+		FeatureScoreboards,
+		FeatureInvites,
+		FeatureLobby,
+		FeatureBans,
+		FeaturePresence,
+		FeatureHistory,
+	}
+}
+
+// HelloPayload is the handshake payload
+// Clients send version and features; the server replies with its own plus limits
+type HelloPayload struct {
+	Version  int             `json:"version"`
+	Features []Feature       `json:"features"`
+	Limits   *ProtocolLimits `json:"limits,omitempty"` // Server reply only
+
+	// LastSeq resumes history like the last_seq URL parameter
+	// Only used if history has not been sent yet (e.g. still waiting in the lobby)
+	LastSeq uint64 `json:"last_seq,omitempty"`
+}
+
+// ProtocolLimits advertises server-side limits in the hello reply
+type ProtocolLimits struct {
+	MaxMessageSize int `json:"max_message_size"` // Bytes per inbound frame
+	MaxHistorySize int `json:"max_history_size"` // Messages replayed on join
+}
+
+// SessionTokenPayload carries the reconnect token issued on connect
+type SessionTokenPayload struct {
+	Token string `json:"token"`
+}
+
+// AuthPayload is the first frame of a connection opened with ?auth=frame
+// An empty token asks for a fresh persona; a ticket or invite admits it to a protected room
+type AuthPayload struct {
+	Token  string `json:"token,omitempty"`
+	Ticket string `json:"ticket,omitempty"`
+	Invite string `json:"invite,omitempty"`
+}
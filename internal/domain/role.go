@@ -0,0 +1,41 @@
+package domain
+
+// Role is a user's authority level within a room
+type Role string
+
+const (
+	RoleOwner     Role = "owner"     // Room host, one per room
+	RoleModerator Role = "moderator" // Co-host granted by the owner
+	RoleMember    Role = "member"    // Everyone else
+)
+
+// Permission is a privileged action gated by role
+type Permission string
+
+const (
+	PermMusic     Permission = "music"     // Control the shared music player
+	PermNobar     Permission = "nobar"     // Control nobar (watch together)
+	PermKick      Permission = "kick"      // Kick, ban, mute and time out lower roles
+	PermParty     Permission = "party"     // Change party mode
+	PermApprovals Permission = "approvals" // Approve requests, invites and waiting clients
+)
+
+// DefaultRolePermissions is the permission set each role starts with
+// Approvals stay with the owner, who decides who and what gets into the room
+var DefaultRolePermissions = map[Role][]Permission{
+	RoleOwner:     {PermMusic, PermNobar, PermKick, PermParty, PermApprovals},
+	RoleModerator: {PermMusic, PermNobar, PermKick, PermParty},
+	RoleMember:    {},
+}
+
+// Rank orders roles so moderators cannot act on the owner or each other
+func (r Role) Rank() int {
+	switch r {
+	case RoleOwner:
+		return 2
+	case RoleModerator:
+		return 1
+	default:
+		return 0
+	}
+}
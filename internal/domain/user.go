@@ -23,3 +23,10 @@ func NewUser(personaName, personaColor string) *User {
 		BatteryLevel: -1, // -1 means unknown/not shared
 	}
 }
+
+// RestoreUser recreates a User with the ID it had before reconnecting
+func RestoreUser(id uuid.UUID, personaName, personaColor string) *User {
+	user := NewUser(personaName, personaColor)
+	user.ID = id
+	return user
+}
@@ -0,0 +1,160 @@
+// Package handoff passes in-memory state from a process that is being replaced to its successor.
+//
+// The new process binds the HTTP port alongside the old one (SO_REUSEPORT), then
+// connects to the old process's Unix socket. The old process streams its state over
+// the socket and, once the new process has restored it and acknowledged, stops
+// accepting HTTP connections and disconnects its clients so they reconnect to the
+// new process. Without an ack the old process keeps serving and listens again for
+// the next attempt, while the new process exits rather than serve without the rooms.
+// Nothing is written to disk but the socket file itself.
+//
+// The socket carries every session token, so only the same user may use it: the
+// file is created 0600 and, on Linux, both ends check the peer's uid. Abstract
+// socket names (leading "@") are refused, because they have no file permissions
+// and any local user could bind or connect to them.
+package handoff
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// transferTimeout bounds the whole exchange, so a stuck peer cannot hold a deploy
+	transferTimeout = 30 * time.Second
+
+	// ack is the byte the new process sends once it has restored the state
+	ack = 1
+)
+
+var (
+	// ErrNoPeer means no running process offered its state (e.g. the first start)
+	ErrNoPeer = errors.New("no process to take over from")
+
+	// ErrUntrustedPeer means the other end of the socket runs as a different user
+	ErrUntrustedPeer = errors.New("handoff peer runs as another user")
+
+	// ErrAbstractSocket means the socket path names the abstract namespace
+	ErrAbstractSocket = errors.New("abstract handoff sockets are not supported")
+)
+
+// Receive takes over from the process serving on socketPath, passing its state to restore
+// restore runs before the old process disconnects its clients, so they reconnect into restored state
+func Receive(socketPath string, restore func(io.Reader) error) error {
+	if err := checkPath(socketPath); err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("unix", socketPath, time.Second)
+	if err != nil {
+		return ErrNoPeer
+	}
+	defer conn.Close()
+	if err := checkPeer(conn); err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(transferTimeout))
+
+	if err := restore(conn); err != nil {
+		return err
+	}
+	_, err = conn.Write([]byte{ack})
+	return err
+}
+
+// Server waits for the next process to ask for this one's state
+type Server struct {
+	ln       net.Listener
+	requests chan net.Conn
+}
+
+// Serve starts listening on socketPath for a successor
+// A stale socket file left by a crashed process is removed first
+func Serve(socketPath string) (*Server, error) {
+	if err := checkPath(socketPath); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	// Connecting needs write permission on the file; the peer check covers the moment before this
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+
+	s := &Server{
+		ln:       ln,
+		requests: make(chan net.Conn, 1),
+	}
+	go s.acceptLoop()
+	return s, nil
+}
+
+// acceptLoop hands the first trusted successor to Requests and stops listening
+// After a failed Send, the old process calls Serve again to wait for the next attempt
+func (s *Server) acceptLoop() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		if err := checkPeer(conn); err != nil {
+			conn.Close()
+			continue
+		}
+		// The successor binds the same socket once it is done, for the deploy after it
+		s.ln.Close()
+		s.requests <- conn
+		return
+	}
+}
+
+// Requests delivers the connection of a successor asking for state
+func (s *Server) Requests() <-chan net.Conn {
+	return s.requests
+}
+
+// Send streams this process's state to the successor and waits for it to be restored
+func (s *Server) Send(conn net.Conn, write func(io.Writer) error) error {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(transferTimeout))
+
+	if err := write(conn); err != nil {
+		return err
+	}
+	if uc, ok := conn.(*net.UnixConn); ok {
+		uc.CloseWrite()
+	}
+
+	reply := make([]byte, 1)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != ack {
+		return errors.New("successor did not restore the state")
+	}
+	return nil
+}
+
+// checkPath refuses socket names outside the filesystem
+func checkPath(socketPath string) error {
+	if strings.HasPrefix(socketPath, "@") {
+		return fmt.Errorf("%w: %s", ErrAbstractSocket, socketPath)
+	}
+	return nil
+}
+
+// Close stops waiting for a successor
+func (s *Server) Close() error {
+	return s.ln.Close()
+}
@@ -0,0 +1,156 @@
+package handoff
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestReceive_NoPeer(t *testing.T) {
+	err := Receive(filepath.Join(t.TempDir(), "handoff.sock"), func(io.Reader) error {
+		t.Error("restore must not run without a peer")
+		return nil
+	})
+	if !errors.Is(err, ErrNoPeer) {
+		t.Errorf("Expected ErrNoPeer, got %v", err)
+	}
+}
+
+func TestServe_SendsStateToSuccessor(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "handoff.sock")
+	server, err := Serve(socket)
+	if err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	defer server.Close()
+
+	sent := make(chan error, 1)
+	go func() {
+		conn := <-server.Requests()
+		sent <- server.Send(conn, func(w io.Writer) error {
+			_, err := io.WriteString(w, "rooms")
+			return err
+		})
+	}()
+
+	var got []byte
+	err = Receive(socket, func(r io.Reader) error {
+		got, err = io.ReadAll(r)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if string(got) != "rooms" {
+		t.Errorf("Expected state %q, got %q", "rooms", got)
+	}
+	if err := <-sent; err != nil {
+		t.Errorf("Expected Send to see the ack, got %v", err)
+	}
+
+	// The old process let go of the socket, so the successor can serve the next deploy
+	successor, err := Serve(socket)
+	if err != nil {
+		t.Fatalf("Expected the socket to be free after a handoff: %v", err)
+	}
+	successor.Close()
+}
+
+func TestServe_SendFailsWhenSuccessorGivesUp(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "handoff.sock")
+	server, err := Serve(socket)
+	if err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	defer server.Close()
+
+	sent := make(chan error, 1)
+	go func() {
+		conn := <-server.Requests()
+		sent <- server.Send(conn, func(w io.Writer) error {
+			_, err := io.WriteString(w, "rooms")
+			return err
+		})
+	}()
+
+	err = Receive(socket, func(io.Reader) error {
+		return errors.New("cannot restore")
+	})
+	if err == nil {
+		t.Fatal("Expected the restore error")
+	}
+	if err := <-sent; err == nil {
+		t.Error("Expected Send to fail without an ack")
+	}
+
+	// The old process listens again, so the next successor can still take over
+	retry, err := Serve(socket)
+	if err != nil {
+		t.Fatalf("Expected to serve again after a failed handoff: %v", err)
+	}
+	defer retry.Close()
+	go func() {
+		conn := <-retry.Requests()
+		retry.Send(conn, func(w io.Writer) error {
+			_, err := io.WriteString(w, "rooms")
+			return err
+		})
+	}()
+	if err := Receive(socket, func(r io.Reader) error {
+		_, err := io.ReadAll(r)
+		return err
+	}); err != nil {
+		t.Errorf("Expected the second attempt to succeed, got %v", err)
+	}
+}
+
+func TestServe_SocketIsPrivate(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "handoff.sock")
+	server, err := Serve(socket)
+	if err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	defer server.Close()
+
+	info, err := os.Stat(socket)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("Expected socket mode 0600, got %o", mode)
+	}
+}
+
+func TestServe_RejectsAbstractSocket(t *testing.T) {
+	if _, err := Serve("@goat-chat-handoff"); !errors.Is(err, ErrAbstractSocket) {
+		t.Errorf("Expected ErrAbstractSocket from Serve, got %v", err)
+	}
+	err := Receive("@goat-chat-handoff", func(io.Reader) error {
+		t.Error("restore must not run over an abstract socket")
+		return nil
+	})
+	if !errors.Is(err, ErrAbstractSocket) {
+		t.Errorf("Expected ErrAbstractSocket from Receive, got %v", err)
+	}
+}
+
+func TestListen_SharesPort(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("SO_REUSEPORT is only set on Linux")
+	}
+
+	first, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer first.Close()
+
+	second, err := Listen(first.Addr().String())
+	if err != nil {
+		t.Fatalf("Expected a successor to bind the same port: %v", err)
+	}
+	second.Close()
+}
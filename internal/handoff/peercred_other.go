@@ -0,0 +1,10 @@
+//go:build !linux
+
+package handoff
+
+import "net"
+
+// checkPeer trusts the socket file's 0600 mode; peer credentials are only read on Linux
+func checkPeer(net.Conn) error {
+	return nil
+}
@@ -0,0 +1,30 @@
+//go:build !mips && !mipsle && !mips64 && !mips64le
+
+package handoff
+
+import (
+	"context"
+	"net"
+	"syscall"
+)
+
+// soReusePort is SO_REUSEPORT, which the frozen syscall package does not define for Linux
+// (MIPS numbers it differently and falls back to reuseport_other.go)
+const soReusePort = 0xf
+
+// Listen opens a TCP listener with SO_REUSEPORT, so a successor can bind the port before this process lets go
+func Listen(addr string) (net.Listener, error) {
+	lc := net.ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			var sockErr error
+			err := c.Control(func(fd uintptr) {
+				sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soReusePort, 1)
+			})
+			if err != nil {
+				return err
+			}
+			return sockErr
+		},
+	}
+	return lc.Listen(context.Background(), "tcp", addr)
+}
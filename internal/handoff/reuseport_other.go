@@ -0,0 +1,10 @@
+//go:build !linux || mips || mipsle || mips64 || mips64le
+
+package handoff
+
+import "net"
+
+// Listen opens a plain TCP listener; without SO_REUSEPORT the successor can only bind once this process exits
+func Listen(addr string) (net.Listener, error) {
+	return net.Listen("tcp", addr)
+}
@@ -1,10 +1,12 @@
 package middleware
 
 import (
+	"net"
 	"net/http"
 	"sync"
 	"time"
 
+	"github.com/mmuslimabdulj/goat-chat/internal/config"
 	"golang.org/x/time/rate"
 )
 
@@ -15,6 +17,8 @@ type IPRateLimiter struct {
 	rate     rate.Limit
 	burst    int
 	cleanup  time.Duration
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
 // NewIPRateLimiter creates a new IP-based rate limiter
@@ -25,6 +29,7 @@ func NewIPRateLimiter(r rate.Limit, b int) *IPRateLimiter {
 		rate:     r,
 		burst:    b,
 		cleanup:  5 * time.Minute,
+		stop:     make(chan struct{}),
 	}
 	
 	// Cleanup old entries periodically
@@ -52,12 +57,29 @@ func (l *IPRateLimiter) Allow(ip string) bool {
 	return l.GetLimiter(ip).Allow()
 }
 
+// Blocked reports whether the IP has exhausted its burst, without consuming a token
+func (l *IPRateLimiter) Blocked(ip string) bool {
+	return l.GetLimiter(ip).Tokens() < 1
+}
+
+// Stop ends the limiter's cleanup goroutine
+// Safe to call more than once
+func (l *IPRateLimiter) Stop() {
+	l.stopOnce.Do(func() { close(l.stop) })
+}
+
 // cleanupLoop removes old limiters to prevent memory leaks
 func (l *IPRateLimiter) cleanupLoop() {
 	ticker := time.NewTicker(l.cleanup)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+
 		l.mu.Lock()
 		// Simple cleanup: remove all and let them be recreated
 		// In production, you might want to track last access time
@@ -86,6 +108,16 @@ func getIP(r *http.Request) string {
 	return r.RemoteAddr
 }
 
+// ClientIP extracts the client IP from the request, without the port
+// so a ban on it also covers the client's next connection
+func ClientIP(r *http.Request) string {
+	ip := getIP(r)
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		return host
+	}
+	return ip
+}
+
 // RateLimitMiddleware creates a middleware that rate limits requests
 func RateLimitMiddleware(limiter *IPRateLimiter) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -116,14 +148,31 @@ func RateLimitFunc(limiter *IPRateLimiter, next http.HandlerFunc) http.HandlerFu
 	}
 }
 
-// Default rate limiters for different purposes
+// Default rate limiters for different purposes, built from config.AppConfig
 var (
-	// APILimiter: 10 requests per second, burst of 20
-	APILimiter = NewIPRateLimiter(10, 20)
-	
-	// WebSocketLimiter: 5 connections per second, burst of 10
-	WebSocketLimiter = NewIPRateLimiter(5, 10)
-	
-	// StrictLimiter: 2 requests per second, burst of 5 (for sensitive operations)
-	StrictLimiter = NewIPRateLimiter(2, 5)
+	// APILimiter: RATE_LIMIT_API requests per second
+	APILimiter *IPRateLimiter
+
+	// WebSocketLimiter: RATE_LIMIT_WS connections per second
+	WebSocketLimiter *IPRateLimiter
+
+	// StrictLimiter: RATE_LIMIT_STRICT requests per second (for sensitive operations)
+	StrictLimiter *IPRateLimiter
 )
+
+func init() {
+	ConfigureLimiters(config.AppConfig)
+}
+
+// ConfigureLimiters rebuilds the default limiters from cfg, stopping the ones it replaces
+// NOTE: Call before handlers capture the limiters (e.g. after reloading config in main)
+func ConfigureLimiters(cfg *config.Config) {
+	for _, old := range []*IPRateLimiter{APILimiter, WebSocketLimiter, StrictLimiter} {
+		if old != nil {
+			old.Stop()
+		}
+	}
+	APILimiter = NewIPRateLimiter(cfg.RateLimitAPI, cfg.RateLimitAPIBurst)
+	WebSocketLimiter = NewIPRateLimiter(cfg.RateLimitWS, cfg.RateLimitWSBurst)
+	StrictLimiter = NewIPRateLimiter(cfg.RateLimitStrict, cfg.RateLimitStrictBurst)
+}
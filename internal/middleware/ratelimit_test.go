@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/mmuslimabdulj/goat-chat/internal/config"
 	"golang.org/x/time/rate"
 )
 
@@ -195,6 +196,15 @@ func TestGetIP_RemoteAddr(t *testing.T) {
 	}
 }
 
+func TestClientIP_StripsPort(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+
+	if ip := ClientIP(req); ip != "192.168.1.1" {
+		t.Errorf("Expected IP without port, got %s", ip)
+	}
+}
+
 func TestDefaultLimiters(t *testing.T) {
 	// Check that default limiters are initialized
 	if APILimiter == nil {
@@ -207,3 +217,19 @@ func TestDefaultLimiters(t *testing.T) {
 		t.Error("StrictLimiter should be initialized")
 	}
 }
+
+func TestConfigureLimiters_StopsReplacedLimiters(t *testing.T) {
+	old := []*IPRateLimiter{APILimiter, WebSocketLimiter, StrictLimiter}
+	ConfigureLimiters(config.AppConfig)
+
+	for _, l := range old {
+		select {
+		case <-l.stop:
+		default:
+			t.Error("Expected replaced limiter to be stopped")
+		}
+	}
+	if APILimiter == old[0] {
+		t.Error("Expected a fresh API limiter")
+	}
+}
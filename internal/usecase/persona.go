@@ -5,6 +5,7 @@ import (
 	"math/rand"
 	"sync"
 
+	"github.com/google/uuid"
 	"github.com/mmuslimabdulj/goat-chat/internal/domain"
 )
 
@@ -126,16 +127,24 @@ func (pg *PersonaGenerator) Generate() *domain.User {
 	return domain.NewUser(name, color)
 }
 
-// GenerateWithPersona creates a user with an existing persona name and color
-// Used when a user reconnects/refreshes and wants to keep their persona
-func (pg *PersonaGenerator) GenerateWithPersona(name string, color string) *domain.User {
+// GenerateWithPersona recreates a user with an existing ID, persona name and color
+// Used when a user reconnects/refreshes and wants to keep their identity
+func (pg *PersonaGenerator) GenerateWithPersona(id uuid.UUID, name string, color string) *domain.User {
 	pg.mu.Lock()
 	defer pg.mu.Unlock()
 
 	// Mark this persona as existing
 	pg.existing[name] = true
 
-	return domain.NewUser(name, color)
+	return domain.RestoreUser(id, name, color)
+}
+
+// Reserve marks a persona as taken without creating a user
+// Used for users handed over from a previous process who have not reconnected yet
+func (pg *PersonaGenerator) Reserve(name string) {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+	pg.existing[name] = true
 }
 
 // Release removes a persona from the active set
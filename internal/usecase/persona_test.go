@@ -55,6 +55,18 @@ func TestPersonaGenerator_Release(t *testing.T) {
 	}
 }
 
+func TestPersonaGenerator_Reserve(t *testing.T) {
+	pg := NewPersonaGenerator()
+
+	pg.Reserve("Kambing Galak")
+	if !pg.existing["Kambing Galak"] {
+		t.Error("Expected reserved name to be marked as existing")
+	}
+	if pg.ActiveCount() != 1 {
+		t.Errorf("Expected 1 active persona, got %d", pg.ActiveCount())
+	}
+}
+
 func TestPersonaGenerator_Concurrency(t *testing.T) {
 	pg := NewPersonaGenerator()
 	
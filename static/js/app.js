@@ -1,10 +1,9 @@
 import { WebSocketClient } from './modules/ws.js';
 import { UIManager } from './modules/ui.js';
-import * as Game from './modules/game.js';
 import { searchGifs } from './modules/gif.js';
 
 // ============ MODULAR IMPORTS ============
-import { RAVE_BPM_INTERVALS, RAVE_EMOJI_LIFETIME_MS, RAVE_EMOJIS } from './modules/constants.js';
+import { RAVE_BPM_INTERVALS, RAVE_EMOJI_LIFETIME_MS, RAVE_EMOJIS, PROTOCOL_VERSION, PROTOCOL_FEATURES } from './modules/constants.js';
 import { extractYoutubeVideoId, formatDuration } from './modules/helpers.js';
 import { partyMixin } from './modules/party.js';
 
@@ -85,6 +84,13 @@ window.chatApp = function () {
         typingUsers: new Set(),
         typingTimeouts: {},
         connected: false,
+        serverLimits: null, // From the hello handshake
+        presenceVersion: null, // Last applied presence version (null until a snapshot)
+        lastSeq: 0, // Newest room history seq seen, sent on reconnect to skip replayed history
+        oldestSeq: 0, // Oldest room history seq loaded, cursor for scrolling back
+        historyHasMore: true,
+        historyLoading: false,
+        historyChunks: [], // history_page chunks collected until the final one
         isKicked: false,
         hasNewMessages: false,
         unreadDividerIndex: -1,
@@ -169,6 +175,7 @@ window.chatApp = function () {
             { cmd: '/tod', desc: 'Truth or Dare acak', example: '/tod' },
             { cmd: '/poll', desc: 'Buat voting', example: '/poll Makan apa?|Nasi|Mie' },
             { cmd: '/suit', desc: 'Main suit', example: '/suit @nama' },
+            { cmd: '/score', desc: 'Papan skor room', example: '/score' },
             { cmd: '/yt', desc: 'Share video YouTube', example: '/yt url' },
             { cmd: '/ytm', desc: 'Request musik (Host approve)', example: '/ytm url' },
             { cmd: '/nobar', desc: 'Nonton bareng video', example: '/nobar url' },
@@ -192,6 +199,8 @@ window.chatApp = function () {
         polls: {},
         suitChallenges: {},
         suitTimers: {},
+        scoreboard: [], // Room leaderboard, kept sorted like the server's
+        suitScores: [],
         timeRemaining: {},
 
         // Confirm Modal State
@@ -255,6 +264,11 @@ window.chatApp = function () {
             // 4. Lifecycle Listeners
             window.addEventListener('beforeunload', () => this.wsClient?.close());
 
+            // Page in older history when scrolled to the top
+            document.getElementById('messages')?.addEventListener('scroll', (e) => {
+                if (e.target.scrollTop < 50) this.loadOlderHistory();
+            });
+
             // 5. Battery & Permissions (Lazy Init)
             // Modern browsers require interaction to read accurate battery/audio contexts
             const unlockFeatures = () => {
@@ -400,19 +414,41 @@ window.chatApp = function () {
             if (!this.roomCode) return; // Don't connect if not in a room
 
             const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
-            let url = `${protocol}//${window.location.host}/ws?room=${this.roomCode}`;
-
-            // Use session token for secure reconnection (instead of exposing persona in URL)
-            const sessionToken = sessionStorage.getItem('sessionToken');
-            if (sessionToken) {
-                url += `&token=${encodeURIComponent(sessionToken)}`;
-            }
+            const url = `${protocol}//${window.location.host}/ws?room=${this.roomCode}`;
+
+            // Protected rooms: take the join ticket or invite off the page URL,
+            // so it stays out of history, and offer it as a subprotocol instead
+            const pageParams = new URLSearchParams(window.location.search);
+            const accessProtocols = [];
+            for (const key of ['ticket', 'invite']) {
+                const value = pageParams.get(key);
+                if (value) accessProtocols.push(`goat.${key}.${value}`);
+                pageParams.delete(key);
+            }
+            if (accessProtocols.length) {
+                const query = pageParams.toString();
+                history.replaceState(null, '', window.location.pathname + (query ? `?${query}` : ''));
+            }
+
+            // Rebuilt on every (re)connect so the server only replays missed history
+            const connectUrl = () => this.lastSeq ? `${url}&last_seq=${this.lastSeq}` : url;
+
+            // Session token rides in the subprotocol list so it never lands in URLs or logs
+            // Tokens are single-use; the server sends a fresh one after every (re)connect
+            const connectProtocols = () => {
+                const sessionToken = sessionStorage.getItem('sessionToken');
+                const protocols = sessionToken ? [...accessProtocols, `goat.token.${sessionToken}`] : accessProtocols;
+                return protocols.length ? ['goat.json', ...protocols] : undefined;
+            };
 
             this.wsClient = new WebSocketClient(
-                url,
+                connectUrl,
                 (msg) => this.handleMessage(msg),
                 () => {
                     this.connected = true;
+                    this.presenceVersion = null; // New connection, wait for its snapshot
+                    // Declare protocol version and features before anything else
+                    this.wsClient.send({ type: 'hello', payload: { version: PROTOCOL_VERSION, features: PROTOCOL_FEATURES, last_seq: this.lastSeq || undefined } });
                     this.showToast('Terhubung ke server', '✅', 'success', 2000);
 
                     // Wait for 'user_join' (self) event to enable appReady (live mode)
@@ -478,7 +514,8 @@ window.chatApp = function () {
                         clearInterval(this.nobarSyncInterval);
                         this.nobarSyncInterval = null;
                     }
-                }
+                },
+                connectProtocols
             );
 
             this.wsClient.connect();
@@ -597,10 +634,16 @@ window.chatApp = function () {
                 }
             }
 
+            if (msg.seq && msg.seq > this.lastSeq) this.lastSeq = msg.seq;
+            if (msg.seq && (!this.oldestSeq || msg.seq < this.oldestSeq)) this.oldestSeq = msg.seq;
+
             const isLive = this.isLive(msg);
 
             // Dispatch to specific handlers
             switch (msg.type) {
+                case 'hello':
+                    this.serverLimits = msg.payload?.limits || null;
+                    break;
                 case 'session_token':
                     // Store secure session token for reconnection
                     if (msg.payload?.token) {
@@ -618,6 +661,8 @@ window.chatApp = function () {
                 case 'user_join': this.onUserJoin(msg, isLive); break;
                 case 'user_sync': this.onUserSync(msg); break;
                 case 'user_leave': this.onUserLeave(msg, isLive); break;
+                case 'user_delta': this.onUserDelta(msg); break;
+                case 'presence_snapshot': this.onPresenceSnapshot(msg); break;
                 case 'chat': this.onChat(msg, isLive); break;
                 case 'vibrate': if (isLive) this.onVibrate(msg); else this.addMessage(msg); break;
                 case 'chaos': if (isLive) this.onChaos(msg); else this.addMessage(msg); break;
@@ -640,9 +685,12 @@ window.chatApp = function () {
                 case 'whisper': this.onWhisper(msg, isLive); break;
                 case 'gif': this.onGif(msg); break;
                 case 'suit': this.onSuit(msg); break;
+                case 'scoreboard': this.onScoreboard(msg); break;
+                case 'suit_scoreboard': this.suitScores = msg.payload?.scores || []; break;
                 case 'tod': if (isLive) this.onTod(msg); else this.addMessage(msg); break;
                 case 'poll': this.onPoll(msg); break;
                 case 'vote': this.onVote(msg); break;
+                case 'poll_update': this.onPollUpdate(msg); break;
                 case 'typing': this.onTyping(msg); break;
                 case 'confetti': if (isLive) this.onConfetti(msg); break;
                 case 'tts': if (isLive) this.onTts(msg); else this.addMessage(msg); break;
@@ -693,6 +741,32 @@ window.chatApp = function () {
                 case 'status_update':
                     this.updateUserStatus(msg.from_id, msg.payload);
                     break;
+                case 'knock':
+                    if (msg.payload.status === 'waiting') {
+                        this.showToast(`Menunggu persetujuan host (antrian #${msg.payload.position})`, '🚪', 'info', 4000);
+                    } else if (msg.payload.status === 'denied') {
+                        this.showToast(msg.payload.reason || 'Permintaan masuk ditolak', '⛔', 'error', 5000);
+                    }
+                    break;
+                case 'error':
+                    if (isLive && msg.payload) {
+                        this.showToast(msg.payload.message || 'Terjadi kesalahan', '⚠️', 'error', 3000);
+                    }
+                    break;
+                case 'ack':
+                    break;
+                case 'history_page':
+                    this.onHistoryPage(msg);
+                    break;
+                case 'history_truncated':
+                    this.showToast('Sebagian riwayat chat terlewat saat offline', 'ℹ️', 'info', 3000);
+                    break;
+                case 'lobby_sync':
+                    this.lobby = msg.payload.waiting || [];
+                    if (this.lobby.length > 0) {
+                        this.showToast(`${this.lobby.length} orang menunggu di luar`, '🚪', 'info', 3000);
+                    }
+                    break;
 
                 default:
                     this.addMessage(msg);
@@ -714,6 +788,78 @@ window.chatApp = function () {
             }
         },
 
+        loadOlderHistory() {
+            if (this.historyLoading || !this.historyHasMore || this.oldestSeq <= 1) return;
+            this.historyLoading = true;
+            this.wsClient.send({ type: 'history_request', payload: { before: this.oldestSeq, limit: 50 } });
+        },
+
+        onHistoryPage(msg) {
+            const p = msg.payload;
+            this.historyChunks.push(...(p.messages || []));
+            if (!p.final) return;
+
+            // Prepend older messages without jumping the scroll position
+            const older = this.historyChunks.filter(m => !m.id || !this.seenIds.has(m.id));
+            this.historyChunks = [];
+            older.forEach(m => m.id && this.seenIds.add(m.id));
+
+            const container = document.getElementById('messages');
+            const prevHeight = container ? container.scrollHeight : 0;
+            this.messages = [...older, ...this.messages];
+            if (this.unreadDividerIndex >= 0) this.unreadDividerIndex += older.length;
+            if (container) {
+                setTimeout(() => {
+                    container.scrollTop += container.scrollHeight - prevHeight;
+                }, 50);
+            }
+
+            if (older.length && older[0].seq) this.oldestSeq = older[0].seq;
+            if (p.next) this.oldestSeq = p.next;
+            this.historyHasMore = p.has_more;
+            this.historyLoading = false;
+        },
+
+        onPresenceSnapshot(msg) {
+            const p = msg.payload;
+            this.users = (p.users || []).map(u => ({
+                id: u.id, persona: u.persona, color: u.color,
+                battery: u.battery || -1
+            }));
+            this.userCount = p.user_count || this.users.length;
+            if (p.host_id) this.hostId = p.host_id;
+            this.presenceVersion = p.version;
+        },
+
+        onUserDelta(msg) {
+            const p = msg.payload;
+            if (this.presenceVersion === null) return; // Snapshot still on its way
+            if (p.version <= this.presenceVersion) return; // Already applied
+            if (p.version !== this.presenceVersion + 1) {
+                // Missed a delta; ask for a fresh snapshot
+                this.presenceVersion = null;
+                this.wsClient.send({ type: 'presence_resync', payload: {} });
+                return;
+            }
+            this.presenceVersion = p.version;
+
+            // Patch the list in place instead of replacing it
+            const toUser = u => ({
+                id: u.id, persona: u.persona, color: u.color,
+                battery: u.battery || -1
+            });
+            if (p.removed) {
+                this.users = this.users.filter(u => !p.removed.includes(u.id));
+            }
+            for (const u of [...(p.added || []), ...(p.updated || [])]) {
+                const i = this.users.findIndex(x => x.id === u.id);
+                if (i >= 0) this.users.splice(i, 1, toUser(u));
+                else this.users.push(toUser(u));
+            }
+            this.userCount = p.user_count || this.userCount;
+            if (p.host_id) this.hostId = p.host_id;
+        },
+
         onUserJoin(msg, isLive) {
             const p = msg.payload;
             if (!this.myId) {
@@ -876,48 +1022,27 @@ window.chatApp = function () {
         },
 
         onSuit(msg) {
+            // The server owns the match; frames carry its authoritative state
             const p = msg.payload;
+            const existing = this.suitChallenges[p.id];
 
-            // Merge Logic: Combine incoming moves with existing local state
-            // to prevent race conditions where one client overwrites another's move.
-            if (this.suitChallenges[p.id]) {
-                const existing = this.suitChallenges[p.id];
-                if (existing.moves && p.moves) {
-                    p.moves = { ...existing.moves, ...p.moves };
-                } else if (existing.moves && !p.moves) {
-                    p.moves = existing.moves;
-                }
-
-                // If local state is already completed, prefer it (prevents reverting to pending)
-                if (existing.status === 'completed' && p.status === 'pending') {
-                    p.status = 'completed';
-                    p.winner = existing.winner;
-                }
-            }
-
+            // Only the private ack to a player carries their own move before the reveal
+            const mine = p.challenger_id === this.myId ? p.challenger_move
+                : (p.opponent_id === this.myId ? p.opponent_move : '');
+            p.my_move = mine || existing?.my_move || '';
+            p.timeLeft = existing?.timeLeft;
             this.suitChallenges[p.id] = p;
 
-            if (p.status === 'pending') {
-                if (!this.suitTimers[p.id]) this.startSuitTimer(p.id, p.expiry);
-
-                // Robust Resolve: Any participant can resolve if both moved (prevents stuck game if Host disconnects)
-                if (p.moves && Object.keys(p.moves).length >= 2) {
-                    // Check if I am a participant (Challenger or Opponent) to trigger resolve
-                    if (this.myId === p.challenger_id || this.myId === p.opponent_id) {
-                        this.resolveSuitGame(p);
-                    }
-                }
-            } else if (p.status === 'completed') {
-                // Ensure timer stops
-                if (this.suitTimers[p.id]) {
-                    clearInterval(this.suitTimers[p.id]);
-                    delete this.suitTimers[p.id];
-                }
-                if (this.appReady) this.ui.playSound('suit');
+            const live = p.status === 'pending' || p.status === 'accepted';
+            if (live && p.expires_at) {
+                this.startSuitTimer(p.id, Date.parse(p.expires_at));
+            } else if (!live) {
+                this.stopSuitTimer(p.id);
+                if (p.status === 'completed' && this.appReady) this.ui.playSound('suit');
             }
 
             // Update message in place
-            const existingIdx = this.messages.findIndex(m => m.payload?.id === p.id);
+            const existingIdx = this.messages.findIndex(m => m.type === 'suit' && m.payload?.id === p.id);
             if (existingIdx !== -1) {
                 this.messages[existingIdx] = msg;
             } else {
@@ -926,77 +1051,29 @@ window.chatApp = function () {
         },
 
         startSuitTimer(id, expiry) {
+            // Countdown only; the server expires or forfeits the match
+            this.stopSuitTimer(id);
             const update = () => {
-                const now = Date.now();
-                const left = Math.ceil((expiry - now) / 1000);
-
+                const left = Math.ceil((expiry - Date.now()) / 1000);
                 if (this.suitChallenges[id]) {
                     this.suitChallenges[id].timeLeft = left;
                 }
-
-                if (left === 0) {
-                    this.handleSuitTimeout(id, 'self');
-                }
-
-                if (left === -3) {
-                    this.handleSuitTimeout(id, 'force_opponent');
-                }
-
-                if (left <= -5 || (this.suitChallenges[id] && this.suitChallenges[id].status === 'completed')) {
-                    clearInterval(this.suitTimers[id]);
-                    delete this.suitTimers[id];
-                }
+                if (left <= 0) this.stopSuitTimer(id);
             };
 
             update();
             this.suitTimers[id] = setInterval(update, 1000);
         },
 
-        handleSuitTimeout(id, mode) {
-            const challenge = this.suitChallenges[id];
-            if (!challenge || challenge.status === 'completed') return;
-
-            const expiry = challenge.expiry || 0;
-            if (Date.now() > expiry + 10000) return;
-
-            const myMove = challenge.moves && challenge.moves[this.myId];
-            const moves = ['rock', 'paper', 'scissors'];
-            const randomMove = moves[Math.floor(Math.random() * moves.length)];
-
-            if (mode === 'self') {
-                const isParticipant = challenge.challenger_id === this.myId || challenge.opponent_id === this.myId;
-                if (isParticipant && !myMove) {
-                    this.sendSuitMove(randomMove, id);
-                }
-            } else if (mode === 'force_opponent') {
-                if (challenge.challenger_id === this.myId) {
-                    const oppId = challenge.opponent_id;
-                    if (!challenge.moves?.[oppId]) {
-                        this.sendSuitMove(randomMove, id, oppId);
-                    }
-                } else if (challenge.opponent_id === this.myId) {
-                    const chalId = challenge.challenger_id;
-                    if (!challenge.moves?.[chalId]) {
-                        this.sendSuitMove(randomMove, id, chalId);
-                    }
-                }
+        stopSuitTimer(id) {
+            if (this.suitTimers[id]) {
+                clearInterval(this.suitTimers[id]);
+                delete this.suitTimers[id];
             }
         },
 
-        resolveSuitGame(p) {
-            const m1 = p.moves[p.challenger_id];
-            const m2 = p.moves[p.opponent_id];
-            if (!m1 || !m2) return;
-            const winner = Game.determineSuitWinner(m1, m2, p.challenger_id, p.opponent_id);
-            this.wsClient.send({
-                type: 'suit',
-                payload: { ...p, status: 'completed', winner }
-            });
-        },
-
         hasMoved(id) {
-            const ch = this.suitChallenges[id];
-            return ch && ch.moves && !!ch.moves[this.myId];
+            return !!this.suitChallenges[id]?.my_move;
         },
 
         formatCountdown(expiry) {
@@ -1016,6 +1093,44 @@ window.chatApp = function () {
             this.addMessage(msg);
         },
 
+        onPollUpdate(msg) {
+            // Server-authoritative tally replaces any local state
+            const p = msg.payload;
+            this.polls[p.poll_id] = { ...(this.polls[p.poll_id] || {}), ...p };
+        },
+
+        onScoreboard(msg) {
+            const p = msg.payload || {};
+            const entries = p.entries || [];
+            if (!p.partial) {
+                this.scoreboard = entries;
+                return;
+            }
+
+            // Partial frames carry only the changed entries: merge them by persona
+            const merged = new Map(this.scoreboard.map(e => [e.persona_name, e]));
+            entries.forEach(e => merged.set(e.persona_name, e));
+            this.scoreboard = [...merged.values()].sort((a, b) =>
+                (b.wins - a.wins) ||
+                (b.best_streak - a.best_streak) ||
+                (b.rolls - a.rolls) ||
+                a.persona_name.localeCompare(b.persona_name)
+            );
+        },
+
+        showScoreboard() {
+            const top = this.scoreboard.slice(0, 5);
+            const text = top.length === 0
+                ? '🏆 Belum ada skor di room ini'
+                : '🏆 ' + top.map((e, i) => `${i + 1}. ${e.persona_name} (${e.wins}W/${e.losses}L, 🎲${e.rolls})`).join(' · ');
+            this.addMessage({
+                id: this.generateId(),
+                type: 'system',
+                from_name: text,
+                created_at: new Date()
+            });
+        },
+
         onVote(msg) {
             const { poll_id, option_index } = msg.payload;
             if (this.polls[poll_id]) {
@@ -1102,7 +1217,7 @@ window.chatApp = function () {
             if (text.startsWith('/')) {
                 this.processCommand(text);
             } else {
-                this.wsClient.send({ type: 'chat', payload: { text } });
+                this.wsClient.sendTracked({ type: 'chat', payload: { text } });
             }
 
             // Auto scroll to bottom after sending own message
@@ -1122,6 +1237,7 @@ window.chatApp = function () {
                 case '/spin': this.sendSpin(args); break;
                 case '/w': case '/whisper': this.sendWhisper(args); break;
                 case '/suit': this.sendSuitChallenge(args); break;
+                case '/score': this.showScoreboard(); break;
                 case '/tod': this.sendTod(); break;
                 case '/poll': this.sendPoll(args); break;
                 case '/confetti': this.sendConfetti(); break;
@@ -1133,17 +1249,17 @@ window.chatApp = function () {
                 case '/theme': this.setTheme(args); break;
                 case '/dev': case '/repo': case '/git': this.showRepoInfo(); break;
                 case '/help': this.showHelp(); break;
-                default: this.wsClient.send({ type: 'chat', payload: { text } });
+                default: this.wsClient.sendTracked({ type: 'chat', payload: { text } });
             }
         },
 
         sendDice(max) {
-            this.wsClient.send({ type: 'dice', payload: { max, result: Game.rollDice(max) } });
+            this.wsClient.send({ type: 'dice', payload: { max } });
         },
 
         sendFlip(text) {
             if (!text) return;
-            this.wsClient.send({ type: 'flip', payload: { original: text, flipped: Game.flipText(text) } });
+            this.wsClient.send({ type: 'flip', payload: { original: text } });
         },
 
         sendSpin(text) {
@@ -1313,43 +1429,30 @@ window.chatApp = function () {
             if (!target) { alert('User tidak ditemukan!'); return; }
             if (target.id === this.myId) { alert('Tidak bisa menantang diri sendiri!'); return; }
 
-            const challengeId = this.generateId();
-            const expiry = Date.now() + 15000; // 15 seconds (buffer)
-
             this.wsClient.send({
                 type: 'suit',
-                payload: {
-                    id: challengeId,
-                    challenger_id: this.myId, challenger_name: this.myPersona,
-                    opponent_id: target.id, opponent_name: target.persona,
-                    status: 'pending',
-                    expiry,
-                    moves: {}
-                }
+                payload: { action: 'challenge', opponent_id: target.id }
             });
         },
 
-        sendSuitMove(move, challengeId, playerId = null) {
-            const challenge = this.suitChallenges[challengeId];
-            if (!challenge) return;
+        respondSuit(challengeId, accept) {
+            this.wsClient.send({
+                type: 'suit',
+                payload: { action: accept ? 'accept' : 'decline', id: challengeId }
+            });
+        },
 
-            const uid = playerId || this.myId;
+        sendSuitMove(move, challengeId) {
+            if (!this.suitChallenges[challengeId] || this.hasMoved(challengeId)) return;
 
             this.wsClient.send({
                 type: 'suit',
-                payload: {
-                    ...challenge,
-                    moves: {
-                        ...challenge.moves,
-                        [uid]: move
-                    }
-                }
+                payload: { action: 'move', id: challengeId, move }
             });
         },
 
         sendTod() {
-            const result = Game.getRandomTod();
-            this.wsClient.send({ type: 'tod', payload: result });
+            this.wsClient.send({ type: 'tod', payload: {} });
         },
 
         sendPoll(args) {
@@ -1545,6 +1648,7 @@ window.chatApp = function () {
                         { cmd: '/spin teks', desc: 'Teks putar' },
                         { cmd: '/w @u msg', desc: 'Bisik' },
                         { cmd: '/suit @u', desc: 'Tantang suit' },
+                        { cmd: '/score', desc: 'Papan skor' },
                         { cmd: '/tod', desc: 'Truth or Dare' },
                         { cmd: '/poll Q?|A|B', desc: 'Buat poll' },
                         { cmd: '/yt [URL]', desc: 'Share YouTube' },
@@ -1625,6 +1729,11 @@ window.chatApp = function () {
         },
 
         clearRoomSession() {
+            // Revoke the reconnect token server-side so the persona cannot be resumed
+            if (this.wsClient) {
+                this.wsClient.send({ type: 'logout', payload: {} });
+            }
+            sessionStorage.removeItem('sessionToken');
             sessionStorage.removeItem('roomCode');
             sessionStorage.removeItem('roomName');
             sessionStorage.removeItem('myPersona');
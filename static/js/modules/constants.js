@@ -1,4 +1,8 @@
 // ============ CONSTANTS ============
+// Wire protocol spoken in the 'hello' handshake
+export const PROTOCOL_VERSION = 2;
+export const PROTOCOL_FEATURES = ['errors', 'polls', 'scoreboards', 'invites', 'lobby', 'bans', 'presence', 'history'];
+
 // Rave mode BPM intervals (ms between emoji spawns)
 export const RAVE_BPM_INTERVALS = {
     'rave-slow': 500,      // 80 BPM - chill
@@ -1,6 +1,7 @@
 export class WebSocketClient {
-    constructor(url, onMessage, onOpen, onClose) {
+    constructor(url, onMessage, onOpen, onClose, protocols) {
         this.url = url;
+        this.protocols = protocols; // Optional Sec-WebSocket-Protocol list (or function returning one)
         this.onMessage = onMessage;
         this.onOpen = onOpen;
         this.onClose = onClose;
@@ -10,6 +11,7 @@ export class WebSocketClient {
         this.isConnected = false;
         this.shouldReconnect = true;
         this.isKicked = false; // Permanent flag for kick
+        this.msgSeq = 0; // Counter for client_msg_id
     }
 
     connect() {
@@ -20,7 +22,10 @@ export class WebSocketClient {
             this.ws.close();
         }
 
-        this.ws = new WebSocket(this.url);
+        // url and protocols may be functions so reconnects can carry fresh parameters
+        const url = typeof this.url === 'function' ? this.url() : this.url;
+        const protocols = typeof this.protocols === 'function' ? this.protocols() : this.protocols;
+        this.ws = protocols ? new WebSocket(url, protocols) : new WebSocket(url);
 
         this.ws.onopen = () => {
             this.isConnected = true;
@@ -78,6 +83,13 @@ export class WebSocketClient {
         }
     }
 
+    // Send with a client_msg_id; the server answers with an 'ack' or 'error' frame carrying it
+    sendTracked(data) {
+        const clientMsgId = `${Date.now().toString(36)}-${++this.msgSeq}`;
+        this.send({ ...data, client_msg_id: clientMsgId });
+        return clientMsgId;
+    }
+
     disableReconnect() {
         this.shouldReconnect = false;
     }
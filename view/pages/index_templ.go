@@ -106,7 +106,7 @@ func Index(users []*domain.User, roomName string, roomCode string) templ.Compone
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "</div></div><!-- Messages Area --><div id=\"messages\" class=\"flex-1 overflow-y-auto p-4 space-y-2\" x-ref=\"messagesContainer\"><!-- Welcome Message --><div class=\"flex justify-center my-4\"><div class=\"px-4 py-2 bg-gray-200 rounded-full text-xs font-medium text-gray-600 border-2 border-black\">🎉 Selamat datang di GOAT Chat! Ketik /help untuk melihat commands.</div></div><!-- Messages --><template x-for=\"(msg, index) in messages\" :key=\"msg.id || index\"><div><!-- Unread Messages Divider --><div x-show=\"unreadDividerIndex === index\" class=\"flex items-center gap-3 my-4 px-2\"><div class=\"flex-1 h-1 bg-neon-pink rounded-full\"></div><span class=\"px-4 py-1.5 bg-neon-pink text-white text-xs font-bold rounded-full border-2 border-black shadow-brutal-sm whitespace-nowrap\">Pesan Baru ⬇️</span><div class=\"flex-1 h-1 bg-neon-pink rounded-full\"></div></div><!-- System Messages (user join/leave/system) --><div x-show=\"msg.type === 'user_join' || msg.type === 'user_leave' || msg.type === 'system'\" class=\"flex justify-center my-4\"><div class=\"px-4 py-2 bg-gray-200 rounded-full text-xs font-medium text-gray-600 border-2 border-black\"><span x-text=\"msg.type === 'user_join' ? '👋 ' + msg.from_name + ' bergabung!' : (msg.type === 'user_leave' ? '👻 ' + msg.from_name + ' pergi...' : msg.from_name)\"></span></div></div><!-- Repo Info Messages --><div x-show=\"msg.type === 'repo_info'\" class=\"flex justify-center my-4\"><div class=\"w-full max-w-md bg-white rounded-xl border-4 border-black shadow-brutal p-6 text-center\"><div class=\"text-4xl mb-2\">👨\u200d💻</div><div class=\"text-xl font-bold mb-2\">GOAT CHAT DEV</div><p class=\"text-xs text-gray-600 mb-4 px-4\" x-text=\"msg.payload?.desc\"></p><a :href=\"msg.payload?.url\" target=\"_blank\" class=\"inline-block w-full bg-black text-white font-bold py-3 rounded-lg border-2 border-transparent hover:bg-gray-800 hover:border-black transition-colors\">⭐ Buka GitHub Repository</a></div></div><!-- Help Messages --><div x-show=\"msg.type === 'help'\" class=\"flex justify-center my-4\"><div class=\"w-full max-w-md bg-white rounded-xl border-4 border-black shadow-brutal p-4\"><div class=\"text-lg font-bold mb-3 text-center\">📚 DAFTAR PERINTAH</div><div class=\"space-y-2\"><template x-for=\"cmd in msg.payload?.commands\" :key=\"cmd.cmd\"><div class=\"flex items-start gap-2 text-sm border-b border-gray-100 pb-2\"><code class=\"bg-gray-200 px-2 py-1 rounded font-mono text-xs shrink-0\" x-text=\"cmd.cmd\"></code> <span class=\"text-gray-600 flex-1\" x-text=\"cmd.desc\"></span><code class=\"bg-green-100 text-green-700 px-1 rounded text-xs\" x-text=\"cmd.ex\"></code></div></template></div></div></div><!-- Chat Messages --><div x-show=\"msg.type === 'chat'\" :class=\"{'flex gap-3 mb-4': true, 'flex-row-reverse': isMyMessage(msg)}\"><div class=\"w-10 h-10 rounded-full border-4 border-black flex items-center justify-center text-lg font-bold shadow-brutal-sm shrink-0\" :style=\"'background-color: ' + (msg.from_color || '#FFD100')\" x-text=\"msg.from_name ? msg.from_name.charAt(0) : '?'\"></div><div class=\"min-w-0 overflow-hidden\" :class=\"{'max-w-[75%] lg:max-w-[60%]': true, 'text-right': isMyMessage(msg)}\"><div class=\"text-xs font-semibold mb-1 px-1\" :style=\"'color: ' + (msg.from_color || '#FFD100')\"><span x-text=\"msg.from_name\"></span> <span x-show=\"isHost(msg.from_id)\" title=\"Host\" class=\"ml-1\">👑</span></div><div class=\"border-4 border-black rounded-lg p-3 shadow-brutal-sm bg-white text-left\" :class=\"{'caps-warning': msg.payload?.isCaps}\"><p class=\"text-sm leading-relaxed break-all\" x-html=\"formatMessage(msg.payload?.text || '')\"></p><span x-show=\"msg.payload?.isCaps\" class=\"text-xs text-red-500 font-bold\">📢 JANGAN TERIAK!</span></div><div class=\"text-[10px] text-gray-400 mt-1 px-1\" x-text=\"formatTime(msg.created_at)\"></div></div></div><!-- Dice Messages --><div x-show=\"msg.type === 'dice'\" class=\"flex justify-center my-4\"><div class=\"px-6 py-4 bg-neon-yellow rounded-xl border-4 border-black shadow-brutal text-center\"><div class=\"text-xs font-bold mb-2\" x-text=\"msg.from_name + ' melempar dadu' + ((msg.payload?.max && msg.payload.max !== 6) ? ' d' + msg.payload.max : '')\"></div><div class=\"text-4xl font-black\" :class=\"{ 'dice-result': msg.should_animate }\" x-text=\"'🎲 ' + (msg.payload?.result || 0)\"></div></div></div><!-- Flip Messages --><div x-show=\"msg.type === 'flip'\" class=\"flex justify-center my-4\"><div class=\"px-6 py-4 bg-neon-cyan rounded-xl border-4 border-black shadow-brutal text-center\"><div class=\"text-xs font-bold mb-2\" x-text=\"msg.from_name + ' membalik teks:'\"></div><div class=\"text-sm line-through opacity-50 mb-1\" x-text=\"msg.payload?.original\"></div><div class=\"text-xl font-bold\" x-text=\"msg.payload?.flipped\"></div></div></div><!-- Spin Messages --><div x-show=\"msg.type === 'spin'\" class=\"flex justify-center my-4\"><div class=\"px-6 py-4 bg-neon-pink rounded-xl border-4 border-black shadow-brutal text-center\"><div class=\"text-xs font-bold mb-2\" x-text=\"msg.from_name + ' mengirim pesan berputar:'\"></div><div class=\"text-xl font-bold spin-text\" x-text=\"msg.payload?.text\"></div></div></div><!-- TTS Messages --><div x-show=\"msg.type === 'tts'\" class=\"flex justify-center my-4\"><div @click=\"playTTSMessage(msg)\" class=\"px-6 py-4 bg-gradient-to-r from-blue-400 to-purple-500 rounded-xl border-4 border-black shadow-brutal text-center text-white cursor-pointer hover:scale-105 transition-transform active:scale-95 group relative\"><div class=\"text-xs font-bold mb-2 opacity-90\" x-text=\"msg.from_name + ' berbicara:'\"></div><div class=\"text-xl font-bold flex items-center justify-center gap-3\"><span class=\"text-2xl animate-pulse\">🔊</span> <span x-text=\"msg.payload?.text\"></span></div><div class=\"text-[10px] mt-2 font-mono opacity-0 group-hover:opacity-100 transition-opacity bg-black/20 rounded px-2 py-0.5 inline-block\">Klik untuk putar ulang ↺</div></div></div><!-- Whisper Messages --><div x-show=\"msg.type === 'whisper'\" :class=\"{'flex gap-3 mb-4': true, 'flex-row-reverse': isMyMessage(msg)}\"><div class=\"w-10 h-10 rounded-full border-4 border-purple-800 flex items-center justify-center text-lg font-bold shadow-brutal-sm shrink-0 bg-purple-300\" x-text=\"msg.from_name ? msg.from_name.charAt(0) : '?'\"></div><div class=\"min-w-0 overflow-hidden\" :class=\"{'max-w-[75%] lg:max-w-[60%]': true, 'text-right': isMyMessage(msg)}\"><!-- Name + Whisper Badge --><div class=\"text-xs font-semibold mb-1 px-1 flex items-center gap-2 flex-wrap\" :class=\"isMyMessage(msg) ? 'justify-end' : ''\"><span class=\"bg-purple-900 text-white text-[10px] px-2 py-0.5 rounded font-bold border border-black\">🤫 BISIKAN</span> <span class=\"text-gray-700\" x-text=\"msg.from_name + ' → ' + (msg.payload?.to_name || 'kamu')\"></span></div><div class=\"border-4 border-purple-800 rounded-lg p-3 shadow-brutal-sm bg-purple-200 text-left\"><p class=\"text-sm leading-relaxed break-all text-black font-medium\" x-text=\"msg.payload?.text\"></p></div><div class=\"text-[10px] text-gray-400 mt-1 px-1\" x-text=\"formatTime(msg.created_at)\"></div></div></div><!-- YouTube Messages --><div x-show=\"msg.type === 'youtube'\" class=\"flex justify-center my-4\"><div class=\"w-full max-w-md bg-white rounded-2xl border-4 border-black shadow-brutal overflow-hidden\"><!-- Header --><div style=\"background-color: #dc2626;\" class=\"text-white px-4 py-2 font-bold flex items-center gap-2\">▶️ <span x-text=\"msg.from_name + ' membagikan video'\"></span></div><!-- Video container --><div class=\"aspect-video bg-black\"><iframe :src=\"'https://www.youtube.com/embed/' + msg.payload?.video_id\" title=\"YouTube video player\" frameborder=\"0\" allow=\"accelerometer; autoplay; clipboard-write; encrypted-media; gyroscope; picture-in-picture\" allowfullscreen class=\"w-full h-full\"></iframe></div><!-- Footer --><div class=\"bg-gray-50 p-3 border-t-4 border-black flex items-center justify-between\"><a :href=\"msg.payload?.url\" target=\"_blank\" class=\"inline-flex items-center gap-2 px-4 py-2 bg-red-500 text-white text-sm font-bold rounded-lg border-2 border-black shadow-brutal-sm hover:bg-red-600 hover:translate-x-0.5 hover:translate-y-0.5 hover:shadow-none hover:scale-105 active:translate-x-1 active:translate-y-1 active:shadow-none active:scale-95 transition-all\">🔗 Buka di YouTube</a> <span class=\"text-xs text-gray-400\" x-text=\"formatTime(msg.created_at)\"></span></div></div></div><!-- GIF Messages --><div x-show=\"msg.type === 'gif'\" :class=\"{'flex gap-3 mb-4': true, 'flex-row-reverse': isMyMessage(msg)}\"><div class=\"w-10 h-10 rounded-full border-4 border-black flex items-center justify-center text-lg font-bold shadow-brutal-sm shrink-0\" :style=\"'background-color: ' + (msg.from_color || '#FFD100')\" x-text=\"msg.from_name ? msg.from_name.charAt(0) : '?'\"></div><div :class=\"{'max-w-[300px]': true, 'text-right': isMyMessage(msg)}\"><div class=\"text-xs font-semibold mb-1 px-1\" :style=\"'color: ' + (msg.from_color || '#FFD100')\" x-text=\"msg.from_name\"></div><div class=\"gif-container border-4 border-black rounded-lg overflow-hidden shadow-brutal-sm\"><img :src=\"msg.payload?.url\" @load=\"handleImageLoad($el)\" alt=\"GIF\" class=\"w-full\" loading=\"lazy\"></div><div class=\"text-[10px] text-gray-400 mt-1 px-1\" x-text=\"formatTime(msg.created_at)\"></div></div></div><!-- Suit (Rock Paper Scissors) Messages --><div x-show=\"msg.type === 'suit'\" class=\"flex justify-center my-4\"><div class=\"px-6 py-4 bg-neon-purple rounded-xl border-4 border-black shadow-brutal text-center text-white\"><template x-if=\"msg.payload?.status === 'pending'\"><div><div class=\"text-lg font-bold mb-2\">⚔️ TANTANGAN SUIT!</div><div class=\"text-sm\" x-text=\"msg.payload?.challenger_name + ' menantang ' + msg.payload?.opponent_name\"></div><!-- Timer --><div class=\"text-3xl font-black my-3\" x-text=\"Math.max(0, suitChallenges[msg.payload.id]?.timeLeft || 0) + 's'\"></div><!-- Actions --><div x-show=\"(isMe(msg.payload?.challenger_id, msg.payload?.challenger_name) || isMe(msg.payload?.opponent_id, msg.payload?.opponent_name)) && !hasMoved(msg.payload.id)\" class=\"mt-3 flex gap-2 justify-center\"><button @click=\"sendSuitMove('rock', msg.payload.id)\" class=\"w-12 h-12 flex items-center justify-center bg-white text-black rounded-lg border-3 border-black font-bold text-2xl hover:scale-110 transition-transform\">✊</button> <button @click=\"sendSuitMove('paper', msg.payload.id)\" class=\"w-12 h-12 flex items-center justify-center bg-white text-black rounded-lg border-3 border-black font-bold text-2xl hover:scale-110 transition-transform\">✋</button> <button @click=\"sendSuitMove('scissors', msg.payload.id)\" class=\"w-12 h-12 flex items-center justify-center bg-white text-black rounded-lg border-3 border-black font-bold text-2xl hover:scale-110 transition-transform\">✌️</button></div><div x-show=\"hasMoved(msg.payload.id)\" class=\"mt-2 text-sm italic font-medium bg-black/20 p-2 rounded-lg inline-block\">⏳ Menunggu lawan memilih...</div></div></template><template x-if=\"msg.payload?.status === 'completed'\"><div><div class=\"text-lg font-bold mb-2\">🏆 HASIL SUIT!</div><div class=\"text-sm mb-2\" x-text=\"msg.payload?.challenger_name + ' vs ' + msg.payload?.opponent_name\"></div><div class=\"flex justify-center gap-6 my-4 bg-white/20 p-2 rounded-xl\"><div class=\"flex flex-col items-center\"><div class=\"text-3xl\" x-text=\"getSuitEmoji(msg.payload.moves[msg.payload.challenger_id])\"></div><div class=\"text-[10px] font-bold mt-1 max-w-[80px] truncate\" x-text=\"msg.payload.challenger_name\"></div></div><div class=\"flex items-center text-xl font-black italic\">VS</div><div class=\"flex flex-col items-center\"><div class=\"text-3xl\" x-text=\"getSuitEmoji(msg.payload.moves[msg.payload.opponent_id])\"></div><div class=\"text-[10px] font-bold mt-1 max-w-[80px] truncate\" x-text=\"msg.payload.opponent_name\"></div></div></div><div class=\"text-2xl font-bold\" x-text=\"msg.payload?.winner === 'draw' ? '🤝 SERI!' : (isMe(msg.payload?.winner, msg.payload?.winner === msg.payload?.challenger_id ? msg.payload?.challenger_name : msg.payload?.opponent_name) ? '🎉 KAMU MENANG!' : (msg.payload?.winner === msg.payload?.challenger_id ? msg.payload?.challenger_name : msg.payload?.opponent_name) + ' MENANG!')\"></div></div></template></div></div><!-- Truth or Dare Messages --><div x-show=\"msg.type === 'tod'\" class=\"flex justify-center my-4\"><div class=\"px-6 py-4 rounded-xl border-4 border-black shadow-brutal text-center\" :class=\"msg.payload?.type === 'truth' ? 'bg-blue-400' : 'bg-red-400'\"><div class=\"text-lg font-bold mb-2 text-white\" x-text=\"msg.payload?.type === 'truth' ? '🎤 TRUTH' : '🔥 DARE'\"></div><div class=\"text-xs font-medium text-white opacity-80 mb-2\" x-text=\"'Untuk: ' + msg.from_name\"></div><div class=\"text-sm font-bold text-white\" x-text=\"msg.payload?.question\"></div></div></div><!-- Poll Messages --><div x-show=\"msg.type === 'poll'\" class=\"flex justify-center my-4\"><div class=\"px-6 py-4 bg-white rounded-xl border-4 border-black shadow-brutal w-full max-w-md\"><div class=\"text-lg font-bold mb-3\">📊 <span x-text=\"msg.payload?.question\"></span></div><div class=\"space-y-2\"><template x-for=\"(opt, idx) in msg.payload?.options\" :key=\"idx\"><button @click=\"votePoll(msg.payload?.poll_id, idx)\" class=\"poll-option w-full text-left px-4 py-2 rounded-lg border-2 border-black bg-gray-100 hover:bg-neon-yellow\" :disabled=\"polls[msg.payload?.poll_id]?.voters?.includes(myId)\"><div class=\"flex justify-between items-center\"><span x-text=\"opt\"></span> <span class=\"text-xs font-bold\" x-text=\"(polls[msg.payload?.poll_id]?.votes?.[opt] || 0) + ' votes'\"></span></div><div class=\"poll-bar h-1 bg-neon-pink rounded mt-1\" :style=\"'width: ' + ((polls[msg.payload?.poll_id]?.votes?.[opt] || 0) * 20) + '%'\"></div></button></template></div><div class=\"text-xs text-gray-500 mt-2\" x-text=\"'Dibuat oleh ' + msg.from_name\"></div></div></div></div></template></div><!-- New Messages Indicator --><div x-show=\"hasNewMessages\" x-transition class=\"flex justify-center py-2\"><button @click=\"scrollToLatest()\" style=\"padding: 12px 32px;\" class=\"bg-neon-green text-black font-bold text-sm rounded-xl border-4 border-black shadow-[3px_3px_0px_0px_#000] hover:shadow-[1px_1px_0px_0px_#000] hover:translate-x-0.5 hover:translate-y-0.5 active:shadow-none active:translate-x-1 active:translate-y-1 transition-all flex items-center gap-2\">⬇️ Pesan Baru</button></div><!-- Typing Indicator (floating above input) --><div x-show=\"typingText\" x-transition class=\"px-4 py-2\"><div class=\"inline-flex items-center gap-2 px-4 py-2 bg-gray-200 rounded-lg text-xs font-bold text-gray-700 border-2 border-black shadow-brutal-sm\"><span x-text=\"typingText\"></span> <span class=\"typing-pulse w-2 h-2 bg-gray-600 rounded-full\"></span> <span class=\"typing-pulse w-2 h-2 bg-gray-600 rounded-full\"></span> <span class=\"typing-pulse w-2 h-2 bg-gray-600 rounded-full\"></span></div></div><!-- Chat Input -->")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "</div></div><!-- Messages Area --><div id=\"messages\" class=\"flex-1 overflow-y-auto p-4 space-y-2\" x-ref=\"messagesContainer\"><!-- Welcome Message --><div class=\"flex justify-center my-4\"><div class=\"px-4 py-2 bg-gray-200 rounded-full text-xs font-medium text-gray-600 border-2 border-black\">🎉 Selamat datang di GOAT Chat! Ketik /help untuk melihat commands.</div></div><!-- Messages --><template x-for=\"(msg, index) in messages\" :key=\"msg.id || index\"><div><!-- Unread Messages Divider --><div x-show=\"unreadDividerIndex === index\" class=\"flex items-center gap-3 my-4 px-2\"><div class=\"flex-1 h-1 bg-neon-pink rounded-full\"></div><span class=\"px-4 py-1.5 bg-neon-pink text-white text-xs font-bold rounded-full border-2 border-black shadow-brutal-sm whitespace-nowrap\">Pesan Baru ⬇️</span><div class=\"flex-1 h-1 bg-neon-pink rounded-full\"></div></div><!-- System Messages (user join/leave/system) --><div x-show=\"msg.type === 'user_join' || msg.type === 'user_leave' || msg.type === 'system'\" class=\"flex justify-center my-4\"><div class=\"px-4 py-2 bg-gray-200 rounded-full text-xs font-medium text-gray-600 border-2 border-black\"><span x-text=\"msg.type === 'user_join' ? '👋 ' + msg.from_name + ' bergabung!' : (msg.type === 'user_leave' ? '👻 ' + msg.from_name + ' pergi...' : msg.from_name)\"></span></div></div><!-- Repo Info Messages --><div x-show=\"msg.type === 'repo_info'\" class=\"flex justify-center my-4\"><div class=\"w-full max-w-md bg-white rounded-xl border-4 border-black shadow-brutal p-6 text-center\"><div class=\"text-4xl mb-2\">👨\u200d💻</div><div class=\"text-xl font-bold mb-2\">GOAT CHAT DEV</div><p class=\"text-xs text-gray-600 mb-4 px-4\" x-text=\"msg.payload?.desc\"></p><a :href=\"msg.payload?.url\" target=\"_blank\" class=\"inline-block w-full bg-black text-white font-bold py-3 rounded-lg border-2 border-transparent hover:bg-gray-800 hover:border-black transition-colors\">⭐ Buka GitHub Repository</a></div></div><!-- Help Messages --><div x-show=\"msg.type === 'help'\" class=\"flex justify-center my-4\"><div class=\"w-full max-w-md bg-white rounded-xl border-4 border-black shadow-brutal p-4\"><div class=\"text-lg font-bold mb-3 text-center\">📚 DAFTAR PERINTAH</div><div class=\"space-y-2\"><template x-for=\"cmd in msg.payload?.commands\" :key=\"cmd.cmd\"><div class=\"flex items-start gap-2 text-sm border-b border-gray-100 pb-2\"><code class=\"bg-gray-200 px-2 py-1 rounded font-mono text-xs shrink-0\" x-text=\"cmd.cmd\"></code> <span class=\"text-gray-600 flex-1\" x-text=\"cmd.desc\"></span><code class=\"bg-green-100 text-green-700 px-1 rounded text-xs\" x-text=\"cmd.ex\"></code></div></template></div></div></div><!-- Chat Messages --><div x-show=\"msg.type === 'chat'\" :class=\"{'flex gap-3 mb-4': true, 'flex-row-reverse': isMyMessage(msg)}\"><div class=\"w-10 h-10 rounded-full border-4 border-black flex items-center justify-center text-lg font-bold shadow-brutal-sm shrink-0\" :style=\"'background-color: ' + (msg.from_color || '#FFD100')\" x-text=\"msg.from_name ? msg.from_name.charAt(0) : '?'\"></div><div class=\"min-w-0 overflow-hidden\" :class=\"{'max-w-[75%] lg:max-w-[60%]': true, 'text-right': isMyMessage(msg)}\"><div class=\"text-xs font-semibold mb-1 px-1\" :style=\"'color: ' + (msg.from_color || '#FFD100')\"><span x-text=\"msg.from_name\"></span> <span x-show=\"isHost(msg.from_id)\" title=\"Host\" class=\"ml-1\">👑</span></div><div class=\"border-4 border-black rounded-lg p-3 shadow-brutal-sm bg-white text-left\" :class=\"{'caps-warning': msg.payload?.isCaps}\"><p class=\"text-sm leading-relaxed break-all\" x-html=\"formatMessage(msg.payload?.text || '')\"></p><span x-show=\"msg.payload?.isCaps\" class=\"text-xs text-red-500 font-bold\">📢 JANGAN TERIAK!</span></div><div class=\"text-[10px] text-gray-400 mt-1 px-1\" x-text=\"formatTime(msg.created_at)\"></div></div></div><!-- Dice Messages --><div x-show=\"msg.type === 'dice'\" class=\"flex justify-center my-4\"><div class=\"px-6 py-4 bg-neon-yellow rounded-xl border-4 border-black shadow-brutal text-center\"><div class=\"text-xs font-bold mb-2\" x-text=\"msg.from_name + ' melempar dadu' + ((msg.payload?.max && msg.payload.max !== 6) ? ' d' + msg.payload.max : '')\"></div><div class=\"text-4xl font-black\" :class=\"{ 'dice-result': msg.should_animate }\" x-text=\"'🎲 ' + (msg.payload?.result || 0)\"></div></div></div><!-- Flip Messages --><div x-show=\"msg.type === 'flip'\" class=\"flex justify-center my-4\"><div class=\"px-6 py-4 bg-neon-cyan rounded-xl border-4 border-black shadow-brutal text-center\"><div class=\"text-xs font-bold mb-2\" x-text=\"msg.from_name + ' membalik teks:'\"></div><div class=\"text-sm line-through opacity-50 mb-1\" x-text=\"msg.payload?.original\"></div><div class=\"text-xl font-bold\" x-text=\"msg.payload?.flipped\"></div></div></div><!-- Spin Messages --><div x-show=\"msg.type === 'spin'\" class=\"flex justify-center my-4\"><div class=\"px-6 py-4 bg-neon-pink rounded-xl border-4 border-black shadow-brutal text-center\"><div class=\"text-xs font-bold mb-2\" x-text=\"msg.from_name + ' mengirim pesan berputar:'\"></div><div class=\"text-xl font-bold spin-text\" x-text=\"msg.payload?.text\"></div></div></div><!-- TTS Messages --><div x-show=\"msg.type === 'tts'\" class=\"flex justify-center my-4\"><div @click=\"playTTSMessage(msg)\" class=\"px-6 py-4 bg-gradient-to-r from-blue-400 to-purple-500 rounded-xl border-4 border-black shadow-brutal text-center text-white cursor-pointer hover:scale-105 transition-transform active:scale-95 group relative\"><div class=\"text-xs font-bold mb-2 opacity-90\" x-text=\"msg.from_name + ' berbicara:'\"></div><div class=\"text-xl font-bold flex items-center justify-center gap-3\"><span class=\"text-2xl animate-pulse\">🔊</span> <span x-text=\"msg.payload?.text\"></span></div><div class=\"text-[10px] mt-2 font-mono opacity-0 group-hover:opacity-100 transition-opacity bg-black/20 rounded px-2 py-0.5 inline-block\">Klik untuk putar ulang ↺</div></div></div><!-- Whisper Messages --><div x-show=\"msg.type === 'whisper'\" :class=\"{'flex gap-3 mb-4': true, 'flex-row-reverse': isMyMessage(msg)}\"><div class=\"w-10 h-10 rounded-full border-4 border-purple-800 flex items-center justify-center text-lg font-bold shadow-brutal-sm shrink-0 bg-purple-300\" x-text=\"msg.from_name ? msg.from_name.charAt(0) : '?'\"></div><div class=\"min-w-0 overflow-hidden\" :class=\"{'max-w-[75%] lg:max-w-[60%]': true, 'text-right': isMyMessage(msg)}\"><!-- Name + Whisper Badge --><div class=\"text-xs font-semibold mb-1 px-1 flex items-center gap-2 flex-wrap\" :class=\"isMyMessage(msg) ? 'justify-end' : ''\"><span class=\"bg-purple-900 text-white text-[10px] px-2 py-0.5 rounded font-bold border border-black\">🤫 BISIKAN</span> <span class=\"text-gray-700\" x-text=\"msg.from_name + ' → ' + (msg.payload?.to_name || 'kamu')\"></span></div><div class=\"border-4 border-purple-800 rounded-lg p-3 shadow-brutal-sm bg-purple-200 text-left\"><p class=\"text-sm leading-relaxed break-all text-black font-medium\" x-text=\"msg.payload?.text\"></p></div><div class=\"text-[10px] text-gray-400 mt-1 px-1\" x-text=\"formatTime(msg.created_at)\"></div></div></div><!-- YouTube Messages --><div x-show=\"msg.type === 'youtube'\" class=\"flex justify-center my-4\"><div class=\"w-full max-w-md bg-white rounded-2xl border-4 border-black shadow-brutal overflow-hidden\"><!-- Header --><div style=\"background-color: #dc2626;\" class=\"text-white px-4 py-2 font-bold flex items-center gap-2\">▶️ <span x-text=\"msg.from_name + ' membagikan video'\"></span></div><!-- Video container --><div class=\"aspect-video bg-black\"><iframe :src=\"'https://www.youtube.com/embed/' + msg.payload?.video_id\" title=\"YouTube video player\" frameborder=\"0\" allow=\"accelerometer; autoplay; clipboard-write; encrypted-media; gyroscope; picture-in-picture\" allowfullscreen class=\"w-full h-full\"></iframe></div><!-- Footer --><div class=\"bg-gray-50 p-3 border-t-4 border-black flex items-center justify-between\"><a :href=\"msg.payload?.url\" target=\"_blank\" class=\"inline-flex items-center gap-2 px-4 py-2 bg-red-500 text-white text-sm font-bold rounded-lg border-2 border-black shadow-brutal-sm hover:bg-red-600 hover:translate-x-0.5 hover:translate-y-0.5 hover:shadow-none hover:scale-105 active:translate-x-1 active:translate-y-1 active:shadow-none active:scale-95 transition-all\">🔗 Buka di YouTube</a> <span class=\"text-xs text-gray-400\" x-text=\"formatTime(msg.created_at)\"></span></div></div></div><!-- GIF Messages --><div x-show=\"msg.type === 'gif'\" :class=\"{'flex gap-3 mb-4': true, 'flex-row-reverse': isMyMessage(msg)}\"><div class=\"w-10 h-10 rounded-full border-4 border-black flex items-center justify-center text-lg font-bold shadow-brutal-sm shrink-0\" :style=\"'background-color: ' + (msg.from_color || '#FFD100')\" x-text=\"msg.from_name ? msg.from_name.charAt(0) : '?'\"></div><div :class=\"{'max-w-[300px]': true, 'text-right': isMyMessage(msg)}\"><div class=\"text-xs font-semibold mb-1 px-1\" :style=\"'color: ' + (msg.from_color || '#FFD100')\" x-text=\"msg.from_name\"></div><div class=\"gif-container border-4 border-black rounded-lg overflow-hidden shadow-brutal-sm\"><img :src=\"msg.payload?.url\" @load=\"handleImageLoad($el)\" alt=\"GIF\" class=\"w-full\" loading=\"lazy\"></div><div class=\"text-[10px] text-gray-400 mt-1 px-1\" x-text=\"formatTime(msg.created_at)\"></div></div></div><!-- Suit (Rock Paper Scissors) Messages --><div x-show=\"msg.type === 'suit'\" class=\"flex justify-center my-4\"><div class=\"px-6 py-4 bg-neon-purple rounded-xl border-4 border-black shadow-brutal text-center text-white\"><template x-if=\"msg.payload?.status === 'pending' || msg.payload?.status === 'accepted'\"><div><div class=\"text-lg font-bold mb-2\" x-text=\"msg.payload?.status === 'pending' ? '⚔️ TANTANGAN SUIT!' : '⚔️ SUIT DIMULAI!'\"></div><div class=\"text-sm\" x-text=\"msg.payload?.challenger_name + ' menantang ' + msg.payload?.opponent_name\"></div><!-- Timer --><div class=\"text-3xl font-black my-3\" x-text=\"Math.max(0, suitChallenges[msg.payload.id]?.timeLeft || 0) + 's'\"></div><!-- Opponent accepts or declines first --><div x-show=\"msg.payload?.status === 'pending' && isMe(msg.payload?.opponent_id, msg.payload?.opponent_name)\" class=\"mt-3 flex gap-2 justify-center\"><button @click=\"respondSuit(msg.payload.id, true)\" class=\"px-4 py-2 bg-neon-green text-black rounded-lg border-3 border-black font-bold hover:scale-105 transition-transform\">Terima</button> <button @click=\"respondSuit(msg.payload.id, false)\" class=\"px-4 py-2 bg-white text-black rounded-lg border-3 border-black font-bold hover:scale-105 transition-transform\">Tolak</button></div><!-- Moves: the challenger may pick early, the opponent once accepted --><div x-show=\"!hasMoved(msg.payload.id) && (isMe(msg.payload?.challenger_id, msg.payload?.challenger_name) || (msg.payload?.status === 'accepted' && isMe(msg.payload?.opponent_id, msg.payload?.opponent_name)))\" class=\"mt-3 flex gap-2 justify-center\"><button @click=\"sendSuitMove('rock', msg.payload.id)\" class=\"w-12 h-12 flex items-center justify-center bg-white text-black rounded-lg border-3 border-black font-bold text-2xl hover:scale-110 transition-transform\">✊</button> <button @click=\"sendSuitMove('paper', msg.payload.id)\" class=\"w-12 h-12 flex items-center justify-center bg-white text-black rounded-lg border-3 border-black font-bold text-2xl hover:scale-110 transition-transform\">✋</button> <button @click=\"sendSuitMove('scissors', msg.payload.id)\" class=\"w-12 h-12 flex items-center justify-center bg-white text-black rounded-lg border-3 border-black font-bold text-2xl hover:scale-110 transition-transform\">✌️</button></div><div x-show=\"hasMoved(msg.payload.id)\" class=\"mt-2 text-sm italic font-medium bg-black/20 p-2 rounded-lg inline-block\" x-text=\"'⏳ Kamu pilih ' + getSuitEmoji(suitChallenges[msg.payload.id]?.my_move) + ', menunggu lawan...'\"></div></div></template><template x-if=\"msg.payload?.status === 'declined' || msg.payload?.status === 'expired'\"><div><div class=\"text-lg font-bold mb-2\">⚔️ SUIT BATAL</div><div class=\"text-sm\" x-text=\"msg.payload?.status === 'declined' ? msg.payload?.opponent_name + ' menolak tantangan ' + msg.payload?.challenger_name : 'Waktu habis, tantangan ' + msg.payload?.challenger_name + ' kedaluwarsa'\"></div></div></template><template x-if=\"msg.payload?.status === 'completed'\"><div><div class=\"text-lg font-bold mb-2\">🏆 HASIL SUIT!</div><div class=\"text-sm mb-2\" x-text=\"msg.payload?.challenger_name + ' vs ' + msg.payload?.opponent_name\"></div><div class=\"flex justify-center gap-6 my-4 bg-white/20 p-2 rounded-xl\"><div class=\"flex flex-col items-center\"><div class=\"text-3xl\" x-text=\"getSuitEmoji(msg.payload.challenger_move)\"></div><div class=\"text-[10px] font-bold mt-1 max-w-[80px] truncate\" x-text=\"msg.payload.challenger_name\"></div></div><div class=\"flex items-center text-xl font-black italic\">VS</div><div class=\"flex flex-col items-center\"><div class=\"text-3xl\" x-text=\"getSuitEmoji(msg.payload.opponent_move)\"></div><div class=\"text-[10px] font-bold mt-1 max-w-[80px] truncate\" x-text=\"msg.payload.opponent_name\"></div></div></div><div class=\"text-2xl font-bold\" x-text=\"msg.payload?.winner === 'draw' ? '🤝 SERI!' : (isMe(msg.payload?.winner, msg.payload?.winner === msg.payload?.challenger_id ? msg.payload?.challenger_name : msg.payload?.opponent_name) ? '🎉 KAMU MENANG!' : (msg.payload?.winner === msg.payload?.challenger_id ? msg.payload?.challenger_name : msg.payload?.opponent_name) + ' MENANG!')\"></div></div></template></div></div><!-- Truth or Dare Messages --><div x-show=\"msg.type === 'tod'\" class=\"flex justify-center my-4\"><div class=\"px-6 py-4 rounded-xl border-4 border-black shadow-brutal text-center\" :class=\"msg.payload?.type === 'truth' ? 'bg-blue-400' : 'bg-red-400'\"><div class=\"text-lg font-bold mb-2 text-white\" x-text=\"msg.payload?.type === 'truth' ? '🎤 TRUTH' : '🔥 DARE'\"></div><div class=\"text-xs font-medium text-white opacity-80 mb-2\" x-text=\"'Untuk: ' + msg.from_name\"></div><div class=\"text-sm font-bold text-white\" x-text=\"msg.payload?.question\"></div></div></div><!-- Poll Messages --><div x-show=\"msg.type === 'poll'\" class=\"flex justify-center my-4\"><div class=\"px-6 py-4 bg-white rounded-xl border-4 border-black shadow-brutal w-full max-w-md\"><div class=\"text-lg font-bold mb-3\">📊 <span x-text=\"msg.payload?.question\"></span></div><div class=\"space-y-2\"><template x-for=\"(opt, idx) in msg.payload?.options\" :key=\"idx\"><button @click=\"votePoll(msg.payload?.poll_id, idx)\" class=\"poll-option w-full text-left px-4 py-2 rounded-lg border-2 border-black bg-gray-100 hover:bg-neon-yellow\" :disabled=\"polls[msg.payload?.poll_id]?.voters?.includes(myId)\"><div class=\"flex justify-between items-center\"><span x-text=\"opt\"></span> <span class=\"text-xs font-bold\" x-text=\"(polls[msg.payload?.poll_id]?.votes?.[opt] || 0) + ' votes'\"></span></div><div class=\"poll-bar h-1 bg-neon-pink rounded mt-1\" :style=\"'width: ' + ((polls[msg.payload?.poll_id]?.votes?.[opt] || 0) * 20) + '%'\"></div></button></template></div><div class=\"text-xs text-gray-500 mt-2\" x-text=\"'Dibuat oleh ' + msg.from_name\"></div></div></div></div></template></div><!-- New Messages Indicator --><div x-show=\"hasNewMessages\" x-transition class=\"flex justify-center py-2\"><button @click=\"scrollToLatest()\" style=\"padding: 12px 32px;\" class=\"bg-neon-green text-black font-bold text-sm rounded-xl border-4 border-black shadow-[3px_3px_0px_0px_#000] hover:shadow-[1px_1px_0px_0px_#000] hover:translate-x-0.5 hover:translate-y-0.5 active:shadow-none active:translate-x-1 active:translate-y-1 transition-all flex items-center gap-2\">⬇️ Pesan Baru</button></div><!-- Typing Indicator (floating above input) --><div x-show=\"typingText\" x-transition class=\"px-4 py-2\"><div class=\"inline-flex items-center gap-2 px-4 py-2 bg-gray-200 rounded-lg text-xs font-bold text-gray-700 border-2 border-black shadow-brutal-sm\"><span x-text=\"typingText\"></span> <span class=\"typing-pulse w-2 h-2 bg-gray-600 rounded-full\"></span> <span class=\"typing-pulse w-2 h-2 bg-gray-600 rounded-full\"></span> <span class=\"typing-pulse w-2 h-2 bg-gray-600 rounded-full\"></span></div></div><!-- Chat Input -->")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}